@@ -1,23 +1,51 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 
 	"github.com/0xshariq/totion/internal/app"
+	"github.com/0xshariq/totion/internal/cli"
 	"github.com/0xshariq/totion/internal/config"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	vault := flag.String("vault", "", "vault profile name or directory to open")
+	flag.Usage = func() {
+		cli.Run([]string{"help"}, os.Stdin, flag.CommandLine.Output(), os.Stderr)
+		fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Subcommands run headless, for scripts and cron
+	headless := flag.NArg() > 0
+	if headless && !cli.IsCommand(flag.Arg(0)) {
+		fmt.Fprintf(os.Stderr, "totion: unknown command %q (see 'totion help')\n", flag.Arg(0))
+		os.Exit(cli.ExitUsage)
+	}
+
 	// Initialize configuration
-	if err := config.Initialize(); err != nil {
+	if err := config.Initialize(*vault); err != nil {
+		if headless {
+			fmt.Fprintf(os.Stderr, "totion: failed to initialize config: %v\n", err)
+			os.Exit(cli.ExitError)
+		}
 		log.Fatalf("Failed to initialize config: %v", err)
 	}
 
+	if headless {
+		os.Exit(cli.Run(flag.Args(), os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	// Create and run the application
-	model := app.New()
+	model, err := app.New()
+	if err != nil {
+		log.Fatalf("Failed to open vault: %v", err)
+	}
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	if _, err := p.Run(); err != nil {
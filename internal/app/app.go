@@ -7,16 +7,24 @@ import (
 	"sync"
 	"time"
 
+	"github.com/0xshariq/totion/internal/config"
+	"github.com/0xshariq/totion/internal/features/attachments"
 	"github.com/0xshariq/totion/internal/features/autosave"
 	"github.com/0xshariq/totion/internal/features/daily"
+	"github.com/0xshariq/totion/internal/features/doctor"
+	"github.com/0xshariq/totion/internal/features/history"
+	"github.com/0xshariq/totion/internal/features/linking"
 	"github.com/0xshariq/totion/internal/features/pinned"
 	"github.com/0xshariq/totion/internal/features/quick"
 	"github.com/0xshariq/totion/internal/features/recent"
 	"github.com/0xshariq/totion/internal/features/search"
 	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/features/trash"
+	"github.com/0xshariq/totion/internal/features/watcher"
 	"github.com/0xshariq/totion/internal/lingo"
 	"github.com/0xshariq/totion/internal/models"
 	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/themes"
 	"github.com/0xshariq/totion/internal/ui/components"
 	"github.com/0xshariq/totion/internal/ui/styles"
 	"github.com/charmbracelet/bubbles/list"
@@ -55,10 +63,21 @@ const (
 	ViewSearch
 	ViewTags
 	ViewLanguageSelector
+	ViewSwapRecovery
+	ViewSettings
+	ViewVaults
+	ViewTrash
+	ViewRename
+	ViewHistory
+	ViewAttach
+	ViewAttachments
+	ViewDoctor
+	ViewTrashConfirm
 )
 
 // Model represents the main application model
 type Model struct {
+	config            *config.Config
 	storage           *storage.Storage
 	state             ViewState
 	list              list.Model
@@ -86,10 +105,38 @@ type Model struct {
 	pinnedManager     *pinned.PinnedManager   // Pinned notes manager
 	dailyManager      *daily.DailyManager     // Daily notes manager
 	quickManager      *quick.QuickNoteManager // Quick note manager
-	searchManager     *search.SearchManager   // Search manager
+	searchManager     *search.SearchManager   // Manager of the last search, results of older ones are dropped
 	tagManager        *tags.TagManager        // Tag manager
-	searchResults     []search.SearchResult   // Search results
+	linkManager       *linking.LinkManager    // Wiki link index, kept current by the watcher
+	watcher           *watcher.Watcher        // Watches the vault for external changes
+	themeManager      *themes.ThemeManager    // Theme manager
+	searchResults     []search.NoteResult     // Notes found by the search view, best first
+	searchQuery       string                  // Query the search results are for
+	searching         bool                    // The search for searchQuery is still running
+	searchIndex       int                     // Selected note in search view
+	searchMode        search.Mode             // Exact, fuzzy or regex search, Tab switches
+	searchReturn      ViewState               // View to go back to from search
+	pendingSwaps      []storage.SwapFile      // Swap files left by an interrupted session
+	settingsIndex     int                     // Selected row in settings view
+	settingsEditing   bool                    // Editing the selected setting
+	settingsInput     textinput.Model         // Input for setting values
+	vaultIndex        int                     // Selected row in vault switcher
+	trashItems        []trash.TrashedItem     // Items shown in trash view
+	trashIndex        int                     // Selected row in trash view
+	trashEmptying     bool                    // Trash confirm view empties the trash rather than deleting the selected item
+	historyVersions   []history.Version       // Versions shown in history view
+	historyIndex      int                     // Selected row in history view
+	historyDiff       bool                    // Show the selected version as a diff
+	attachReport      *attachments.Report     // Orphaned and missing attachments
+	attachIndex       int                     // Selected orphan in attachments view
+	doctorIssues      []doctor.Issue          // Problems shown in doctor view
+	doctorIndex       int                     // Selected problem in doctor view
+	deletingNotebook  bool                    // Notebook selection is for deleting
+	renamingNotebook  bool                    // Notebook selection is for renaming
+	renamePath        string                  // Note or notebook being renamed
 	isEditorDirty     bool                    // Track if editor has unsaved changes
+	diskChanged       bool                    // Open note changed on disk while it had unsaved edits
+	readOnly          bool                    // Open note is locked by another totion and can't be edited
 	focusMode         bool                    // Focus mode (minimal UI)
 	homeViewReady     bool                    // Track if home viewport is initialized
 	lingoClient       *lingo.Client           // Lingo.dev translation client
@@ -105,14 +152,15 @@ type Model struct {
 }
 
 // New creates a new application model
-func New() *Model {
+// Returns an error if the vault's storage can't be opened
+func New() (*Model, error) {
+	cfg := config.AppConfig
 	homeDir, _ := os.UserHomeDir()
-	configDir := filepath.Join(homeDir, ".totion")
 
 	// Load .env file from current directory first, then from home directory
-	_ = godotenv.Load(".env")                           // Try current directory
-	_ = godotenv.Load(filepath.Join(homeDir, ".env"))   // Try home directory
-	_ = godotenv.Load(filepath.Join(configDir, ".env")) // Try config directory
+	_ = godotenv.Load(".env")                              // Try current directory
+	_ = godotenv.Load(filepath.Join(homeDir, ".env"))      // Try home directory
+	_ = godotenv.Load(filepath.Join(cfg.VaultDir, ".env")) // Try vault directory
 
 	// Get API key from .env file, fallback to environment variable
 	lingoAPIKey := os.Getenv("LINGODOTDEV_API_KEY")
@@ -122,7 +170,7 @@ func New() *Model {
 	}
 
 	// Initialize bridge server (don't start yet - lazy load when needed)
-	bridgeServer := lingo.NewBridgeServer()
+	bridgeServer := lingo.NewBridgeServer(cfg.Bridge.Port)
 
 	// Start bridge server asynchronously in background if API key is configured
 	if lingoAPIKey != "" {
@@ -133,19 +181,20 @@ func New() *Model {
 	}
 
 	// Initialize Lingo.dev client with API key
-	lingoClient := lingo.NewClient(lingoAPIKey)
+	lingoClient := lingo.NewClient(lingoAPIKey, bridgeServer.GetURL())
 	lingoClient.SetBridgeServer(bridgeServer) // Connect client to bridge for smart waiting
 
 	m := &Model{
-		storage:           storage.New(),
+		config:            cfg,
 		state:             ViewHome,
 		editor:            components.NewEditor(),
 		fileNameInput:     components.NewFileNameInput(),
 		notebookNameInput: components.NewFileNameInput(),
+		settingsInput:     components.NewFileNameInput(),
+		searchInput:       components.NewSearchInput(),
 		selectedFormat:    models.FormatMarkdown,
 		formatIndex:       0,
-		recentManager:     recent.NewRecentManager(configDir),
-		pinnedManager:     pinned.NewPinnedManager(configDir),
+		themeManager:      themes.NewThemeManager(cfg.Theme),
 		focusMode:         false,
 		lingoClient:       lingoClient,
 		bridgeServer:      bridgeServer,
@@ -157,10 +206,12 @@ func New() *Model {
 		viewCache:         make(map[string]string),
 	}
 
-	// Setup auto-save callback
-	m.autoSaver = autosave.NewAutoSaver(func() error {
-		if m.isEditorDirty && m.currentFile != nil {
-			if err := m.storage.SaveNote(m.currentFile, m.editor.Value()); err != nil {
+	// Setup auto-save callback (atomic write, keeps the file handle open)
+	m.autoSaver = autosave.NewAutoSaver(cfg.AutoSave.Interval, cfg.AutoSave.SwapInterval, func() error {
+		// Don't overwrite external changes the user hasn't decided about yet,
+		// nor a note another totion holds the lock of
+		if m.isEditorDirty && !m.diskChanged && !m.readOnly && m.currentFile != nil && m.currentNote != nil {
+			if err := m.storage.WriteNote(m.currentNote.Path, m.editor.Value()); err != nil {
 				return err
 			}
 			m.isEditorDirty = false
@@ -169,12 +220,78 @@ func New() *Model {
 		return nil
 	})
 
-	return m
+	// Keep unsaved editor content in a swap file between auto-saves
+	m.autoSaver.SetSwapCallback(func() error {
+		// Keep the open note's lock fresh for totion on other hosts
+		m.storage.RefreshLocks()
+
+		// The swap file of a read-only note belongs to the totion holding its lock
+		if m.isEditorDirty && !m.readOnly && m.currentNote != nil {
+			return m.storage.WriteSwap(m.currentNote.Path, m.editor.Value())
+		}
+		return nil
+	})
+
+	if err := m.initVault(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// initVault (re)creates everything scoped to the active vault
+// Per-vault state files (recents, pins, tag index) live in the vault directory
+// Nothing changes if the vault's storage can't be opened
+func (m *Model) initVault() error {
+	vaultDir := m.config.VaultDir
+
+	store, err := storage.NewWithVault(vaultDir)
+	if err != nil {
+		return err
+	}
+	if m.storage != nil {
+		search.Forget(m.storage) // The search index of the vault we switch away from
+		_ = m.storage.Close()
+	}
+	m.storage = store
+	m.recentManager = recent.NewRecentManager(vaultDir, m.config.MaxRecent)
+	m.pinnedManager = pinned.NewPinnedManager(vaultDir, m.config.MaxPinned)
+	m.dailyManager = daily.NewDailyManager(vaultDir)
+	m.quickManager = quick.NewQuickNoteManager(vaultDir)
+	m.searchManager = search.NewSearchManager(m.storage)
+	m.tagManager = tags.NewTagManager(m.storage, vaultDir)
+	m.searchResults = nil
+	m.searchQuery = ""
+	m.searching = false
+	m.diskChanged = false
+	m.pendingSwaps = nil
+	m.trashItems = nil
+	m.historyVersions = nil
+	m.attachReport = nil
+
+	// Give new notes IDs and point pins and recents at moved notes
+	m.syncNoteIDs()
+
+	// Apply the trash and history retention policies
+	_, _ = m.storage.Trash().PurgeExpired(m.config.Trash.Retention())
+	m.applyHistoryPolicy()
+	_ = m.storage.History().Prune()
+	m.applySearchOptions()
+
+	// Pick up changes made by other editors and git
+	m.startWatcher()
+
+	// Offer to recover unsaved changes from a previous session
+	if swaps, err := m.storage.FindSwapFiles(); err == nil && len(swaps) > 0 {
+		m.pendingSwaps = swaps
+		m.state = ViewSwapRecovery
+	}
+	return nil
 }
 
 // Init initializes the model
 func (m *Model) Init() tea.Cmd {
-	return nil
+	return m.waitForVaultChanges()
 }
 
 // Update handles messages and updates the model
@@ -182,6 +299,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case vaultChangedMsg:
+		if msg.watcher != m.watcher {
+			return m, nil // Left over from a vault we switched away from
+		}
+		return m, tea.Batch(m.applyVaultChanges(msg.events), m.waitForVaultChanges())
+
+	case searchDoneMsg:
+		if msg.manager == m.searchManager {
+			m.showSearchResults(msg)
+		}
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -234,14 +363,26 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case ViewList:
 			m.list, cmd = m.list.Update(msg)
 		case ViewEditor:
+			// A read-only note can be scrolled but not changed
+			if m.readOnly && !isNavigationKey(msg) {
+				return m, nil
+			}
 			// In editor mode, let the editor handle the key
 			m.editor, cmd = m.editor.Update(msg)
 			// Mark as dirty when content changes (any key that wasn't handled globally)
-			m.isEditorDirty = true
-		case ViewNewFile, ViewNoteNameInNotebook:
+			if !m.readOnly {
+				m.isEditorDirty = true
+			}
+		case ViewNewFile, ViewNoteNameInNotebook, ViewRename, ViewAttach:
 			m.fileNameInput, cmd = m.fileNameInput.Update(msg)
 		case ViewNotebookNameInput:
 			m.notebookNameInput, cmd = m.notebookNameInput.Update(msg)
+		case ViewSearch:
+			m.searchInput, cmd = m.searchInput.Update(msg)
+		case ViewSettings:
+			if m.settingsEditing {
+				m.settingsInput, cmd = m.settingsInput.Update(msg)
+			}
 		}
 		return m, cmd
 	}
@@ -261,10 +402,14 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.list, cmd = m.list.Update(msg)
 	case ViewEditor:
 		m.editor, cmd = m.editor.Update(msg)
-	case ViewNewFile, ViewNoteNameInNotebook:
+	case ViewNewFile, ViewNoteNameInNotebook, ViewRename, ViewAttach:
 		m.fileNameInput, cmd = m.fileNameInput.Update(msg)
 	case ViewNotebookNameInput:
 		m.notebookNameInput, cmd = m.notebookNameInput.Update(msg)
+	case ViewSearch:
+		m.searchInput, cmd = m.searchInput.Update(msg)
+	case ViewSettings:
+		m.settingsInput, cmd = m.settingsInput.Update(msg)
 	}
 
 	return m, cmd
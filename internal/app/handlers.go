@@ -1,21 +1,36 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/0xshariq/totion/internal/config"
+	"github.com/0xshariq/totion/internal/features/attachments"
+	"github.com/0xshariq/totion/internal/features/doctor"
 	"github.com/0xshariq/totion/internal/features/export"
 	"github.com/0xshariq/totion/internal/features/git"
+	"github.com/0xshariq/totion/internal/features/history"
 	importpkg "github.com/0xshariq/totion/internal/features/import"
 	"github.com/0xshariq/totion/internal/features/linking"
+	"github.com/0xshariq/totion/internal/features/pinned"
+	"github.com/0xshariq/totion/internal/features/recent"
+	"github.com/0xshariq/totion/internal/features/rename"
+	"github.com/0xshariq/totion/internal/features/search"
 	"github.com/0xshariq/totion/internal/features/stats"
 	"github.com/0xshariq/totion/internal/features/sync"
+	"github.com/0xshariq/totion/internal/features/tags"
 	"github.com/0xshariq/totion/internal/features/templates"
 	"github.com/0xshariq/totion/internal/models"
 	"github.com/0xshariq/totion/internal/notebook"
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/lock"
+	"github.com/0xshariq/totion/internal/themes"
 	"github.com/0xshariq/totion/internal/ui/components"
 	"github.com/0xshariq/totion/internal/ui/styles"
 	tea "github.com/charmbracelet/bubbletea"
@@ -31,6 +46,9 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			if m.bridgeServer != nil {
 				m.bridgeServer.Stop()
 			}
+			m.stopWatcher()
+			// Let other totion processes edit the notes this one had open
+			m.storage.ReleaseLocks()
 			// Reset language to English before quitting
 			m.currentUILanguage = "en"
 			m.translationCache = make(map[string]string)
@@ -67,12 +85,36 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			return true, m, nil
 		}
 
+	case "ctrl+r":
+		if m.state == ViewList && m.list.FilterState() != 1 { // Not while typing a filter
+			m.startRenameNote()
+			return true, m, nil
+		}
+
 	case "ctrl+h", "?":
+		if m.state == ViewSearch && msg.String() == "?" {
+			return false, m, nil // Part of the query
+		}
 		m.state = ViewHelp
 		m.helpTopic = "" // Reset to show menu
 		m.statusMessage = ""
 		return true, m, nil
 
+	case "ctrl+_", "/":
+		// Terminals send Ctrl+/ as Ctrl+_
+		if msg.String() == "ctrl+_" || m.state == ViewHome {
+			if m.state != ViewSearch {
+				m.openSearch()
+			}
+			return true, m, nil
+		}
+
+	case "pgup", "pgdown":
+		if m.state == ViewSearch {
+			m.moveSearchPage(msg.String() == "pgdown")
+			return true, m, nil
+		}
+
 	case "ctrl+t":
 		m.state = ViewTemplates
 		m.statusMessage = ""
@@ -102,7 +144,38 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 	case "alt+l":
 		if m.state == ViewEditor {
 			// Show linking menu in editor
-			m.statusMessage = styles.InfoStyle.Render(m.translate("Use [[Note Title]] to create links"))
+			hint := m.translate("Use [[Note Title]] to create links")
+			if m.currentNote != nil && m.currentNote.ID != "" {
+				name := strings.TrimSuffix(m.currentNote.Name, filepath.Ext(m.currentNote.Name))
+				link := linking.NewLinkManager().CreateIDLink(m.currentNote.ID, name)
+				hint += "  •  " + fmt.Sprintf(m.translate("Link here from anywhere with %s"), link)
+			}
+			m.statusMessage = styles.InfoStyle.Render(hint)
+			return true, m, nil
+		}
+
+	case "alt+r":
+		if m.state == ViewEditor && m.currentNote != nil && m.diskChanged {
+			m.reloadCurrentNote()
+			return true, m, nil
+		}
+
+	case "alt+v":
+		if m.state == ViewEditor && m.currentNote != nil {
+			m.openHistory()
+			return true, m, nil
+		}
+
+	case "alt+a":
+		if m.state == ViewEditor && m.currentNote != nil && m.readOnly {
+			m.showReadOnlyStatus()
+			return true, m, nil
+		}
+		if m.state == ViewEditor && m.currentNote != nil {
+			m.fileNameInput.SetValue("")
+			m.fileNameInput.Focus()
+			m.state = ViewAttach
+			m.statusMessage = ""
 			return true, m, nil
 		}
 
@@ -136,26 +209,20 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			}
 
 			// Open the note file
-			file, err := m.storage.OpenNote(notePath)
+			file, err := m.openNoteFile(notePath)
 			if err != nil {
 				m.statusMessage = styles.ErrorStyle.Render(m.translate("Error opening daily note: ") + err.Error())
 				return true, m, nil
 			}
 
 			// Create note model
-			noteInfo, err := os.Stat(notePath)
+			note, err := m.storage.GetNote(notePath)
 			if err != nil {
 				m.statusMessage = styles.ErrorStyle.Render(m.translate("Error reading note info: ") + err.Error())
 				return true, m, nil
 			}
 
-			m.currentNote = &models.Note{
-				Name:    filepath.Base(notePath),
-				Path:    notePath,
-				Format:  models.FormatMarkdown,
-				ModTime: noteInfo.ModTime(),
-				Size:    noteInfo.Size(),
-			}
+			m.currentNote = &note
 			m.currentFile = file
 			m.state = ViewEditor
 			m.editor.SetValue(content)
@@ -173,6 +240,9 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			m.isEditorDirty = false
 
 			m.statusMessage = styles.SuccessStyle.Render(m.translate("📅 Daily journal opened"))
+			if m.readOnly {
+				m.showReadOnlyStatus()
+			}
 			return true, m, nil
 		}
 
@@ -191,7 +261,7 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 	case "alt+p":
 		if m.state == ViewEditor && m.currentNote != nil {
 			// Toggle pin for current note
-			isPinned, err := m.pinnedManager.Toggle(m.currentNote.Path, m.currentNote.Name)
+			isPinned, err := m.pinnedManager.Toggle(m.currentNote)
 			if err != nil {
 				m.statusMessage = styles.ErrorStyle.Render(m.translate("Error toggling pin: ") + err.Error())
 			} else {
@@ -210,6 +280,10 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			m.statusMessage = ""
 			return true, m, nil
 		}
+		if m.state == ViewHelp && m.helpTopic == "" {
+			m.helpTopic = "s"
+			return true, m, nil
+		}
 
 	case "g", "G":
 		if m.state == ViewHome {
@@ -232,11 +306,67 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			return true, m, nil
 		}
 
+	case "x", "X":
+		if m.state == ViewHome {
+			m.openTrash()
+			return true, m, nil
+		}
+
+	case "e", "E":
+		if m.state == ViewTrash {
+			m.confirmTrashDelete(true)
+			return true, m, nil
+		}
+
+	case "v", "V":
+		if m.state == ViewHome {
+			m.state = ViewVaults
+			m.vaultIndex = 0
+			m.statusMessage = ""
+			return true, m, nil
+		}
+
+	case "a", "A":
+		if m.state == ViewHome {
+			m.openAttachments()
+			return true, m, nil
+		}
+
+	case "c", "C":
+		if m.state == ViewHome {
+			m.state = ViewSettings
+			m.settingsIndex = 0
+			m.settingsEditing = false
+			m.statusMessage = ""
+			return true, m, nil
+		}
+
 	case "enter":
 		if m.state == ViewLanguageSelector {
 			return m.translateNote()
 		}
-		if m.state == ViewNewFile || m.state == ViewFormatSelector || m.state == ViewList || m.state == ViewNotebookNameInput || m.state == ViewNoteNameInNotebook {
+		if m.state == ViewSettings {
+			m.handleSettingsEnter()
+			return true, m, nil
+		}
+		if m.state == ViewSearch {
+			// Search for a new query, or open the selected note
+			if strings.TrimSpace(m.searchInput.Value()) != m.searchQuery {
+				return true, m, m.runSearch()
+			}
+			if !m.searching {
+				m.openSearchResult()
+			}
+			return true, m, nil
+		}
+		if m.state == ViewVaults {
+			profiles := m.config.VaultProfiles()
+			if m.vaultIndex < len(profiles) {
+				return true, m, m.switchVault(profiles[m.vaultIndex].Name)
+			}
+			return true, m, nil
+		}
+		if m.state == ViewNewFile || m.state == ViewFormatSelector || m.state == ViewList || m.state == ViewNotebookNameInput || m.state == ViewNoteNameInNotebook || m.state == ViewRename || m.state == ViewAttach {
 			newModel, cmd := m.handleEnter()
 			return true, newModel, cmd
 		}
@@ -246,6 +376,48 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			m.selectedLangIndex--
 			return true, m, nil
 		}
+		if m.state == ViewSettings && !m.settingsEditing {
+			if m.settingsIndex > 0 {
+				m.settingsIndex--
+			}
+			return true, m, nil
+		}
+		if m.state == ViewVaults {
+			if m.vaultIndex > 0 {
+				m.vaultIndex--
+			}
+			return true, m, nil
+		}
+		if m.state == ViewTrash {
+			if m.trashIndex > 0 {
+				m.trashIndex--
+			}
+			return true, m, nil
+		}
+		if m.state == ViewHistory {
+			if m.historyIndex > 0 {
+				m.historyIndex--
+			}
+			return true, m, nil
+		}
+		if m.state == ViewAttachments {
+			if m.attachIndex > 0 {
+				m.attachIndex--
+			}
+			return true, m, nil
+		}
+		if m.state == ViewDoctor {
+			if m.doctorIndex > 0 {
+				m.doctorIndex--
+			}
+			return true, m, nil
+		}
+		if m.state == ViewSearch && msg.String() == "up" {
+			if m.searchIndex > 0 {
+				m.searchIndex--
+			}
+			return true, m, nil
+		}
 
 	case "down", "j":
 		if m.state == ViewLanguageSelector {
@@ -255,23 +427,74 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			}
 			return true, m, nil
 		}
+		if m.state == ViewSettings && !m.settingsEditing {
+			if m.settingsIndex < len(config.Fields())-1 {
+				m.settingsIndex++
+			}
+			return true, m, nil
+		}
+		if m.state == ViewVaults {
+			if m.vaultIndex < len(m.config.VaultProfiles())-1 {
+				m.vaultIndex++
+			}
+			return true, m, nil
+		}
+		if m.state == ViewTrash {
+			if m.trashIndex < len(m.trashItems)-1 {
+				m.trashIndex++
+			}
+			return true, m, nil
+		}
+		if m.state == ViewHistory {
+			if m.historyIndex < len(m.historyVersions)-1 {
+				m.historyIndex++
+			}
+			return true, m, nil
+		}
+		if m.state == ViewAttachments && m.attachReport != nil {
+			if m.attachIndex < len(m.attachReport.Orphaned)-1 {
+				m.attachIndex++
+			}
+			return true, m, nil
+		}
+		if m.state == ViewDoctor {
+			if m.doctorIndex < len(m.doctorIssues)-1 {
+				m.doctorIndex++
+			}
+			return true, m, nil
+		}
+		if m.state == ViewSearch && msg.String() == "down" {
+			if m.searchIndex < len(m.searchResults)-1 {
+				m.searchIndex++
+			}
+			return true, m, nil
+		}
 
 	case "tab":
 		if m.state == ViewFormatSelector {
-			m.formatIndex = (m.formatIndex + 1) % 2
-			if m.formatIndex == 0 {
-				m.selectedFormat = models.FormatMarkdown
-			} else {
-				m.selectedFormat = models.FormatText
-			}
+			formats := models.Formats()
+			m.formatIndex = (m.formatIndex + 1) % len(formats)
+			m.selectedFormat = formats[m.formatIndex].Format
 			return true, m, nil
 		}
+		if m.state == ViewSearch {
+			return true, m, m.cycleSearchMode()
+		}
 
 	case "y", "Y":
 		if m.state == ViewDeleteConfirm {
 			newModel, cmd := m.deleteSelectedNote()
 			return true, newModel, cmd
 		}
+		if m.state == ViewTrashConfirm {
+			m.state = ViewTrash
+			if m.trashEmptying {
+				m.emptyTrash()
+			} else {
+				m.purgeTrashItem()
+			}
+			return true, m, nil
+		}
 
 	case "n", "N":
 		if m.state == ViewDeleteConfirm {
@@ -279,6 +502,11 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			m.statusMessage = ""
 			return true, m, nil
 		}
+		if m.state == ViewTrashConfirm {
+			m.state = ViewTrash
+			m.statusMessage = ""
+			return true, m, nil
+		}
 
 	case "1", "2", "3", "4", "5", "6", "7", "8", "9", "0":
 		if m.state == ViewHelp && m.helpTopic == "" {
@@ -331,6 +559,48 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (bool, tea.Model, tea.Cmd) {
 			m.statusMessage = ""
 			return true, m, nil
 		}
+
+	case "r", "R":
+		if m.state == ViewSwapRecovery {
+			newModel, cmd := m.recoverSwap()
+			return true, newModel, cmd
+		}
+		if m.state == ViewTrash {
+			m.restoreTrashItem()
+			return true, m, nil
+		}
+		if m.state == ViewHistory {
+			m.restoreHistoryVersion()
+			return true, m, nil
+		}
+
+	case "d", "D":
+		if m.state == ViewHome {
+			m.openDoctor()
+			return true, m, nil
+		}
+		if m.state == ViewSwapRecovery {
+			m.discardSwap()
+			return true, m, nil
+		}
+		if m.state == ViewTrash {
+			m.confirmTrashDelete(false)
+			return true, m, nil
+		}
+		if m.state == ViewHistory {
+			m.historyDiff = !m.historyDiff
+			return true, m, nil
+		}
+		if m.state == ViewAttachments {
+			m.trashOrphanedAttachment()
+			return true, m, nil
+		}
+
+	case "f", "F":
+		if m.state == ViewDoctor {
+			m.fixDoctorIssues()
+			return true, m, nil
+		}
 	}
 
 	// Key not handled globally, let component handle it
@@ -351,13 +621,16 @@ func (m *Model) handleEscape() (tea.Model, tea.Cmd) {
 		}
 		m.statusMessage = ""
 
-	case ViewNewFile, ViewFormatSelector, ViewTemplates, ViewThemes, ViewExport, ViewImport, ViewLinking, ViewStats, ViewGit, ViewSync, ViewNotebooks, ViewNotebookNameInput, ViewSelectNotebookForNote, ViewNoteNameInNotebook, ViewLanguageSelector:
+	case ViewNewFile, ViewFormatSelector, ViewTemplates, ViewThemes, ViewExport, ViewImport, ViewLinking, ViewStats, ViewGit, ViewSync, ViewNotebooks, ViewNotebookNameInput, ViewSelectNotebookForNote, ViewNoteNameInNotebook, ViewLanguageSelector, ViewVaults, ViewTrash, ViewAttachments, ViewDoctor:
 		// Clear inputs before going home
 		m.notebookNameInput.SetValue("")
 		m.fileNameInput.SetValue("")
 		m.selectedNotebook = ""
+		m.deletingNotebook = false
+		m.renamingNotebook = false
 		m.selectedLangIndex = 0
 		m.translating = false
+		m.attachReport = nil
 		m.state = ViewHome
 		m.statusMessage = ""
 
@@ -365,6 +638,53 @@ func (m *Model) handleEscape() (tea.Model, tea.Cmd) {
 		m.state = ViewList
 		m.statusMessage = ""
 
+	case ViewTrashConfirm:
+		m.state = ViewTrash
+		m.statusMessage = ""
+
+	case ViewSearch:
+		// Results are kept for the next search
+		m.searchInput.Blur()
+		m.state = m.searchReturn
+		m.statusMessage = ""
+
+	case ViewHistory:
+		m.historyVersions = nil
+		m.state = ViewEditor
+		m.statusMessage = ""
+
+	case ViewAttach:
+		m.fileNameInput.SetValue("")
+		m.state = ViewEditor
+		m.statusMessage = ""
+
+	case ViewRename:
+		// Note renames start from the list, notebook renames from the notebooks menu
+		m.state = ViewList
+		if m.renamingNotebook {
+			m.state = ViewHome
+		}
+		m.renamingNotebook = false
+		m.renamePath = ""
+		m.fileNameInput.SetValue("")
+		m.statusMessage = ""
+
+	case ViewSettings:
+		if m.settingsEditing {
+			// Cancel the edit but stay in settings
+			m.settingsEditing = false
+			m.settingsInput.Blur()
+		} else {
+			m.state = ViewHome
+		}
+		m.statusMessage = ""
+
+	case ViewSwapRecovery:
+		// Keep swap files on disk, they will be offered again on next launch
+		m.pendingSwaps = nil
+		m.state = ViewHome
+		m.statusMessage = styles.InfoStyle.Render(m.translate("Recovery skipped - unsaved changes kept for next launch"))
+
 	case ViewEditor:
 		// Just go back to home without saving
 		// Don't close file or stop auto-save - let auto-save continue in background
@@ -386,8 +706,7 @@ func (m *Model) handleEnter() (tea.Model, tea.Cmd) {
 		filename := m.fileNameInput.Value()
 		if filename != "" {
 			m.state = ViewFormatSelector
-			m.formatIndex = 0
-			m.selectedFormat = models.FormatMarkdown
+			m.resetFormatSelection()
 			m.statusMessage = ""
 		}
 		return m, nil
@@ -405,13 +724,20 @@ func (m *Model) handleEnter() (tea.Model, tea.Cmd) {
 	case ViewNotebookNameInput:
 		return m.createNotebook()
 
+	case ViewRename:
+		m.renameItem()
+		return m, nil
+
+	case ViewAttach:
+		m.attachFile()
+		return m, nil
+
 	case ViewNoteNameInNotebook:
 		// Move to format selector
 		filename := m.fileNameInput.Value()
 		if filename != "" {
 			m.state = ViewFormatSelector
-			m.formatIndex = 0
-			m.selectedFormat = models.FormatMarkdown
+			m.resetFormatSelection()
 			m.statusMessage = ""
 		}
 		return m, nil
@@ -420,6 +746,21 @@ func (m *Model) handleEnter() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// resetFormatSelection preselects the default format from the config
+func (m *Model) resetFormatSelection() {
+	m.formatIndex = 0
+	m.selectedFormat = models.FormatMarkdown
+	if m.config == nil {
+		return
+	}
+	for i, info := range models.Formats() {
+		if string(info.Format) == m.config.DefaultFormat {
+			m.formatIndex = i
+			m.selectedFormat = info.Format
+		}
+	}
+}
+
 // showList shows the note list
 func (m *Model) showList() (tea.Model, tea.Cmd) {
 	notes, err := m.storage.ListNotes()
@@ -434,227 +775,990 @@ func (m *Model) showList() (tea.Model, tea.Cmd) {
 	m.state = ViewList
 	m.statusMessage = ""
 
-	return m, nil
+	return m, nil
+}
+
+// createNewNote creates a new note
+func (m *Model) createNewNote() (tea.Model, tea.Cmd) {
+	filename := m.fileNameInput.Value()
+
+	file, path, err := m.storage.CreateNote(filename, m.selectedFormat)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		m.state = ViewHome
+		return m, nil
+	}
+
+	// Get template content if a template was selected
+	templateContent := ""
+	if m.selectedTemplate != "" {
+		tm := templates.NewTemplateManager(m.config.VaultDir)
+		template, err := tm.GetTemplate(m.selectedTemplate)
+		if err == nil {
+			templateContent = template.Content
+			if m.selectedFormat == models.FormatMarkdown {
+				templateContent = template.Render(filename)
+			}
+		}
+		// Reset selected template
+		m.selectedTemplate = ""
+	}
+
+	note, err := m.storage.GetNote(path)
+	if err != nil {
+		note = models.Note{
+			Name:   filename + m.selectedFormat.GetExtension(),
+			Path:   path,
+			Format: m.selectedFormat,
+		}
+	}
+
+	m.currentFile = file
+	m.currentNote = &note
+	m.state = ViewEditor
+	m.editor.SetValue(templateContent)
+	m.editor.Focus()
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("Created %s %s"), m.selectedFormat.GetIcon(), filename))
+
+	return m, nil
+}
+
+// openSelectedNote opens the selected note from the list
+func (m *Model) openSelectedNote() (tea.Model, tea.Cmd) {
+	if m.currentFile != nil {
+		return m, nil
+	}
+
+	item, ok := m.list.SelectedItem().(models.Note)
+	if !ok {
+		return m, nil
+	}
+	m.openNote(item)
+	return m, nil
+}
+
+// openNote opens a note in the editor and reports whether it could
+func (m *Model) openNote(item models.Note) bool {
+	content, err := m.storage.ReadNote(item.Path)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		return false
+	}
+
+	file, err := m.openNoteFile(item.Path)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		return false
+	}
+
+	m.editor.SetValue(content)
+	m.currentFile = file
+	m.currentNote = &item
+	m.state = ViewEditor
+
+	// Add to recent notes
+	if m.recentManager != nil {
+		_ = m.recentManager.AddRecent(&item)
+	}
+
+	// Start auto-save
+	if m.autoSaver != nil {
+		m.autoSaver.Start()
+	}
+	m.isEditorDirty = false
+
+	// Parse and show links if any
+	linker := linking.NewLinkManager()
+	links := linker.ParseLinks(content, item.Name)
+	if m.readOnly {
+		m.showReadOnlyStatus()
+	} else if len(links) > 0 {
+		linkInfo := m.translate(fmt.Sprintf("Found %d wiki-style links. Press Alt+L for link help.", len(links)))
+		m.statusMessage = styles.InfoStyle.Render(linkInfo)
+	} else {
+		m.statusMessage = styles.StatusStyle.Render(fmt.Sprintf(m.translate("Editing %s %s"), item.Format.GetIcon(), item.Name))
+	}
+
+	return true
+}
+
+// openNoteFile opens a note for editing
+// If another totion process has the note open, it is opened read-only instead
+func (m *Model) openNoteFile(path string) (*os.File, error) {
+	m.readOnly = false
+
+	file, err := m.storage.OpenNote(path)
+	if _, locked := lock.IsLocked(err); !locked {
+		return file, err
+	}
+
+	file, err = m.storage.OpenNoteReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	m.readOnly = true
+	return file, nil
+}
+
+// showReadOnlyStatus explains why the open note can't be edited, naming the
+// totion process that has it open
+func (m *Model) showReadOnlyStatus() {
+	holder := m.translate("another totion")
+	if m.currentNote != nil {
+		if h, ok := m.storage.LockHolder(m.currentNote.Path); ok {
+			holder = fmt.Sprintf(m.translate("another totion (%s)"), h)
+		}
+	}
+	m.statusMessage = styles.WarningStyle.Render(fmt.Sprintf(m.translate("🔒 Read-only: this note is open in %s. Ctrl+S closes it."), holder))
+}
+
+// isNavigationKey checks if a key only moves around the editor without changing it
+func isNavigationKey(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "up", "down", "left", "right", "home", "end", "pgup", "pgdown",
+		"ctrl+home", "ctrl+end", "ctrl+left", "ctrl+right", "alt+left", "alt+right",
+		"ctrl+a", "ctrl+e", "ctrl+f", "ctrl+b", "ctrl+p":
+		return true
+	}
+	return false
+}
+
+// saveCurrentNote saves the current note
+func (m *Model) saveCurrentNote() (tea.Model, tea.Cmd) {
+	if m.currentFile == nil {
+		return m, nil
+	}
+
+	// Nothing to save in a read-only note, Ctrl+S just closes it
+	if m.readOnly {
+		m.closeCurrentNote()
+		m.state = ViewHome
+		m.statusMessage = styles.InfoStyle.Render(m.translate("Closed read-only note"))
+		return m, nil
+	}
+
+	err := m.storage.SaveNote(m.currentFile, m.editor.Value())
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		return m, nil
+	}
+
+	// Close the file handle
+	if m.currentFile != nil {
+		_ = m.storage.CloseNote(m.currentFile)
+	}
+
+	// Stop auto-save
+	if m.autoSaver != nil {
+		m.autoSaver.Stop()
+	}
+	m.isEditorDirty = false
+	m.diskChanged = false
+
+	m.currentFile = nil
+	m.currentNote = nil
+	m.editor.SetValue("")
+	m.state = ViewHome
+	m.statusMessage = styles.SuccessStyle.Render(m.translate("✓ Note saved successfully!"))
+
+	return m, nil
+}
+
+// deleteSelectedNote deletes the selected note
+func (m *Model) deleteSelectedNote() (tea.Model, tea.Cmd) {
+	item, ok := m.list.SelectedItem().(models.Note)
+	if !ok {
+		m.state = ViewList
+		return m, nil
+	}
+
+	// Close the note first if it is open, otherwise auto-save would recreate it
+	if m.currentNote != nil && m.currentNote.Path == item.Path {
+		m.closeCurrentNote()
+	}
+
+	err := m.storage.DeleteNote(item.Path)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: " + err.Error()))
+		m.state = ViewList
+		return m, nil
+	}
+
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("🗑️  Moved %s to trash (X to restore)"), item.Name))
+	m.state = ViewHome
+
+	return m, nil
+}
+
+// startRenameNote asks for a new name for the selected note
+func (m *Model) startRenameNote() {
+	item, ok := m.list.SelectedItem().(models.Note)
+	if !ok {
+		return
+	}
+
+	m.renamePath = item.Path
+	m.renamingNotebook = false
+	m.fileNameInput.SetValue(strings.TrimSuffix(item.RelID, filepath.Ext(item.RelID)))
+	m.fileNameInput.CursorEnd()
+	m.fileNameInput.Focus()
+	m.state = ViewRename
+	m.statusMessage = ""
+}
+
+// startRenameNotebook asks for a new name for a notebook
+func (m *Model) startRenameNotebook(nb *notebook.Notebook) {
+	m.renamePath = nb.Path
+	m.fileNameInput.SetValue(nb.Name)
+	m.fileNameInput.CursorEnd()
+	m.fileNameInput.Focus()
+	m.state = ViewRename
+	m.statusMessage = ""
+}
+
+// renameItem renames the note or notebook in renamePath and rewrites links to it
+// A note name containing "/" moves the note to that path inside the vault
+func (m *Model) renameItem() {
+	newName := strings.TrimSpace(m.fileNameInput.Value())
+	if newName == "" {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Name cannot be empty"))
+		return
+	}
+
+	// Save pending edits first, the open note may be moved or have its links rewritten
+	if m.currentFile != nil && m.isEditorDirty && m.currentNote != nil {
+		if err := m.storage.WriteNote(m.currentNote.Path, m.editor.Value()); err != nil {
+			m.statusMessage = styles.ErrorStyle.Render(m.translate("Error saving note before rename: ") + err.Error())
+			return
+		}
+		m.isEditorDirty = false
+	}
+
+	renamer := rename.NewRenamer(m.storage, m.tagManager, m.pinnedManager, m.recentManager)
+
+	var report *rename.Report
+	var err error
+	switch {
+	case m.renamingNotebook:
+		report, err = renamer.RenameNotebook(m.renamePath, newName)
+	case strings.ContainsAny(newName, `/\`):
+		newPath := filepath.Join(m.storage.VaultDir(), filepath.FromSlash(newName))
+		if !storage.IsNoteFile(newPath) {
+			newPath += filepath.Ext(m.renamePath)
+		}
+		report, err = renamer.MoveNote(m.renamePath, newPath)
+	default:
+		report, err = renamer.RenameNote(m.renamePath, newName)
+	}
+
+	if report != nil {
+		m.applyRenameToEditor(report)
+	}
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error renaming: ") + err.Error())
+		return
+	}
+
+	m.state = ViewHome
+	m.renamingNotebook = false
+	m.renamePath = ""
+	m.fileNameInput.SetValue("")
+	m.statusMessage = styles.SuccessStyle.Render("✓ " + m.translate("Renamed: ") + report.Summary())
+}
+
+// applyRenameToEditor points the open note at its new path and reloads rewritten links
+func (m *Model) applyRenameToEditor(report *rename.Report) {
+	if m.currentFile == nil || m.currentNote == nil {
+		return
+	}
+
+	path := m.currentNote.Path
+	if newPath := report.NewPath(path); newPath != "" {
+		file, err := m.storage.OpenNote(newPath)
+		if err != nil {
+			return
+		}
+		_ = m.storage.CloseNote(m.currentFile)
+		m.currentFile = file
+		path = newPath
+	}
+
+	note, err := m.storage.GetNote(path)
+	if err != nil {
+		return
+	}
+	m.currentNote = &note
+
+	// The note was saved before renaming, so reloading it keeps every edit
+	if content, err := m.storage.ReadNote(path); err == nil && content != m.editor.Value() {
+		m.editor.SetValue(content)
+	}
+}
+
+// recoverSwap opens the first pending swap file in the editor
+func (m *Model) recoverSwap() (tea.Model, tea.Cmd) {
+	if len(m.pendingSwaps) == 0 || m.currentFile != nil {
+		m.state = ViewHome
+		return m, nil
+	}
+
+	sf := m.pendingSwaps[0]
+	m.pendingSwaps = m.pendingSwaps[1:]
+
+	content, err := m.storage.ReadSwap(sf)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		m.state = ViewHome
+		return m, nil
+	}
+
+	// Recreate the note if it was deleted after the swap was written
+	if _, statErr := m.storage.Stat(sf.NotePath); errors.Is(statErr, fs.ErrNotExist) {
+		err = m.storage.WriteFile(sf.NotePath, nil)
+	}
+	var file *os.File
+	if err == nil {
+		file, err = m.storage.OpenNote(sf.NotePath)
+	}
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		m.state = ViewHome
+		return m, nil
+	}
+
+	note, err := m.storage.GetNote(sf.NotePath)
+	if err != nil {
+		file.Close()
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		m.state = ViewHome
+		return m, nil
+	}
+
+	m.currentFile = file
+	m.currentNote = &note
+	m.editor.SetValue(content)
+	m.editor.Focus()
+	m.state = ViewEditor
+
+	// Recovered content is unsaved until the user saves it
+	m.isEditorDirty = true
+	if m.autoSaver != nil {
+		m.autoSaver.Start()
+	}
+
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("♻️  Recovered unsaved changes for %s - press Ctrl+S to save"), note.DisplayName()))
+	return m, nil
+}
+
+// discardSwap deletes the first pending swap file
+func (m *Model) discardSwap() {
+	if len(m.pendingSwaps) == 0 {
+		m.state = ViewHome
+		return
+	}
+
+	sf := m.pendingSwaps[0]
+	m.pendingSwaps = m.pendingSwaps[1:]
+
+	if err := m.storage.RemoveSwap(sf.NotePath); err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+	} else {
+		m.statusMessage = styles.InfoStyle.Render(fmt.Sprintf(m.translate("Discarded unsaved changes for %s"), sf.NoteName()))
+	}
+
+	if len(m.pendingSwaps) == 0 {
+		m.state = ViewHome
+	}
+}
+
+// selectTemplate selects and applies a template
+func (m *Model) selectTemplate(key string) (tea.Model, tea.Cmd) {
+	templateNames := []string{
+		m.translate("Meeting Notes"),
+		m.translate("Todo List"),
+		m.translate("Journal Entry"),
+		m.translate("Project Plan"),
+		m.translate("Code Snippet"),
+		m.translate("Book Notes"),
+		m.translate("Blank"),
+	}
+
+	index := 0
+	switch key {
+	case "1":
+		index = 0
+	case "2":
+		index = 1
+	case "3":
+		index = 2
+	case "4":
+		index = 3
+	case "5":
+		index = 4
+	case "6":
+		index = 5
+	case "7":
+		index = 6
+	default:
+		return m, nil
+	}
+
+	// Store selected template name
+	m.selectedTemplate = templateNames[index]
+
+	// Transition to new file creation with template
+	m.state = ViewNewFile
+	m.selectedFormat = models.FormatMarkdown
+	m.fileNameInput.SetValue("")
+	m.fileNameInput.Focus()
+	m.statusMessage = styles.SuccessStyle.Render(m.translate(fmt.Sprintf("Selected: %s template", templateNames[index])))
+
+	return m, nil
+}
+
+// selectTheme selects and applies a theme
+func (m *Model) selectTheme(key string) {
+	themeNames := []string{
+		"Default (Blue)",
+		"Dark",
+		"Light",
+		"Monokai",
+		"Solarized Dark",
+		"Nord",
+	}
+
+	index := 0
+	switch key {
+	case "1":
+		index = 0
+	case "2":
+		index = 1
+	case "3":
+		index = 2
+	case "4":
+		index = 3
+	case "5":
+		index = 4
+	case "6":
+		index = 5
+	default:
+		return
+	}
+
+	// Remember the theme across restarts
+	themeKey := themes.BuiltinThemes[index]
+	m.themeManager.SetTheme(themeKey)
+	m.state = ViewHome
+	if err := m.saveSetting("theme", themeKey); err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error saving settings: ") + err.Error())
+		return
+	}
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("✓ Theme changed to: %s"), themeNames[index]))
+}
+
+// handleSettingsEnter starts editing the selected setting or saves the edit
+func (m *Model) handleSettingsEnter() {
+	fields := config.Fields()
+	if m.settingsIndex >= len(fields) {
+		return
+	}
+	field := fields[m.settingsIndex]
+
+	if !m.settingsEditing {
+		value, _ := m.config.Get(field.Key)
+		m.settingsInput.SetValue(value)
+		m.settingsInput.CursorEnd()
+		m.settingsInput.Focus()
+		m.settingsEditing = true
+		m.statusMessage = styles.InfoStyle.Render(m.translate(field.Description))
+		return
+	}
+
+	if err := m.saveSetting(field.Key, m.settingsInput.Value()); err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Invalid setting: ") + err.Error())
+		return
+	}
+
+	m.settingsEditing = false
+	m.settingsInput.Blur()
+	if field.Restart {
+		m.statusMessage = styles.WarningStyle.Render(fmt.Sprintf(m.translate("✓ %s saved - restart Totion to apply"), field.Key))
+	} else {
+		m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("✓ %s saved"), field.Key))
+	}
+}
+
+// saveSetting validates, stores and applies a single setting
+func (m *Model) saveSetting(key, value string) error {
+	if err := m.config.Set(key, value); err != nil {
+		return err
+	}
+	if err := m.config.Save(); err != nil {
+		return err
+	}
+
+	// Apply settings that don't need a restart
+	switch key {
+	case "theme":
+		m.themeManager.SetTheme(m.config.Theme)
+	case "max_recent":
+		m.recentManager = recent.NewRecentManager(m.getVaultDir(), m.config.MaxRecent)
+	case "max_pinned":
+		m.pinnedManager = pinned.NewPinnedManager(m.getVaultDir(), m.config.MaxPinned)
+	case "history.enabled", "history.max_versions", "history.retention_days":
+		m.applyHistoryPolicy()
+	case "search.index", "search.stem":
+		m.applySearchOptions()
+	}
+
+	return nil
+}
+
+// closeCurrentNote closes the open note without saving and stops auto-save
+func (m *Model) closeCurrentNote() {
+	if m.autoSaver != nil {
+		m.autoSaver.Stop()
+	}
+	if m.currentFile != nil {
+		_ = m.storage.CloseNote(m.currentFile)
+	}
+	if m.currentNote != nil && !m.readOnly {
+		_ = m.storage.RemoveSwap(m.currentNote.Path)
+	}
+	m.currentFile = nil
+	m.currentNote = nil
+	m.isEditorDirty = false
+	m.diskChanged = false
+	m.readOnly = false
+	m.editor.SetValue("")
+}
+
+// applyHistoryPolicy passes the history settings to the note history
+func (m *Model) applyHistoryPolicy() {
+	m.storage.History().SetPolicy(history.Policy{
+		Enabled:     m.config.History.Enabled,
+		MaxVersions: m.config.History.MaxVersions,
+		MaxAge:      m.config.History.Retention(),
+	})
+}
+
+// applySearchOptions passes the search settings to the vault's search index
+// and brings the index up to date in the background
+func (m *Model) applySearchOptions() {
+	index := search.IndexFor(m.storage)
+	index.SetOptions(search.IndexOptions{
+		Enabled: m.config.Search.Index,
+		Stem:    m.config.Search.Stem,
+	})
+	if m.config.Search.Index {
+		index.RefreshInBackground()
+	}
+}
+
+// openHistory lists the saved versions of the open note and shows the history view
+func (m *Model) openHistory() {
+	versions, err := m.storage.History().Versions(m.currentNote.Path)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error reading history: ") + err.Error())
+		return
+	}
+
+	m.historyVersions = versions
+	m.historyIndex = 0
+	m.historyDiff = false
+	m.state = ViewHistory
+	m.statusMessage = ""
+}
+
+// restoreHistoryVersion replaces the open note with the selected version
+// The current text is saved first, so the restore itself can be undone from history
+func (m *Model) restoreHistoryVersion() {
+	if m.currentNote == nil || m.historyIndex >= len(m.historyVersions) {
+		return
+	}
+	version := m.historyVersions[m.historyIndex]
+
+	content, err := m.storage.History().Read(version)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error restoring version: ") + err.Error())
+		return
+	}
+
+	if m.isEditorDirty {
+		if err := m.storage.WriteNote(m.currentNote.Path, m.editor.Value()); err != nil {
+			m.statusMessage = styles.ErrorStyle.Render(m.translate("Error saving note: ") + err.Error())
+			return
+		}
+	}
+	if err := m.storage.WriteNote(m.currentNote.Path, content); err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error restoring version: ") + err.Error())
+		return
+	}
+	_ = m.storage.RemoveSwap(m.currentNote.Path)
+
+	m.editor.SetValue(content)
+	m.isEditorDirty = false
+	m.diskChanged = false
+	m.historyVersions = nil
+	m.state = ViewEditor
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("🕘 Restored version from %s"), version.SavedAt.Format("2006-01-02 15:04")))
+}
+
+// attachFile copies the file named in the input into the vault and inserts a link to it
+func (m *Model) attachFile() {
+	src := strings.TrimSpace(m.fileNameInput.Value())
+	// Terminals quote or escape dropped file paths
+	src = strings.Trim(src, `"'`)
+	src = strings.ReplaceAll(src, `\ `, " ")
+	if strings.HasPrefix(src, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			src = filepath.Join(home, src[2:])
+		}
+	}
+	if src == "" {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("File path cannot be empty"))
+		return
+	}
+
+	manager := attachments.NewAttachmentManager(m.storage, m.config.Attachments.PerNotebook)
+	link, err := manager.Add(m.currentNote.Path, src)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error attaching file: ") + err.Error())
+		return
+	}
+
+	m.editor.InsertString(link)
+	m.isEditorDirty = true
+	m.fileNameInput.SetValue("")
+	m.state = ViewEditor
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("📎 Attached %s"), filepath.Base(src)))
+}
+
+// openAttachments scans the vault for orphaned and missing attachments
+func (m *Model) openAttachments() {
+	report, err := attachments.NewAttachmentManager(m.storage, m.config.Attachments.PerNotebook).Scan()
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error scanning attachments: ") + err.Error())
+		return
+	}
+
+	m.attachReport = report
+	m.attachIndex = 0
+	m.state = ViewAttachments
+	m.statusMessage = ""
+}
+
+// trashOrphanedAttachment moves the selected orphaned attachment to the trash
+func (m *Model) trashOrphanedAttachment() {
+	if m.attachReport == nil || m.attachIndex >= len(m.attachReport.Orphaned) {
+		return
+	}
+	path := m.attachReport.Orphaned[m.attachIndex]
+
+	if _, err := m.storage.Trash().Trash(path); err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error moving attachment to trash: ") + err.Error())
+		return
+	}
+
+	report := m.attachReport
+	report.Orphaned = append(report.Orphaned[:m.attachIndex], report.Orphaned[m.attachIndex+1:]...)
+	report.Attachments--
+	if m.attachIndex >= len(report.Orphaned) && m.attachIndex > 0 {
+		m.attachIndex = len(report.Orphaned) - 1
+	}
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("🗑️  Moved %s to trash"), filepath.Base(path)))
+}
+
+// openDoctor scans the vault for problems and shows the doctor view
+func (m *Model) openDoctor() {
+	issues, err := doctor.NewDoctor(m.storage).Scan()
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error checking vault: ") + err.Error())
+		return
+	}
+
+	m.doctorIssues = issues
+	m.doctorIndex = 0
+	m.state = ViewDoctor
+	m.statusMessage = ""
+}
+
+// fixDoctorIssues repairs the fixable problems and scans again
+func (m *Model) fixDoctorIssues() {
+	fixed, err := doctor.NewDoctor(m.storage).Fix(m.doctorIssues)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error fixing vault: ") + err.Error())
+		return
+	}
+
+	// Pins, recent notes and tags were changed on disk, reload them
+	vaultDir := m.getVaultDir()
+	m.recentManager = recent.NewRecentManager(vaultDir, m.config.MaxRecent)
+	m.pinnedManager = pinned.NewPinnedManager(vaultDir, m.config.MaxPinned)
+	m.tagManager = tags.NewTagManager(m.storage, vaultDir)
+
+	m.openDoctor()
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("🩺 Fixed %d problem(s)"), fixed))
+}
+
+// openSearch shows the search view, with the results of the last search
+func (m *Model) openSearch() {
+	m.searchReturn = m.state
+	m.searchInput.SetValue(m.searchQuery)
+	m.searchInput.CursorEnd()
+	m.searchInput.Focus()
+	m.state = ViewSearch
+	m.statusMessage = ""
+}
+
+// searchDoneMsg carries the results of a search run in the background
+type searchDoneMsg struct {
+	manager *search.SearchManager // Manager that ran the search, stale after a newer search
+	results []search.NoteResult
+	err     error
+}
+
+// runSearch returns a command that searches the vault for the query in the
+// search input, so a slow search doesn't freeze the interface
+func (m *Model) runSearch() tea.Cmd {
+	query := strings.TrimSpace(m.searchInput.Value())
+	m.searchQuery = query
+	m.searchIndex = 0
+	m.searchResults = nil
+	m.searching = true
+	m.statusMessage = ""
+
+	// Each search gets its own manager, the one still running keeps its mode
+	manager := search.NewSearchManager(m.storage)
+	manager.SetMode(m.searchMode)
+	m.searchManager = manager
+
+	return func() tea.Msg {
+		results, err := manager.SearchNotes(query)
+		return searchDoneMsg{manager: manager, results: results, err: err}
+	}
 }
 
-// createNewNote creates a new note
-func (m *Model) createNewNote() (tea.Model, tea.Cmd) {
-	filename := m.fileNameInput.Value()
+// showSearchResults shows the results of the last search
+func (m *Model) showSearchResults(msg searchDoneMsg) {
+	m.searching = false
+	m.statusMessage = ""
 
-	file, path, err := m.storage.CreateNote(filename, m.selectedFormat)
-	if err != nil {
-		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
-		m.state = ViewHome
-		return m, nil
+	var limitErr *search.LimitError
+	if errors.As(msg.err, &limitErr) {
+		// A regex search that matched too much, show what it found
+		m.searchResults = msg.results
+		m.statusMessage = styles.WarningStyle.Render(m.translate("⚠️  Showing the first matches, the ") + msg.err.Error())
+		return
 	}
-
-	// Get template content if a template was selected
-	templateContent := ""
-	if m.selectedTemplate != "" {
-		tm := templates.NewTemplateManager()
-		template, err := tm.GetTemplate(m.selectedTemplate)
-		if err == nil {
-			templateContent = template.Content
-		}
-		// Reset selected template
-		m.selectedTemplate = ""
+	if msg.err != nil {
+		m.searchResults = nil
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error searching: ") + msg.err.Error())
+		return
 	}
+	m.searchResults = msg.results
+}
 
-	m.currentFile = file
-	m.currentNote = &models.Note{
-		Name:   filename + m.selectedFormat.GetExtension(),
-		Path:   path,
-		Format: m.selectedFormat,
+// cycleSearchMode switches between exact, fuzzy and regex search and searches
+// again with the new mode
+func (m *Model) cycleSearchMode() tea.Cmd {
+	m.searchMode = search.Modes[(int(m.searchMode)+1)%len(search.Modes)]
+	if query := strings.TrimSpace(m.searchInput.Value()); query != "" {
+		return m.runSearch()
 	}
-	m.state = ViewEditor
-	m.editor.SetValue(templateContent)
-	m.editor.Focus()
-	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("Created %s %s"), m.selectedFormat.GetIcon(), filename))
-
-	return m, nil
+	return nil
 }
 
-// openSelectedNote opens the selected note from the list
-func (m *Model) openSelectedNote() (tea.Model, tea.Cmd) {
-	if m.currentFile != nil {
-		return m, nil
+// moveSearchPage selects the first note of the next or previous page of results
+func (m *Model) moveSearchPage(next bool) {
+	size := m.searchPageSize()
+	page := m.searchIndex / size
+	if next {
+		if (page+1)*size < len(m.searchResults) {
+			m.searchIndex = (page + 1) * size
+		}
+	} else if page > 0 {
+		m.searchIndex = (page - 1) * size
 	}
+}
 
-	item, ok := m.list.SelectedItem().(models.Note)
-	if !ok {
-		return m, nil
+// searchPageSize returns how many notes fit on a page of search results
+// Each note takes a line plus up to searchMatchLines matches and a "more" line
+func (m *Model) searchPageSize() int {
+	size := (m.height - 20) / (searchMatchLines + 2)
+	if size < 3 {
+		size = 3
 	}
+	return size
+}
 
-	content, err := m.storage.ReadNote(item.Path)
-	if err != nil {
-		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
-		return m, nil
+// openSearchResult opens the selected note at its first match
+func (m *Model) openSearchResult() {
+	if m.searchIndex >= len(m.searchResults) {
+		return
 	}
+	result := m.searchResults[m.searchIndex]
 
-	file, err := m.storage.OpenNote(item.Path)
-	if err != nil {
-		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
-		return m, nil
+	if m.currentNote != nil && m.currentNote.Path != result.NotePath {
+		m.statusMessage = styles.WarningStyle.Render(fmt.Sprintf(m.translate("⚠️  Save and close %s first (Ctrl+S)"), m.currentNote.DisplayName()))
+		return
+	}
+	if m.currentNote == nil {
+		note, err := m.storage.GetNote(result.NotePath)
+		if err != nil {
+			m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+			return
+		}
+		if !m.openNote(note) {
+			return
+		}
 	}
-
-	m.editor.SetValue(content)
-	m.currentFile = file
-	m.currentNote = &item
 	m.state = ViewEditor
+	m.editor.Focus()
 
-	// Add to recent notes
-	if m.recentManager != nil {
-		_ = m.recentManager.AddRecent(&item)
+	// Put the cursor on the first matching line
+	line := 0
+	if len(result.Matches) > 0 {
+		line = result.Matches[0].LineNumber
 	}
-
-	// Start auto-save
-	if m.autoSaver != nil {
-		m.autoSaver.Start()
+	for m.editor.Line() > 0 {
+		m.editor.CursorUp()
 	}
-	m.isEditorDirty = false
+	for m.editor.Line() < line-1 {
+		row, info := m.editor.Line(), m.editor.LineInfo()
+		m.editor.CursorDown() // Moves by screen row, wrapped lines take several
+		if m.editor.Line() == row && m.editor.LineInfo() == info {
+			break // Last line
+		}
+	}
+	m.editor.CursorStart()
+}
 
-	// Parse and show links if any
-	linker := linking.NewLinkManager()
-	links := linker.ParseLinks(content, item.Name)
-	if len(links) > 0 {
-		linkInfo := m.translate(fmt.Sprintf("Found %d wiki-style links. Press Alt+L for link help.", len(links)))
-		m.statusMessage = styles.InfoStyle.Render(linkInfo)
-	} else {
-		m.statusMessage = styles.StatusStyle.Render(fmt.Sprintf(m.translate("Editing %s %s"), item.Format.GetIcon(), item.Name))
+// openTrash loads the trash contents and shows the trash view
+func (m *Model) openTrash() {
+	items, err := m.storage.Trash().List()
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error reading trash: ") + err.Error())
+		return
 	}
 
-	return m, nil
+	m.trashItems = items
+	m.trashIndex = 0
+	m.state = ViewTrash
+	m.statusMessage = ""
 }
 
-// saveCurrentNote saves the current note
-func (m *Model) saveCurrentNote() (tea.Model, tea.Cmd) {
-	if m.currentFile == nil {
-		return m, nil
+// restoreTrashItem moves the selected trash item back to its original place
+func (m *Model) restoreTrashItem() {
+	if m.trashIndex >= len(m.trashItems) {
+		return
 	}
+	item := m.trashItems[m.trashIndex]
 
-	err := m.storage.SaveNote(m.currentFile, m.editor.Value())
+	restoredPath, err := m.storage.Trash().Restore(item.ID)
 	if err != nil {
-		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
-		return m, nil
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error restoring: ") + err.Error())
+		return
 	}
 
-	// Close the file handle
-	if m.currentFile != nil {
-		m.currentFile.Close()
-	}
+	m.reloadTrash()
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("♻️  Restored %s"), restoredPath))
+}
 
-	// Stop auto-save
-	if m.autoSaver != nil {
-		m.autoSaver.Stop()
+// purgeTrashItem permanently deletes the selected trash item
+func (m *Model) purgeTrashItem() {
+	if m.trashIndex >= len(m.trashItems) {
+		return
 	}
-	m.isEditorDirty = false
+	item := m.trashItems[m.trashIndex]
 
-	m.currentFile = nil
-	m.currentNote = nil
-	m.editor.SetValue("")
-	m.state = ViewHome
-	m.statusMessage = styles.SuccessStyle.Render(m.translate("✓ Note saved successfully!"))
+	if err := m.storage.Trash().Purge(item.ID); err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		return
+	}
 
-	return m, nil
+	m.reloadTrash()
+	m.statusMessage = styles.WarningStyle.Render(fmt.Sprintf(m.translate("Permanently deleted %s"), item.Name))
 }
 
-// deleteSelectedNote deletes the selected note
-func (m *Model) deleteSelectedNote() (tea.Model, tea.Cmd) {
-	item, ok := m.list.SelectedItem().(models.Note)
-	if !ok {
-		m.state = ViewList
-		return m, nil
+// confirmTrashDelete asks before permanently deleting the selected item, or
+// everything in the trash
+func (m *Model) confirmTrashDelete(all bool) {
+	if len(m.trashItems) == 0 || (!all && m.trashIndex >= len(m.trashItems)) {
+		return
 	}
+	m.trashEmptying = all
+	m.state = ViewTrashConfirm
+	m.statusMessage = m.translate("Press 'y' to delete forever, 'n' to cancel")
+}
 
-	err := m.storage.DeleteNote(item.Path)
+// emptyTrash permanently deletes everything in the trash
+func (m *Model) emptyTrash() {
+	count, err := m.storage.Trash().Empty()
 	if err != nil {
-		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: " + err.Error()))
-		m.state = ViewList
-		return m, nil
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		return
 	}
 
-	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("✓ Deleted %s"), item.Name))
-	m.state = ViewHome
-
-	return m, nil
+	m.reloadTrash()
+	m.statusMessage = styles.WarningStyle.Render(fmt.Sprintf(m.translate("Permanently deleted %d items"), count))
 }
 
-// selectTemplate selects and applies a template
-func (m *Model) selectTemplate(key string) (tea.Model, tea.Cmd) {
-	templateNames := []string{
-		m.translate("Meeting Notes"),
-		m.translate("Todo List"),
-		m.translate("Journal Entry"),
-		m.translate("Project Plan"),
-		m.translate("Code Snippet"),
-		m.translate("Book Notes"),
-		m.translate("Blank"),
+// reloadTrash refreshes the trash view after a change
+func (m *Model) reloadTrash() {
+	items, err := m.storage.Trash().List()
+	if err != nil {
+		items = nil
 	}
-
-	index := 0
-	switch key {
-	case "1":
-		index = 0
-	case "2":
-		index = 1
-	case "3":
-		index = 2
-	case "4":
-		index = 3
-	case "5":
-		index = 4
-	case "6":
-		index = 5
-	case "7":
-		index = 6
-	default:
-		return m, nil
+	m.trashItems = items
+	if m.trashIndex >= len(m.trashItems) && m.trashIndex > 0 {
+		m.trashIndex = len(m.trashItems) - 1
 	}
+}
 
-	// Store selected template name
-	m.selectedTemplate = templateNames[index]
-
-	// Transition to new file creation with template
-	m.state = ViewNewFile
-	m.selectedFormat = models.FormatMarkdown
-	m.fileNameInput.SetValue("")
-	m.fileNameInput.Focus()
-	m.statusMessage = styles.SuccessStyle.Render(m.translate(fmt.Sprintf("Selected: %s template", templateNames[index])))
+// switchVault switches to another vault without restarting
+// The open note is saved and closed first since it belongs to the old vault
+// Returns the command that listens for changes in the new vault
+func (m *Model) switchVault(name string) tea.Cmd {
+	if name == m.config.ActiveVault().Name {
+		m.state = ViewHome
+		m.statusMessage = styles.InfoStyle.Render(fmt.Sprintf(m.translate("Already in vault %s"), name))
+		return nil
+	}
 
-	return m, nil
-}
+	if m.currentFile != nil {
+		if m.isEditorDirty && m.currentNote != nil {
+			if err := m.storage.WriteNote(m.currentNote.Path, m.editor.Value()); err != nil {
+				m.statusMessage = styles.ErrorStyle.Render(m.translate("Error saving note before switching vault: ") + err.Error())
+				return nil
+			}
+		}
+		m.closeCurrentNote()
+	}
 
-// selectTheme selects and applies a theme
-func (m *Model) selectTheme(key string) {
-	themeNames := []string{
-		"Default (Blue)",
-		"Dark",
-		"Light",
-		"Monokai",
-		"Solarized Dark",
-		"Nord",
+	previous := m.config.ActiveVault()
+	profile, err := m.config.UseVault(name)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		return nil
 	}
 
-	index := 0
-	switch key {
-	case "1":
-		index = 0
-	case "2":
-		index = 1
-	case "3":
-		index = 2
-	case "4":
-		index = 3
-	case "5":
-		index = 4
-	case "6":
-		index = 5
-	default:
-		return
+	if err := os.MkdirAll(profile.Path, 0750); err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error creating vault directory: ") + err.Error())
+		return nil
 	}
 
 	m.state = ViewHome
-	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("✓ Theme changed to: %s"), themeNames[index]))
+	if err := m.initVault(); err != nil {
+		// Stay in the vault we were in
+		if _, err := m.config.UseVault(previous.Name); err != nil {
+			_, _ = m.config.UseVault(previous.Path)
+		}
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error opening vault: ") + err.Error())
+		return nil
+	}
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("📚 Switched to vault %s (%s)"), profile.Name, profile.Path))
+	return m.waitForVaultChanges()
 }
 
 // handleExport handles export operations
@@ -666,12 +1770,24 @@ func (m *Model) handleExport(key string) {
 	}
 
 	exporter := export.NewExporter()
-	content := m.editor.Value()
+	content := export.ToMarkdown(m.editor.Value(), m.currentNote.Format)
 	outputPath := "/tmp/" + m.currentNote.Name
 
 	var err error
 	var format string
 
+	switch key {
+	case "1", "2", "3", "4":
+		// Copy linked attachments next to the export and point the links at them
+		files := export.LoadAttachments(m.storage, m.currentNote.Path, content)
+		content, err = exporter.WriteAttachments(content, files, filepath.Dir(outputPath))
+		if err != nil {
+			m.statusMessage = styles.ErrorStyle.Render(fmt.Sprintf(m.translate("Export failed: %v"), err))
+			m.state = ViewHome
+			return
+		}
+	}
+
 	switch key {
 	case "1": // HTML
 		err = exporter.ExportToHTML(content, m.currentNote.Name, outputPath+".html")
@@ -700,7 +1816,7 @@ func (m *Model) handleExport(key string) {
 // handleImport handles import operations
 func (m *Model) handleImport(key string) {
 	// Get vault directory from storage
-	vaultDir := os.ExpandEnv("$HOME/.totion")
+	vaultDir := m.getVaultDir()
 	importer := importpkg.NewImporter(vaultDir)
 	var message string
 
@@ -734,7 +1850,7 @@ func (m *Model) handleImport(key string) {
 		}
 	case "3": // Plain Text
 		// For plain text, show instructions
-		message = "Place .md or .txt files in ~/.totion/ manually, or use git clone to import from repositories"
+		message = fmt.Sprintf("Place .md or .txt files in %s manually, or use git clone to import from repositories", vaultDir)
 		m.statusMessage = styles.InfoStyle.Render(m.translate("💡 ") + message)
 	default:
 		return
@@ -745,7 +1861,7 @@ func (m *Model) handleImport(key string) {
 
 // getVaultDir returns the vault directory path
 func (m *Model) getVaultDir() string {
-	return filepath.Join(os.Getenv("HOME"), ".totion")
+	return m.config.VaultDir
 }
 
 // handleGitAction performs git actions
@@ -784,9 +1900,8 @@ func (m *Model) handleGitAction(key string) {
 
 // handleSyncAction performs sync/backup actions
 func (m *Model) handleSyncAction(key string) {
-	vaultDir := m.getVaultDir()
 	syncDir := filepath.Join(os.TempDir(), "totion_sync")
-	syncer := sync.NewSyncManager(vaultDir, syncDir)
+	syncer := sync.NewSyncManager(m.storage, syncDir)
 
 	switch key {
 	case "1": // Backup
@@ -819,11 +1934,9 @@ func (m *Model) handleSyncAction(key string) {
 
 // handleStatsView shows statistics for the current note or vault
 func (m *Model) handleStatsView() string {
-	homeDir, _ := os.UserHomeDir()
-	configDir := filepath.Join(homeDir, ".totion")
-	statsManager := stats.NewStatsManagerWithConfig(configDir)
 	vaultDir := m.getVaultDir()
-	nbManager := notebook.NewNotebookManager(vaultDir)
+	statsManager := stats.NewStatsManagerWithConfig(vaultDir)
+	nbManager := notebook.NewNotebookManager(m.storage)
 
 	// Get all notes for vault stats
 	notes, _ := m.storage.ListNotes()
@@ -927,8 +2040,7 @@ func (m *Model) handleSyncView() string {
 
 // handleNotebooksView shows notebook management
 func (m *Model) handleNotebooksView() string {
-	vaultDir := m.getVaultDir()
-	nbManager := notebook.NewNotebookManager(vaultDir)
+	nbManager := notebook.NewNotebookManager(m.storage)
 
 	notebooksTitle := styles.TitleStyle.Render(m.translate("📂 NOTEBOOKS & FOLDERS"))
 
@@ -972,8 +2084,7 @@ func (m *Model) handleNotebooksView() string {
 
 // handleNotebookAction performs notebook actions
 func (m *Model) handleNotebookAction(key string) {
-	vaultDir := m.getVaultDir()
-	nbManager := notebook.NewNotebookManager(vaultDir)
+	nbManager := notebook.NewNotebookManager(m.storage)
 
 	switch key {
 	case "1": // Create New Notebook
@@ -999,11 +2110,25 @@ func (m *Model) handleNotebookAction(key string) {
 		m.statusMessage = styles.InfoStyle.Render(m.translate("Feature coming soon: Select note and destination notebook"))
 		m.state = ViewHome
 	case "4": // Rename Notebook
-		m.statusMessage = styles.InfoStyle.Render(m.translate("Feature coming soon: Select notebook to rename"))
-		m.state = ViewHome
+		notebooks, err := nbManager.ListNotebooks()
+		if err != nil || len(notebooks) == 0 {
+			m.statusMessage = styles.ErrorStyle.Render(m.translate("No notebooks found. Create one first with option 1."))
+			m.state = ViewHome
+		} else {
+			m.renamingNotebook = true
+			m.state = ViewSelectNotebookForNote
+			m.statusMessage = styles.InfoStyle.Render(m.translate("Select a notebook to rename (type the number):"))
+		}
 	case "5": // Delete Notebook
-		m.statusMessage = styles.InfoStyle.Render(m.translate("Feature coming soon: Select notebook to delete"))
-		m.state = ViewHome
+		notebooks, err := nbManager.ListNotebooks()
+		if err != nil || len(notebooks) == 0 {
+			m.statusMessage = styles.ErrorStyle.Render(m.translate("No notebooks found. Create one first with option 1."))
+			m.state = ViewHome
+		} else {
+			m.deletingNotebook = true
+			m.state = ViewSelectNotebookForNote
+			m.statusMessage = styles.InfoStyle.Render(m.translate("Select a notebook to move to trash (type the number):"))
+		}
 	case "6": // Create Note in Notebook
 		// Show list of notebooks to select from
 		notebooks, err := nbManager.ListNotebooks()
@@ -1019,6 +2144,24 @@ func (m *Model) handleNotebookAction(key string) {
 	}
 }
 
+// deleteNotebook moves a notebook to the trash
+func (m *Model) deleteNotebook(nbManager *notebook.NotebookManager, nb *notebook.Notebook) {
+	m.deletingNotebook = false
+	m.state = ViewHome
+
+	// Close the open note if it lives in this notebook
+	if m.currentNote != nil && strings.HasPrefix(m.currentNote.Path, nb.Path+string(filepath.Separator)) {
+		m.closeCurrentNote()
+	}
+
+	if err := nbManager.DeleteNotebook(nb.Path); err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		return
+	}
+
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("🗑️  Moved notebook %s to trash (X to restore)"), nb.Name))
+}
+
 // createNotebook creates a new notebook folder
 func (m *Model) createNotebook() (tea.Model, tea.Cmd) {
 	notebookName := m.notebookNameInput.Value()
@@ -1028,8 +2171,7 @@ func (m *Model) createNotebook() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	vaultDir := m.getVaultDir()
-	nbManager := notebook.NewNotebookManager(vaultDir)
+	nbManager := notebook.NewNotebookManager(m.storage)
 
 	err := nbManager.CreateNotebook(notebookName)
 	if err != nil {
@@ -1047,8 +2189,7 @@ func (m *Model) createNotebook() (tea.Model, tea.Cmd) {
 
 // selectNotebookForNote handles notebook selection for creating a note
 func (m *Model) selectNotebookForNote(key string) {
-	vaultDir := m.getVaultDir()
-	nbManager := notebook.NewNotebookManager(vaultDir)
+	nbManager := notebook.NewNotebookManager(m.storage)
 
 	notebooks, err := nbManager.ListNotebooks()
 	if err != nil || len(notebooks) == 0 {
@@ -1080,6 +2221,16 @@ func (m *Model) selectNotebookForNote(key string) {
 		index = 8
 	}
 
+	if index >= 0 && index < len(notebooks) && m.deletingNotebook {
+		m.deleteNotebook(nbManager, notebooks[index])
+		return
+	}
+
+	if index >= 0 && index < len(notebooks) && m.renamingNotebook {
+		m.startRenameNotebook(notebooks[index])
+		return
+	}
+
 	if index >= 0 && index < len(notebooks) {
 		m.selectedNotebook = notebooks[index].Name
 		m.state = ViewNoteNameInNotebook
@@ -1127,20 +2278,28 @@ func (m *Model) createNoteInNotebook() (tea.Model, tea.Cmd) {
 	// Get template content if a template was selected
 	templateContent := ""
 	if m.selectedTemplate != "" {
-		tm := templates.NewTemplateManager()
+		tm := templates.NewTemplateManager(m.config.VaultDir)
 		template, err := tm.GetTemplate(m.selectedTemplate)
 		if err == nil {
 			templateContent = template.Content
+			if m.selectedFormat == models.FormatMarkdown {
+				templateContent = template.Render(filename)
+			}
 		}
 		m.selectedTemplate = ""
 	}
 
-	m.currentFile = file
-	m.currentNote = &models.Note{
-		Name:   filename + ext,
-		Path:   notePath,
-		Format: m.selectedFormat,
+	note, err := m.storage.GetNote(notePath)
+	if err != nil {
+		note = models.Note{
+			Name:   filename + ext,
+			Path:   notePath,
+			Format: m.selectedFormat,
+		}
 	}
+
+	m.currentFile = file
+	m.currentNote = &note
 	m.state = ViewEditor
 	m.editor.SetValue(templateContent)
 	m.editor.Focus()
@@ -2,12 +2,20 @@ package app
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"unicode"
 
+	"github.com/0xshariq/totion/internal/config"
+	"github.com/0xshariq/totion/internal/features/doctor"
+	"github.com/0xshariq/totion/internal/features/history"
+	"github.com/0xshariq/totion/internal/features/search"
 	"github.com/0xshariq/totion/internal/models"
 	"github.com/0xshariq/totion/internal/notebook"
 	"github.com/0xshariq/totion/internal/ui/help"
 	"github.com/0xshariq/totion/internal/ui/styles"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // View renders the application UI
@@ -31,12 +39,12 @@ func (m *Model) View() string {
 		keysTitle = m.translate("🎬 Quick Actions")
 		keys = styles.KeysStyle.Render(
 			"Ctrl+N: " + m.translate("Create New Note") + "  •  Ctrl+L: " + m.translate("View All Notes") + "  •  Ctrl+H: " + m.translate("Help") + "  •  Q: " + m.translate("Quit") + "\n" +
-				"Alt+T: " + m.translate("Change UI Language") + "  •  P: " + m.translate("Themes") + "  •  S: " + m.translate("Statistics") + "  •  B: " + m.translate("Notebooks"),
+				"Alt+T: " + m.translate("Change UI Language") + "  •  P: " + m.translate("Themes") + "  •  S: " + m.translate("Statistics") + "  •  B: " + m.translate("Notebooks") + "  •  C: " + m.translate("Settings") + "  •  V: " + m.translate("Vaults") + "  •  X: " + m.translate("Trash") + "  •  A: " + m.translate("Attachments") + "  •  D: " + m.translate("Doctor") + "  •  /: " + m.translate("Search"),
 		)
 	case ViewList:
 		keysTitle = m.translate("📋 Note List")
 		keys = styles.KeysStyle.Render(
-			"↑↓: " + m.translate("Navigate Notes") + "  •  Enter: " + m.translate("Open Selected") + "  •  Ctrl+D: " + m.translate("Delete Note") + "\n" +
+			"↑↓: " + m.translate("Navigate Notes") + "  •  Enter: " + m.translate("Open Selected") + "  •  Ctrl+R: " + m.translate("Rename/Move") + "  •  Ctrl+D: " + m.translate("Delete Note") + "\n" +
 				"/: " + m.translate("Search Notes") + "  •  Esc: " + m.translate("Back to Home"),
 		)
 	case ViewEditor:
@@ -46,14 +54,17 @@ func (m *Model) View() string {
 			if m.pinnedManager.IsPinned(m.currentNote.Path) {
 				pinStatus = " 📌"
 			}
+			if m.readOnly {
+				pinStatus += " 🔒 " + m.translate("read-only")
+			}
 			editorInfo = styles.StatusStyle.Render(
-				fmt.Sprintf(m.translate("Editing: %s %s%s"), m.currentNote.Format.GetIcon(), m.currentNote.Name, pinStatus),
+				fmt.Sprintf(m.translate("Editing: %s %s%s"), m.currentNote.Format.GetIcon(), m.currentNote.DisplayName(), pinStatus),
 			)
 		}
 		keysTitle = m.translate("✏️  Editor Mode")
 		keys = styles.KeysStyle.Render(
 			"Ctrl+S: " + m.translate("Save and Close") + "  •  Alt+F: " + m.translate("Focus Mode") + "  •  Alt+P: " + m.translate("Pin/Unpin") + "\n" +
-				"Alt+L: " + m.translate("Wiki Links") + "  •  Esc: " + m.translate("Minimize Editor"),
+				"Alt+L: " + m.translate("Wiki Links") + "  •  Alt+V: " + m.translate("Version History") + "  •  Alt+A: " + m.translate("Attach File") + "  •  Esc: " + m.translate("Minimize Editor"),
 		)
 		if editorInfo != "" {
 			keys = editorInfo + "\n" + keys
@@ -66,7 +77,7 @@ func (m *Model) View() string {
 		keys = styles.KeysStyle.Render(m.translate("Tab: Switch Between Formats  •  Enter: Create Note  •  Esc: Cancel"))
 	case ViewDeleteConfirm:
 		keysTitle = "⚠️  Confirm Action"
-		keys = styles.KeysStyle.Render(m.translate("Y: Yes, Delete Permanently  •  N: No, Keep Note"))
+		keys = styles.KeysStyle.Render(m.translate("Y: Yes, Move to Trash  •  N: No, Keep Note"))
 	case ViewHelp:
 		keysTitle = "❓ Help & Documentation"
 		keys = styles.KeysStyle.Render(m.translate("Press Number: Select Topic  •  Esc: Back to Previous Menu"))
@@ -106,6 +117,43 @@ func (m *Model) View() string {
 	case ViewNoteNameInNotebook:
 		keysTitle = "📝 Create Note in Notebook"
 		keys = styles.KeysStyle.Render(m.translate("Enter: Create Note  •  Esc: Cancel & Go Back"))
+	case ViewRename:
+		keysTitle = "✏️  Rename"
+		keys = styles.KeysStyle.Render(m.translate("Enter: Rename and Update Links  •  Esc: Cancel"))
+	case ViewHistory:
+		keysTitle = "🕘 Version History"
+		keys = styles.KeysStyle.Render(m.translate("↑↓: Navigate Versions  •  D: Toggle Diff  •  R: Restore Version  •  Esc: Back to Editor"))
+	case ViewAttach:
+		keysTitle = "📎 Attach File"
+		keys = styles.KeysStyle.Render(m.translate("Enter: Copy Into Vault and Insert Link  •  Esc: Back to Editor"))
+	case ViewAttachments:
+		keysTitle = "📎 Attachments"
+		keys = styles.KeysStyle.Render(m.translate("↑↓: Navigate Orphans  •  D: Move Orphan to Trash  •  Esc: Back to Home"))
+	case ViewDoctor:
+		keysTitle = "🩺 Doctor"
+		keys = styles.KeysStyle.Render(m.translate("↑↓: Navigate Problems  •  F: Fix All Fixable Problems  •  Esc: Back to Home"))
+	case ViewSearch:
+		keysTitle = "🔍 Search"
+		keys = styles.KeysStyle.Render(m.translate("Enter: Search / Open Selected Note  •  Tab: Exact/Fuzzy/Regex  •  ↑↓: Navigate Notes  •  PgUp/PgDn: Previous/Next Page  •  Esc: Back"))
+	case ViewTrashConfirm:
+		keysTitle = "⚠️  Confirm Action"
+		keys = styles.KeysStyle.Render(m.translate("Y: Yes, Delete Forever  •  N: No, Keep in Trash"))
+	case ViewTrash:
+		keysTitle = "🗑️  Trash"
+		keys = styles.KeysStyle.Render(m.translate("↑↓: Navigate  •  R: Restore  •  D: Delete Forever  •  E: Empty Trash  •  Esc: Back to Home"))
+	case ViewVaults:
+		keysTitle = "📚 Vaults"
+		keys = styles.KeysStyle.Render(m.translate("↑↓: Navigate Vaults  •  Enter: Switch Vault  •  Esc: Back to Home"))
+	case ViewSettings:
+		keysTitle = "⚙️  Settings"
+		if m.settingsEditing {
+			keys = styles.KeysStyle.Render(m.translate("Enter: Save Setting  •  Esc: Cancel Edit"))
+		} else {
+			keys = styles.KeysStyle.Render(m.translate("↑↓: Navigate Settings  •  Enter: Edit Value  •  Esc: Back to Home"))
+		}
+	case ViewSwapRecovery:
+		keysTitle = "♻️  Recover Unsaved Changes"
+		keys = styles.KeysStyle.Render(m.translate("R: Recover into Editor  •  D: Discard Changes  •  Esc: Decide Later"))
 	case ViewLanguageSelector:
 		keysTitle = "🌐 UI Language Selection"
 		keys = styles.KeysStyle.Render(m.translate("↑↓: Navigate Languages  •  Enter: Change UI Language  •  Esc: Cancel"))
@@ -148,6 +196,9 @@ func (m *Model) View() string {
 			styles.MenuItemStyle.Render("  • Alt+P → "+m.translate("Pin important notes to top")) + "\n" +
 			styles.MenuItemStyle.Render("  • Alt+L → "+m.translate("Create [[wiki links]] to other notes")) + "\n" +
 			styles.MenuItemStyle.Render("  • P → "+m.translate("Change color themes")) + "\n" +
+			styles.MenuItemStyle.Render("  • C → "+m.translate("Settings (auto-save, pomodoro, limits...)")) + "\n" +
+			styles.MenuItemStyle.Render("  • V → "+m.translate("Switch between vaults (work, personal...)")) + "\n" +
+			styles.MenuItemStyle.Render("  • X → "+m.translate("Trash (restore deleted notes and notebooks)")) + "\n" +
 			styles.MenuItemStyle.Render("  • / → "+m.translate("Search within notes")) + "\n\n" +
 
 			styles.TitleStyle.Render(m.translate("📊 VIEWING & ANALYZING")) + "\n" +
@@ -190,28 +241,25 @@ func (m *Model) View() string {
 		view = fmt.Sprintf("%s\n%s\n\n%s", prompt, hint, m.notebookNameInput.View())
 	case ViewSelectNotebookForNote:
 		view = m.renderNotebookSelection()
+	case ViewRename:
+		view = m.renderRename()
 	case ViewNoteNameInNotebook:
 		prompt := styles.SuccessStyle.Render(fmt.Sprintf(m.translate("📝 Create Note in: %s"), m.selectedNotebook))
 		hint := styles.InfoStyle.Render(m.translate("Enter the name for your note"))
 		view = fmt.Sprintf("%s\n%s\n\n%s", prompt, hint, m.fileNameInput.View())
 	case ViewFormatSelector:
-		mdStyle := styles.StatusStyle
-		txtStyle := styles.StatusStyle
-
-		mdSelected := "  "
-		txtSelected := "  "
-		if m.formatIndex == 0 {
-			mdSelected = "→ "
-			mdStyle = styles.SuccessStyle
-		} else {
-			txtSelected = "→ "
-			txtStyle = styles.SuccessStyle
+		var lines []string
+		for i, info := range models.Formats() {
+			marker := "  "
+			style := styles.StatusStyle
+			if i == m.formatIndex {
+				marker = "→ "
+				style = styles.SuccessStyle
+			}
+			lines = append(lines, style.Render(fmt.Sprintf("%s%s (%s) %s", marker, info.Name, info.Extension(), info.Icon)))
 		}
 
-		mdLine := mdStyle.Render(fmt.Sprintf("%sMarkdown (.md) 📝", mdSelected))
-		txtLine := txtStyle.Render(fmt.Sprintf("%sPlain Text (.txt) 📄", txtSelected))
-
-		view = fmt.Sprintf("%s\n%s", mdLine, txtLine)
+		view = strings.Join(lines, "\n")
 	case ViewDeleteConfirm:
 		item, ok := m.list.SelectedItem().(models.Note)
 		if ok {
@@ -265,6 +313,8 @@ func (m *Model) View() string {
 				helpContent = help.GetDeveloperNotebooks()
 			case "0-9":
 				helpContent = help.GetDeveloperBestPractices()
+			case "s":
+				helpContent = help.GetSearchGuide(m.translate)
 			case "t", "T":
 				helpContent = help.GetTranslationGuide(m.translate)
 			default:
@@ -326,7 +376,7 @@ func (m *Model) View() string {
 			themeList += style.Render(theme) + "\n"
 		}
 
-		themeNote := styles.SubtleStyle.Render(m.translate("\nNote: Theme selection is implemented in internal/themes"))
+		themeNote := styles.SubtleStyle.Render(fmt.Sprintf(m.translate("\nCurrent theme: %s (saved in %s)"), m.themeManager.GetCurrentTheme().Name, m.config.ConfigPath()))
 
 		content := fmt.Sprintf("%s%s\n%s%s", themeTitle, themeDesc, themeList, themeNote)
 		m.contentViewport.SetContent(content)
@@ -440,6 +490,38 @@ func (m *Model) View() string {
 
 	case ViewLanguageSelector:
 		view = m.renderLanguageSelector()
+
+	case ViewSwapRecovery:
+		view = m.renderSwapRecovery()
+
+	case ViewSettings:
+		view = m.renderSettings()
+
+	case ViewVaults:
+		view = m.renderVaults()
+
+	case ViewTrash:
+		view = m.renderTrash()
+
+	case ViewTrashConfirm:
+		view = m.renderTrashConfirm()
+
+	case ViewHistory:
+		view = m.renderHistory()
+
+	case ViewAttach:
+		prompt := styles.SuccessStyle.Render(m.translate("📎 Attach a file to this note"))
+		hint := styles.InfoStyle.Render(m.translate("Enter the path of an image, PDF or other file (or drop it into the terminal)"))
+		view = fmt.Sprintf("%s\n%s\n\n%s", prompt, hint, m.fileNameInput.View())
+
+	case ViewAttachments:
+		view = m.renderAttachments()
+
+	case ViewDoctor:
+		view = m.renderDoctor()
+
+	case ViewSearch:
+		view = m.renderSearch()
 	}
 
 	// Keyboard shortcuts section
@@ -472,8 +554,7 @@ func (m *Model) View() string {
 
 // renderNotebookSelection renders the notebook selection view
 func (m *Model) renderNotebookSelection() string {
-	vaultDir := m.getVaultDir()
-	nbManager := notebook.NewNotebookManager(vaultDir)
+	nbManager := notebook.NewNotebookManager(m.storage)
 
 	notebooks, err := nbManager.ListNotebooks()
 	if err != nil || len(notebooks) == 0 {
@@ -482,6 +563,12 @@ func (m *Model) renderNotebookSelection() string {
 
 	title := styles.TitleStyle.Render(m.translate("📂 SELECT NOTEBOOK"))
 	hint := styles.InfoStyle.Render(m.translate("\nSelect a notebook to create your note in:\n"))
+	if m.deletingNotebook {
+		hint = styles.WarningStyle.Render(m.translate("\nSelect a notebook to move to the trash:\n"))
+	}
+	if m.renamingNotebook {
+		hint = styles.InfoStyle.Render(m.translate("\nSelect a notebook to rename:\n"))
+	}
 
 	var notebookList string
 	for i, nb := range notebooks {
@@ -498,6 +585,482 @@ func (m *Model) renderNotebookSelection() string {
 	return fmt.Sprintf("%s%s\n%s", title, hint, notebookList)
 }
 
+// renderRename renders the rename prompt for a note or notebook
+func (m *Model) renderRename() string {
+	name := filepath.Base(m.renamePath)
+
+	var prompt, hint string
+	if m.renamingNotebook {
+		prompt = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("📁 Rename notebook: %s"), name))
+		hint = styles.InfoStyle.Render(m.translate("Links, pins and recent notes pointing into it are updated"))
+	} else {
+		prompt = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("✏️  Rename note: %s"), name))
+		hint = styles.InfoStyle.Render(m.translate("Enter a new name, or a path like Work/Ideas to move it. [[Links]] to it are updated"))
+	}
+
+	return fmt.Sprintf("%s\n%s\n\n%s", prompt, hint, m.fileNameInput.View())
+}
+
+// renderSwapRecovery renders the unsaved changes recovery prompt
+func (m *Model) renderSwapRecovery() string {
+	if len(m.pendingSwaps) == 0 {
+		return ""
+	}
+
+	sf := m.pendingSwaps[0]
+
+	title := styles.TitleStyle.Render(m.translate("♻️  UNSAVED CHANGES FOUND"))
+	hint := styles.InfoStyle.Render(m.translate("Totion was closed before these changes were saved:"))
+
+	noteStatus := fmt.Sprintf(m.translate("Note last saved: %s"), sf.NoteModTime.Format("2006-01-02 15:04:05"))
+	if sf.NoteModTime.IsZero() {
+		noteStatus = m.translate("Note no longer exists on disk - recovering will recreate it")
+	}
+
+	details := styles.HighlightStyle.Render("  📄 "+sf.NotePath) + "\n" +
+		styles.MenuItemStyle.Render(fmt.Sprintf("  "+m.translate("Unsaved changes from: %s"), sf.ModTime.Format("2006-01-02 15:04:05"))) + "\n" +
+		styles.MenuItemStyle.Render("  "+noteStatus)
+
+	remaining := ""
+	if len(m.pendingSwaps) > 1 {
+		remaining = "\n\n" + styles.SubtleStyle.Render(fmt.Sprintf(m.translate("%d more notes with unsaved changes"), len(m.pendingSwaps)-1))
+	}
+
+	return fmt.Sprintf("%s\n%s\n\n%s%s", title, hint, details, remaining)
+}
+
+// renderSettings renders the settings editor
+func (m *Model) renderSettings() string {
+	title := styles.TitleStyle.Render(m.translate("⚙️  SETTINGS"))
+	hint := styles.InfoStyle.Render(fmt.Sprintf(m.translate("Saved to %s"), m.config.ConfigPath()))
+
+	var rows strings.Builder
+	for i, field := range config.Fields() {
+		value, _ := m.config.Get(field.Key)
+
+		marker := "  "
+		style := styles.MenuItemStyle
+		if i == m.settingsIndex {
+			marker = "▶ "
+			style = styles.HighlightStyle
+		}
+
+		if i == m.settingsIndex && m.settingsEditing {
+			rows.WriteString(style.Render(fmt.Sprintf("%s%-24s ", marker, field.Key)) + m.settingsInput.View() + "\n")
+			continue
+		}
+
+		notes := ""
+		if field.Key == "vault_dir" && m.config.ActiveVault().Name != config.DefaultVaultName {
+			notes += " [vault: " + m.config.ActiveVault().Name + "]"
+		} else if m.config.IsOverridden(field.Key) {
+			notes += " [" + config.EnvName(field.Key) + "]"
+		}
+		if field.Restart {
+			notes += " ↻"
+		}
+		rows.WriteString(style.Render(fmt.Sprintf("%s%-24s %s", marker, field.Key, value)) + styles.SubtleStyle.Render(notes) + "\n")
+	}
+
+	legend := styles.SubtleStyle.Render(m.translate("↻ applies after restart  •  [TOTION_*] set by environment variable"))
+
+	return fmt.Sprintf("%s\n%s\n\n%s\n%s", title, hint, rows.String(), legend)
+}
+
+// renderTrash renders the trash bin
+func (m *Model) renderTrash() string {
+	title := styles.TitleStyle.Render(m.translate("🗑️  TRASH"))
+
+	retention := m.translate("Items are kept until you delete them")
+	if days := m.config.Trash.RetentionDays; days > 0 {
+		retention = fmt.Sprintf(m.translate("Items are deleted forever after %d days"), days)
+	}
+	hint := styles.InfoStyle.Render(retention)
+
+	if len(m.trashItems) == 0 {
+		return fmt.Sprintf("%s\n%s\n\n%s", title, hint, styles.SubtleStyle.Render(m.translate("Trash is empty")))
+	}
+
+	var rows strings.Builder
+	for i, item := range m.trashItems {
+		marker := "  "
+		style := styles.MenuItemStyle
+		if i == m.trashIndex {
+			marker = "▶ "
+			style = styles.HighlightStyle
+		}
+
+		icon := "📄"
+		if item.IsDir {
+			icon = "📁"
+		}
+
+		rows.WriteString(style.Render(fmt.Sprintf("%s%s %s", marker, icon, item.RelPath)) +
+			styles.SubtleStyle.Render("  "+item.DeletedAt.Format("2006-01-02 15:04")) + "\n")
+	}
+
+	return fmt.Sprintf("%s\n%s\n\n%s", title, hint, rows.String())
+}
+
+// renderTrashConfirm asks before deleting trashed items forever
+func (m *Model) renderTrashConfirm() string {
+	warning := fmt.Sprintf(m.translate("⚠️  Empty the trash, deleting %d item(s) forever?"), len(m.trashItems))
+	if !m.trashEmptying && m.trashIndex < len(m.trashItems) {
+		warning = fmt.Sprintf(m.translate("⚠️  Delete %s forever?"), m.trashItems[m.trashIndex].RelPath)
+	}
+	notice := styles.StatusStyle.Render(m.translate("This action cannot be undone!"))
+	return fmt.Sprintf("%s\n%s", styles.ErrorStyle.Render(warning), notice)
+}
+
+// renderHistory renders the saved versions of the open note
+func (m *Model) renderHistory() string {
+	name := ""
+	if m.currentNote != nil {
+		name = m.currentNote.DisplayName()
+	}
+	title := styles.TitleStyle.Render(fmt.Sprintf(m.translate("🕘 HISTORY: %s"), name))
+
+	if !m.config.History.Enabled {
+		hint := styles.WarningStyle.Render(m.translate("Version history is turned off in settings"))
+		if len(m.historyVersions) == 0 {
+			return fmt.Sprintf("%s\n%s", title, hint)
+		}
+		title += "\n" + hint
+	}
+	if len(m.historyVersions) == 0 {
+		return fmt.Sprintf("%s\n\n%s", title, styles.SubtleStyle.Render(m.translate("No earlier versions yet - one is kept every time the note is saved")))
+	}
+
+	var rows strings.Builder
+	for i, version := range m.historyVersions {
+		marker := "  "
+		style := styles.MenuItemStyle
+		if i == m.historyIndex {
+			marker = "▶ "
+			style = styles.HighlightStyle
+		}
+
+		// Versions are newest first, so the one below is the one it was edited from
+		delta := ""
+		if i+1 < len(m.historyVersions) {
+			delta = fmt.Sprintf("  %+d", version.Words-m.historyVersions[i+1].Words)
+		}
+
+		rows.WriteString(style.Render(fmt.Sprintf("%s%s", marker, version.SavedAt.Format("2006-01-02 15:04:05"))) +
+			styles.SubtleStyle.Render(fmt.Sprintf(m.translate("  %d words"), version.Words)+delta) + "\n")
+	}
+
+	if !m.historyDiff {
+		return fmt.Sprintf("%s\n\n%s", title, rows.String())
+	}
+	return fmt.Sprintf("%s\n\n%s\n%s", title, rows.String(), m.renderHistoryDiff())
+}
+
+// historyDiffContext is the number of unchanged lines shown around each change
+const historyDiffContext = 2
+
+// historyDiffMaxLines caps the diff shown under the version list
+const historyDiffMaxLines = 30
+
+// renderHistoryDiff renders the changes from the selected version to the editor text
+func (m *Model) renderHistoryDiff() string {
+	version := m.historyVersions[m.historyIndex]
+	content, err := m.storage.History().Read(version)
+	if err != nil {
+		return styles.ErrorStyle.Render(m.translate("Error reading version: ") + err.Error())
+	}
+
+	lines := history.Diff(content, m.editor.Value())
+	added, removed := history.Stats(lines)
+	header := styles.InfoStyle.Render(fmt.Sprintf(m.translate("Changes since this version: +%d -%d lines"), added, removed))
+	if added == 0 && removed == 0 {
+		return header
+	}
+
+	// Only show changed lines with a little context around them
+	show := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Op == history.Same {
+			continue
+		}
+		for j := max(0, i-historyDiffContext); j <= min(len(lines)-1, i+historyDiffContext); j++ {
+			show[j] = true
+		}
+	}
+
+	var out strings.Builder
+	shown := 0
+	gap := false
+	for i, line := range lines {
+		if !show[i] {
+			gap = true
+			continue
+		}
+		if shown == historyDiffMaxLines {
+			out.WriteString(styles.SubtleStyle.Render(m.translate("… more changes not shown")) + "\n")
+			break
+		}
+		if gap && shown > 0 {
+			out.WriteString(styles.SubtleStyle.Render("  …") + "\n")
+		}
+		gap = false
+
+		switch line.Op {
+		case history.Added:
+			out.WriteString(styles.SuccessStyle.Render(line.String()) + "\n")
+		case history.Removed:
+			out.WriteString(styles.ErrorStyle.Render(line.String()) + "\n")
+		default:
+			out.WriteString(styles.SubtleStyle.Render(line.String()) + "\n")
+		}
+		shown++
+	}
+
+	return fmt.Sprintf("%s\n%s", header, out.String())
+}
+
+// renderAttachments renders orphaned attachments and links to missing files
+func (m *Model) renderAttachments() string {
+	title := styles.TitleStyle.Render(m.translate("📎 ATTACHMENTS"))
+	report := m.attachReport
+	if report == nil {
+		return title
+	}
+	vaultDir := m.storage.VaultDir()
+
+	hint := styles.InfoStyle.Render(fmt.Sprintf(m.translate("%d attachments, %d not linked from any note, %d missing links"),
+		report.Attachments, len(report.Orphaned), len(report.Missing)))
+
+	var rows strings.Builder
+	rows.WriteString(styles.SuccessStyle.Render(m.translate("Orphaned attachments:")) + "\n")
+	if len(report.Orphaned) == 0 {
+		rows.WriteString(styles.SubtleStyle.Render("  "+m.translate("None")) + "\n")
+	}
+	for i, path := range report.Orphaned {
+		marker := "  "
+		style := styles.MenuItemStyle
+		if i == m.attachIndex {
+			marker = "▶ "
+			style = styles.HighlightStyle
+		}
+		rel, _ := filepath.Rel(vaultDir, path)
+		rows.WriteString(style.Render(marker+filepath.ToSlash(rel)) + "\n")
+	}
+
+	rows.WriteString("\n" + styles.WarningStyle.Render(m.translate("Missing attachments:")) + "\n")
+	if len(report.Missing) == 0 {
+		rows.WriteString(styles.SubtleStyle.Render("  "+m.translate("None")) + "\n")
+	}
+	for _, missing := range report.Missing {
+		rel, _ := filepath.Rel(vaultDir, missing.NotePath)
+		rows.WriteString(styles.MenuItemStyle.Render("  "+missing.Target) +
+			styles.SubtleStyle.Render("  "+m.translate("in")+" "+filepath.ToSlash(rel)) + "\n")
+	}
+
+	return fmt.Sprintf("%s\n%s\n\n%s", title, hint, rows.String())
+}
+
+// renderDoctor renders the problems found in the vault, grouped by category,
+// with what fixing them would do
+func (m *Model) renderDoctor() string {
+	title := styles.TitleStyle.Render(m.translate("🩺 DOCTOR"))
+	if len(m.doctorIssues) == 0 {
+		return fmt.Sprintf("%s\n%s", title, styles.SuccessStyle.Render(m.translate("✅ No problems found")))
+	}
+	vaultDir := m.storage.VaultDir()
+
+	fixable := 0
+	for _, issue := range m.doctorIssues {
+		if issue.Fixable() {
+			fixable++
+		}
+	}
+	hint := styles.InfoStyle.Render(fmt.Sprintf(m.translate("%d problem(s) found, %d can be fixed automatically"),
+		len(m.doctorIssues), fixable))
+
+	var rows strings.Builder
+	i := 0
+	for _, category := range doctor.Categories {
+		first := true
+		for _, issue := range m.doctorIssues {
+			if issue.Category != category {
+				continue
+			}
+			if first {
+				rows.WriteString("\n" + styles.WarningStyle.Render(string(category)) + "\n")
+				first = false
+			}
+
+			marker := "  "
+			style := styles.MenuItemStyle
+			if i == m.doctorIndex {
+				marker = "▶ "
+				style = styles.HighlightStyle
+			}
+			rel, _ := filepath.Rel(vaultDir, issue.Path)
+			rows.WriteString(style.Render(marker+filepath.ToSlash(rel)) + styles.SubtleStyle.Render("  "+issue.Detail) + "\n")
+			if issue.Fixable() {
+				rows.WriteString(styles.SubtleStyle.Render("    → "+issue.Fix) + "\n")
+			} else {
+				rows.WriteString(styles.SubtleStyle.Render("    → "+m.translate("fix by hand")) + "\n")
+			}
+			i++
+		}
+	}
+
+	return fmt.Sprintf("%s\n%s\n%s", title, hint, rows.String())
+}
+
+// searchMatchLines is how many matching lines are shown under each note found
+const searchMatchLines = 3
+
+// renderSearch renders the search input and a page of ranked results
+func (m *Model) renderSearch() string {
+	title := styles.TitleStyle.Render(m.translate("🔍 SEARCH"))
+	input := m.searchInput.View() + "\n" + m.renderSearchModes()
+
+	if m.searchQuery == "" {
+		hint := styles.InfoStyle.Render(m.translate("Type words, \"phrases\", AND/OR/NOT, #tags or filters like notebook:work and press Enter (Ctrl+H, S for help)"))
+		if m.searchMode == search.ModeRegex {
+			hint = styles.InfoStyle.Render(m.translate("Type a regular expression, matched against each line, and press Enter (Ctrl+H, S for help)"))
+		}
+		return fmt.Sprintf("%s\n%s\n\n%s", title, input, hint)
+	}
+	if m.searching {
+		hint := styles.InfoStyle.Render(fmt.Sprintf(m.translate("Searching for %q..."), m.searchQuery))
+		return fmt.Sprintf("%s\n%s\n\n%s", title, input, hint)
+	}
+	if len(m.searchResults) == 0 {
+		hint := styles.WarningStyle.Render(fmt.Sprintf(m.translate("No matches for %q"), m.searchQuery))
+		return fmt.Sprintf("%s\n%s\n\n%s", title, input, hint)
+	}
+
+	matches := 0
+	for _, result := range m.searchResults {
+		matches += len(result.Matches)
+	}
+	size := m.searchPageSize()
+	page := m.searchIndex / size
+	pages := (len(m.searchResults) + size - 1) / size
+	hint := styles.InfoStyle.Render(fmt.Sprintf(m.translate("%d note(s), %d match(es) for %q  •  page %d/%d"),
+		len(m.searchResults), matches, m.searchQuery, page+1, pages))
+
+	vaultDir := m.storage.VaultDir()
+	var rows strings.Builder
+	end := (page + 1) * size
+	if end > len(m.searchResults) {
+		end = len(m.searchResults)
+	}
+	for i := page * size; i < end; i++ {
+		result := m.searchResults[i]
+		marker := "  "
+		style := styles.MenuItemStyle
+		if i == m.searchIndex {
+			marker = "▶ "
+			style = styles.HighlightStyle
+		}
+		rel, _ := filepath.Rel(vaultDir, result.NotePath)
+		info := fmt.Sprintf("  %d match(es)", len(result.Matches))
+		if !result.Modified.IsZero() {
+			info += " · " + result.Modified.Format("2006-01-02")
+		}
+		rows.WriteString(style.Render(marker+"📄 "+filepath.ToSlash(rel)) + styles.SubtleStyle.Render(info) + "\n")
+
+		for j, match := range result.Matches {
+			if j == searchMatchLines {
+				rows.WriteString(styles.SubtleStyle.Render(fmt.Sprintf(m.translate("      … %d more"), len(result.Matches)-j)) + "\n")
+				break
+			}
+			rows.WriteString(styles.SubtleStyle.Render(fmt.Sprintf("    %4d: ", match.LineNumber)) + renderMatch(match) + "\n")
+		}
+	}
+
+	return fmt.Sprintf("%s\n%s\n\n%s\n%s", title, input, hint, rows.String())
+}
+
+// renderSearchModes renders the search modes, the one in use highlighted
+func (m *Model) renderSearchModes() string {
+	modes := []string{}
+	for _, mode := range search.Modes {
+		if mode == m.searchMode {
+			modes = append(modes, styles.HighlightStyle.Render("["+mode.String()+"]"))
+		} else {
+			modes = append(modes, styles.SubtleStyle.Render(mode.String()))
+		}
+	}
+	return styles.SubtleStyle.Render(m.translate("Mode: ")) + strings.Join(modes, " ") + styles.SubtleStyle.Render(m.translate("  (Tab to switch)"))
+}
+
+// renderMatch renders the snippet of a search match with what matched
+// highlighted, and regex capture groups highlighted more
+func renderMatch(match search.SearchResult) string {
+	snippet := strings.TrimRightFunc(match.MatchSnippet, unicode.IsSpace)
+	trimmed := len(snippet)
+	snippet = strings.TrimLeftFunc(snippet, unicode.IsSpace)
+	shift := trimmed - len(snippet)
+
+	// What each byte of the snippet is: 0 plain, 1 a match, 2 a capture group
+	kinds := make([]byte, len(snippet))
+	mark := func(spans []search.Span, kind byte) {
+		for _, span := range spans {
+			for i := span.Start - shift; i < span.End-shift; i++ {
+				if i >= 0 && i < len(kinds) {
+					kinds[i] = kind
+				}
+			}
+		}
+	}
+	mark(match.Highlights, 1)
+	mark(match.Captures, 2)
+
+	stylesByKind := []lipgloss.Style{styles.SubtleStyle, styles.MatchStyle, styles.CaptureStyle}
+	var out strings.Builder
+	for start := 0; start < len(snippet); {
+		end := start + 1
+		for end < len(snippet) && kinds[end] == kinds[start] {
+			end++
+		}
+		out.WriteString(stylesByKind[kinds[start]].Render(snippet[start:end]))
+		start = end
+	}
+	return out.String()
+}
+
+// renderVaults renders the vault switcher
+func (m *Model) renderVaults() string {
+	title := styles.TitleStyle.Render(m.translate("📚 VAULTS"))
+	active := m.config.ActiveVault()
+	hint := styles.InfoStyle.Render(fmt.Sprintf(m.translate("Current vault: %s"), active.Name))
+
+	var rows strings.Builder
+	for i, profile := range m.config.VaultProfiles() {
+		marker := "  "
+		style := styles.MenuItemStyle
+		if i == m.vaultIndex {
+			marker = "▶ "
+			style = styles.HighlightStyle
+		}
+
+		status := ""
+		if profile.Name == active.Name {
+			status = " ✓ " + m.translate("active")
+		} else if _, err := os.Stat(profile.Path); os.IsNotExist(err) {
+			status = " " + m.translate("(will be created)")
+		}
+
+		rows.WriteString(style.Render(fmt.Sprintf("%s%-16s %s", marker, profile.Name, profile.Path)) + styles.SubtleStyle.Render(status) + "\n")
+	}
+
+	// Vaults opened by path (--vault ~/dir) have no profile in the list
+	if _, ok := m.config.FindVault(active.Name); !ok {
+		rows.WriteString(styles.MenuItemStyle.Render(fmt.Sprintf("  %-16s %s", active.Name, active.Path)) + styles.SubtleStyle.Render(" ✓ "+m.translate("active")) + "\n")
+	}
+
+	help := styles.SubtleStyle.Render(fmt.Sprintf(m.translate("Add vaults under \"vaults:\" in %s or open one with totion --vault <name|path>"), m.config.ConfigPath()))
+
+	return fmt.Sprintf("%s\n%s\n\n%s\n%s", title, hint, rows.String(), help)
+}
+
 // renderPinnedNotes renders pinned notes section
 func (m *Model) renderPinnedNotes() string {
 	pinned := m.pinnedManager.GetPinned()
@@ -513,7 +1076,7 @@ func (m *Model) renderPinnedNotes() string {
 		if i >= 5 {
 			break // Show max 5 pinned notes
 		}
-		sb.WriteString(styles.HighlightStyle.Render(fmt.Sprintf("  • %s\n", p.Name)))
+		sb.WriteString(styles.HighlightStyle.Render(fmt.Sprintf("  • %s\n", p.DisplayName())))
 	}
 	sb.WriteString("\n")
 
@@ -0,0 +1,197 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/0xshariq/totion/internal/features/linking"
+	"github.com/0xshariq/totion/internal/features/search"
+	"github.com/0xshariq/totion/internal/features/watcher"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/ui/styles"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// vaultChangedMsg carries a batch of changes made to the vault outside Totion
+type vaultChangedMsg struct {
+	watcher *watcher.Watcher // Watcher that sent the batch, stale after a vault switch
+	events  []watcher.Event
+}
+
+// startWatcher builds the link index and starts watching the current vault
+// Any previous watcher is stopped first
+func (m *Model) startWatcher() {
+	m.stopWatcher()
+
+	m.linkManager = linking.NewLinkManager()
+	if notes, err := m.storage.ListNotes(); err == nil {
+		for _, note := range notes {
+			if content, err := m.storage.ReadFile(note.Path); err == nil {
+				m.linkManager.IndexNote(note.Path, string(content))
+			}
+		}
+	}
+
+	if !m.config.Watch.Enabled {
+		return
+	}
+
+	w, err := watcher.New(m.storage, m.config.Watch.Debounce)
+	if err != nil {
+		m.statusMessage = styles.WarningStyle.Render(m.translate("⚠️  Not watching vault for changes: ") + err.Error())
+		return
+	}
+	m.watcher = w
+}
+
+// stopWatcher stops watching the vault
+func (m *Model) stopWatcher() {
+	if m.watcher != nil {
+		_ = m.watcher.Close()
+		m.watcher = nil
+	}
+}
+
+// waitForVaultChanges returns a command that waits for the next batch of changes
+func (m *Model) waitForVaultChanges() tea.Cmd {
+	w := m.watcher
+	if w == nil {
+		return nil
+	}
+
+	return func() tea.Msg {
+		events, ok := <-w.Events()
+		if !ok {
+			return nil
+		}
+		return vaultChangedMsg{watcher: w, events: events}
+	}
+}
+
+// applyVaultChanges updates indexes, the note list and the editor for external changes
+func (m *Model) applyVaultChanges(events []watcher.Event) tea.Cmd {
+	index := search.IndexFor(m.storage)
+	for _, event := range events {
+		if event.Op == watcher.Removed {
+			_, _ = m.tagManager.RemoveNote(event.Path)
+			index.Remove(event.Path)
+			m.linkManager.RemoveNote(event.Path)
+			if event.IsDir {
+				m.linkManager.RemoveNotesWithPrefix(event.Path + string(filepath.Separator))
+			}
+		} else if !event.IsDir {
+			// Notes inside a new notebook arrive as their own events
+			_ = m.tagManager.IndexNote(event.Path)
+			_ = index.Update(event.Path)
+			if content, err := m.storage.ReadFile(event.Path); err == nil {
+				m.linkManager.IndexNote(event.Path, string(content))
+			}
+		}
+
+		// Our own saves also trigger events, they only need re-indexing
+		if m.currentNote != nil && m.currentNote.Path == event.Path &&
+			(event.Op == watcher.Removed || !m.storage.IsOwnWrite(event.Path)) {
+			m.openNoteChangedOnDisk(event)
+		}
+	}
+
+	// Notes moved outside Totion keep their IDs, so pins and recents follow them
+	m.syncNoteIDs()
+
+	return m.refreshList()
+}
+
+// syncNoteIDs gives every note an ID and re-resolves pins and recents by ID
+func (m *Model) syncNoteIDs() {
+	if _, err := m.storage.SyncIDs(); err != nil {
+		return
+	}
+	if m.pinnedManager != nil {
+		_, _ = m.pinnedManager.Resolve(m.storage.ResolveNote)
+	}
+	if m.recentManager != nil {
+		_, _ = m.recentManager.Resolve(m.storage.ResolveNote)
+	}
+	if m.currentNote != nil && m.currentNote.ID == "" {
+		m.currentNote.ID = m.storage.IDs().ID(m.currentNote.Path)
+	}
+}
+
+// openNoteChangedOnDisk reloads or warns about external changes to the open note
+func (m *Model) openNoteChangedOnDisk(event watcher.Event) {
+	name := m.currentNote.DisplayName()
+
+	if event.Op == watcher.Removed {
+		m.diskChanged = true
+		m.statusMessage = styles.WarningStyle.Render(fmt.Sprintf(m.translate("⚠️  %s was deleted or moved outside Totion. Ctrl+S saves it back"), name))
+		return
+	}
+
+	// Nothing to lose, just show the new content
+	if !m.isEditorDirty {
+		if content, err := m.storage.ReadNote(m.currentNote.Path); err == nil {
+			m.editor.SetValue(content)
+			m.diskChanged = false
+			m.statusMessage = styles.InfoStyle.Render(fmt.Sprintf(m.translate("↻ Reloaded %s, it changed on disk"), name))
+		}
+		return
+	}
+
+	m.diskChanged = true
+	m.statusMessage = styles.WarningStyle.Render(fmt.Sprintf(m.translate("⚠️  %s changed on disk. Alt+R reloads it (drops your edits), Ctrl+S keeps yours"), name))
+}
+
+// reloadCurrentNote replaces the editor content with the note on disk
+func (m *Model) reloadCurrentNote() {
+	if m.currentNote == nil {
+		return
+	}
+
+	content, err := m.storage.ReadNote(m.currentNote.Path)
+	if err != nil {
+		m.statusMessage = styles.ErrorStyle.Render(m.translate("Error: ") + err.Error())
+		return
+	}
+
+	m.editor.SetValue(content)
+	m.isEditorDirty = false
+	m.diskChanged = false
+	_ = m.storage.RemoveSwap(m.currentNote.Path)
+	m.statusMessage = styles.SuccessStyle.Render(fmt.Sprintf(m.translate("↻ Reloaded %s from disk"), m.currentNote.DisplayName()))
+}
+
+// refreshList reloads the note list while it is shown, keeping the selected note selected
+func (m *Model) refreshList() tea.Cmd {
+	if m.state != ViewList {
+		return nil
+	}
+	if m.list.FilterState() == list.Filtering {
+		return nil // Don't disturb typing, the list refreshes when reopened
+	}
+
+	notes, err := m.storage.ListNotes()
+	if err != nil {
+		return nil
+	}
+
+	selected, _ := m.list.SelectedItem().(models.Note)
+	index := m.list.Index()
+
+	items := make([]list.Item, len(notes))
+	for i, note := range notes {
+		items[i] = note
+		if note.Path == selected.Path {
+			index = i
+		}
+	}
+
+	cmd := m.list.SetItems(items)
+	if index >= len(items) {
+		index = len(items) - 1
+	}
+	if index >= 0 {
+		m.list.Select(index)
+	}
+	return cmd
+}
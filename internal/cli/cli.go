@@ -0,0 +1,351 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/config"
+	"github.com/0xshariq/totion/internal/features/search"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage"
+)
+
+// Exit codes returned by Run, so scripts can tell failures apart
+const (
+	ExitOK       = 0 // Success
+	ExitError    = 1 // The command failed (I/O error, invalid note, ...)
+	ExitUsage    = 2 // Unknown command, bad flags or missing arguments
+	ExitNotFound = 3 // No note, tag or search result matched
+)
+
+// command is a headless subcommand
+type command struct {
+	name    string
+	usage   string // Arguments, e.g. "[flags] <note>"
+	summary string
+	run     func(e *env, args []string) error
+}
+
+// commands lists the subcommands in help order
+var commands []command
+
+func init() {
+	commands = []command{
+		{"new", "[flags] <name>", "Create a note (content from --content or stdin)", runNew},
+		{"list", "[flags]", "List notes", runList},
+		{"cat", "[flags] <note>...", "Print notes", runCat},
+		{"search", "[flags] <query>", "Search notes (#tag searches tags)", runSearch},
+		{"tags", "[flags] [note]", "List tags with their note counts, or the tags of a note", runTags},
+		{"tasks", "[flags] [note]...", "List tasks (checkboxes and Org TODOs)", runTasks},
+		{"stats", "[flags]", "Print vault statistics", runStats},
+		{"notebooks", "[flags]", "List notebooks with their note counts", runNotebooks},
+		{"export", "[flags] [note]...", "Export notes (all notes by default)", runExport},
+		{"import", "[flags] <source>", "Import notes from a file or folder", runImport},
+		{"daily", "[flags]", "Create today's daily note and print its path", runDaily},
+		{"capture", "[flags] [text]", "Append text (or stdin) to the scratch pad or today's daily note", runCapture},
+		{"doctor", "[flags]", "Check the vault for broken pins, indexes, metadata, links and empty notes", runDoctor},
+		{"serve", "[flags]", "Serve the vault over a local HTTP JSON API", runServe},
+		{"completion", "<bash|zsh|fish>", "Print the shell completion script", runCompletion},
+	}
+}
+
+// env is what a command runs with
+type env struct {
+	store     *storage.Storage
+	cfg       *config.Config
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+	lastFlags *flag.FlagSet // Flag set of the running command, for completion
+}
+
+// exitError is an error with the exit code it should produce
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageError reports a wrong invocation (exit code 2)
+func usageError(format string, args ...interface{}) error {
+	return &exitError{code: ExitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// notFoundError reports that nothing matched (exit code 3)
+func notFoundError(format string, args ...interface{}) error {
+	return &exitError{code: ExitNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// IsCommand checks if name is a subcommand, i.e. totion should run headless
+func IsCommand(name string) bool {
+	if name == "help" || name == completeCommand {
+		return true
+	}
+	_, ok := lookup(name)
+	return ok
+}
+
+// lookup finds a subcommand by name
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Run runs a subcommand against the configured vault and returns the exit code
+// config.Initialize must have been called first
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		if len(args) > 1 {
+			if cmd, ok := lookup(args[1]); ok {
+				return Run([]string{cmd.name, "-h"}, stdin, stdout, stderr)
+			}
+		}
+		printUsage(stdout)
+		return ExitOK
+	}
+
+	store, err := storage.New()
+	if err != nil {
+		if args[0] != completeCommand {
+			fmt.Fprintf(stderr, "totion: %v\n", err)
+		}
+		return ExitError
+	}
+	e := &env{
+		store:  store,
+		cfg:    config.AppConfig,
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	defer func() { _ = e.store.Close() }()
+	search.IndexFor(e.store).SetOptions(search.IndexOptions{
+		Enabled: e.cfg.Search.Index,
+		Stem:    e.cfg.Search.Stem,
+	})
+	if args[0] == completeCommand {
+		// Errors would only garble the shell's completion list
+		_ = runComplete(e, args[1:])
+		return ExitOK
+	}
+
+	cmd, ok := lookup(args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "totion: unknown command %q\n\n", args[0])
+		printUsage(stderr)
+		return ExitUsage
+	}
+
+	err = cmd.run(e, args[1:])
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+
+	fmt.Fprintf(stderr, "totion %s: %v\n", cmd.name, err)
+	var exit *exitError
+	if errors.As(err, &exit) {
+		if exit.code == ExitUsage {
+			fmt.Fprintf(stderr, "usage: totion %s %s\n", cmd.name, cmd.usage)
+		}
+		return exit.code
+	}
+	return ExitError
+}
+
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: totion [--vault name|dir] [command] [flags] [args]")
+	fmt.Fprintln(w, "\nWithout a command, totion opens the terminal UI.")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'totion help <command>' for the flags of a command.")
+	fmt.Fprintln(w, "\nExit codes: 0 success, 1 error, 2 bad usage, 3 nothing found")
+	fmt.Fprintf(w, "\nlist, search, tags, tasks, stats and notebooks take --json or --ndjson (schema version %d).\n", SchemaVersion)
+}
+
+// newFlags creates the flag set of a subcommand, printing help to stdout
+func (e *env) newFlags(cmd string) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	e.lastFlags = fs
+	fs.Usage = func() {
+		c, _ := lookup(cmd)
+		fs.SetOutput(e.stdout)
+		fmt.Fprintf(e.stdout, "Usage: totion %s %s\n\n%s\n", c.name, c.usage, c.summary)
+		if hasFlags(fs) {
+			fmt.Fprintln(e.stdout, "\nFlags:")
+			fs.PrintDefaults()
+		}
+		fs.SetOutput(e.stderr)
+	}
+	return fs
+}
+
+// parse parses flags, turning flag errors into usage errors
+// Flags may come before or after the positional arguments
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &exitError{code: ExitUsage, err: err}
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		// Everything after "--" is positional
+		if consumed := len(args) - fs.NArg(); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, fs.Args()...), nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// hasFlags checks if a flag set defines any flags
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// notebookDir returns the folder of a notebook, or the vault for ""
+func (e *env) notebookDir(notebook string) (string, error) {
+	vaultDir := e.store.VaultDir()
+	if notebook == "" {
+		return vaultDir, nil
+	}
+
+	dir := filepath.Join(vaultDir, filepath.FromSlash(strings.Trim(notebook, "/")))
+	info, err := e.store.Stat(dir)
+	if err != nil || !info.IsDir {
+		return "", notFoundError("no notebook %q", notebook)
+	}
+	return dir, nil
+}
+
+// notesIn lists the notes in a folder of the vault, sorted by vault path
+func (e *env) notesIn(dir string) ([]models.Note, error) {
+	notes, err := e.store.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := dir + string(filepath.Separator)
+	filtered := notes[:0]
+	for _, note := range notes {
+		if dir == e.store.VaultDir() || strings.HasPrefix(note.Path, prefix) {
+			filtered = append(filtered, note)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].RelID < filtered[j].RelID
+	})
+	return filtered, nil
+}
+
+// resolveNote finds the note a command argument refers to:
+// a path (absolute or relative to the vault, extension optional), a note ID
+// ("id:3f9a0c12b7e4" or just the ID), or a note name that is unique in the vault
+func (e *env) resolveNote(ref string) (models.Note, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return models.Note{}, usageError("empty note name")
+	}
+
+	id := strings.TrimPrefix(ref, "id:")
+	if note, ok := e.store.ResolveNote(id, ""); ok {
+		return note, nil
+	}
+
+	candidates := []string{ref}
+	if !filepath.IsAbs(ref) {
+		candidates = []string{filepath.Join(e.store.VaultDir(), filepath.FromSlash(ref))}
+		if abs, err := filepath.Abs(ref); err == nil {
+			candidates = append(candidates, abs)
+		}
+	}
+	for _, path := range candidates {
+		if storage.IsNoteFile(path) {
+			if note, err := e.store.GetNote(path); err == nil {
+				return note, nil
+			}
+			continue
+		}
+		for _, ext := range models.NoteExtensions() {
+			if note, err := e.store.GetNote(path + ext); err == nil {
+				return note, nil
+			}
+		}
+	}
+
+	// Bare name anywhere in the vault
+	notes, err := e.store.ListNotes()
+	if err != nil {
+		return models.Note{}, err
+	}
+	var matches []models.Note
+	for _, note := range notes {
+		name := strings.TrimSuffix(note.Name, filepath.Ext(note.Name))
+		if strings.EqualFold(note.Name, ref) || strings.EqualFold(name, ref) {
+			matches = append(matches, note)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return models.Note{}, notFoundError("no note %q", ref)
+	case 1:
+		return matches[0], nil
+	}
+
+	names := make([]string, len(matches))
+	for i, note := range matches {
+		names[i] = note.RelID
+	}
+	return models.Note{}, fmt.Errorf("%q matches several notes, use a path: %s", ref, strings.Join(names, ", "))
+}
+
+// readStdin returns piped input, or "" when stdin is a terminal
+func (e *env) readStdin() (string, error) {
+	if f, ok := e.stdin.(*os.File); ok {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+			return "", nil
+		}
+	}
+	if e.stdin == nil {
+		return "", nil
+	}
+
+	data, err := io.ReadAll(e.stdin)
+	if err != nil {
+		return "", fmt.Errorf("error reading stdin: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,379 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xshariq/totion/internal/features/capture"
+	"github.com/0xshariq/totion/internal/features/daily"
+	"github.com/0xshariq/totion/internal/features/export"
+	importpkg "github.com/0xshariq/totion/internal/features/import"
+	"github.com/0xshariq/totion/internal/features/quick"
+	"github.com/0xshariq/totion/internal/features/templates"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage"
+)
+
+// runNew creates a note and prints its path
+func runNew(e *env, args []string) error {
+	fs := e.newFlags("new")
+	notebook := fs.String("notebook", "", "notebook to create the note in")
+	format := fs.String("format", e.cfg.DefaultFormat, "note format (md, txt, org, adoc)")
+	template := fs.String("template", "", "template to start from")
+	content := fs.String("content", "", "note content (default: read from stdin if piped)")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError("expected one note name")
+	}
+
+	name := strings.TrimSpace(args[0])
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return usageError("invalid note name %q, use --notebook for folders", args[0])
+	}
+	info, ok := models.LookupFormat(models.FileFormat(*format))
+	if !ok {
+		return usageError("unknown format %q", *format)
+	}
+
+	dir := e.store.VaultDir()
+	if *notebook != "" {
+		dir = filepath.Join(dir, filepath.FromSlash(strings.Trim(*notebook, "/")))
+		if err := e.store.Mkdir(dir); err != nil {
+			return fmt.Errorf("error creating notebook: %w", err)
+		}
+	}
+
+	path := filepath.Join(dir, name)
+	if !storage.IsNoteFile(path) {
+		path += info.Extension()
+	}
+	if _, err := e.store.Stat(path); err == nil {
+		return fmt.Errorf("note already exists: %s", path)
+	}
+
+	text := *content
+	if text == "" {
+		if text, err = e.readStdin(); err != nil {
+			return err
+		}
+	}
+	if *template != "" {
+		tmpl, err := templates.NewTemplateManager(e.store.VaultDir()).GetTemplate(*template)
+		if err != nil {
+			return notFoundError("no template %q", *template)
+		}
+		body := tmpl.Content
+		if info.Format == models.FormatMarkdown {
+			body = tmpl.Render(strings.TrimSuffix(name, filepath.Ext(name)))
+		}
+		if text != "" {
+			body = strings.TrimRight(body, "\n") + "\n\n" + text
+		}
+		text = body
+	}
+
+	if err := e.store.WriteNote(path, text); err != nil {
+		return fmt.Errorf("error creating note: %w", err)
+	}
+	_, _ = e.store.NoteID(path)
+
+	fmt.Fprintln(e.stdout, path)
+	return nil
+}
+
+// runCat prints notes to stdout
+func runCat(e *env, args []string) error {
+	fs := e.newFlags("cat")
+	body := fs.Bool("body", false, "leave out the front matter")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usageError("expected a note")
+	}
+
+	for _, ref := range args {
+		note, err := e.resolveNote(ref)
+		if err != nil {
+			return err
+		}
+		content, err := e.store.ReadNote(note.Path)
+		if err != nil {
+			return err
+		}
+		if *body {
+			content = models.StripFrontMatter(content)
+		}
+
+		fmt.Fprint(e.stdout, content)
+		if !strings.HasSuffix(content, "\n") {
+			fmt.Fprintln(e.stdout)
+		}
+	}
+	return nil
+}
+
+// runExport exports notes into a folder and prints the folder
+func runExport(e *env, args []string) error {
+	fs := e.newFlags("export")
+	format := fs.String("format", "md", "export format (html, pdf, txt, md, json)")
+	out := fs.String("out", "totion-export", "folder to write the exported files to")
+	notebook := fs.String("notebook", "", "export every note in this notebook")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	exporter := export.NewExporter()
+	valid := false
+	for _, f := range exporter.GetExportFormats() {
+		valid = valid || string(f) == *format
+	}
+	if !valid {
+		return usageError("unknown export format %q", *format)
+	}
+
+	var paths []string
+	if len(args) > 0 {
+		if *notebook != "" {
+			return usageError("give notes or --notebook, not both")
+		}
+		for _, ref := range args {
+			note, err := e.resolveNote(ref)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, note.Path)
+		}
+	} else {
+		dir, err := e.notebookDir(*notebook)
+		if err != nil {
+			return err
+		}
+		notes, err := e.notesIn(dir)
+		if err != nil {
+			return err
+		}
+		for _, note := range notes {
+			paths = append(paths, note.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return notFoundError("no notes to export")
+	}
+
+	outDir, err := filepath.Abs(*out)
+	if err != nil {
+		return err
+	}
+	exported, err := exporter.BatchExport(export.LoadNotes(e.store, paths), outDir, export.ExportFormat(*format))
+	if err != nil {
+		return err
+	}
+
+	for _, ex := range exported {
+		if ex.Renamed {
+			rel, _ := filepath.Rel(e.store.VaultDir(), ex.NotePath)
+			fmt.Fprintf(e.stderr, "%s exported as %s, its name was taken\n", filepath.ToSlash(rel), ex.File)
+		}
+	}
+	fmt.Fprintf(e.stderr, "exported %d notes\n", len(exported))
+	fmt.Fprintln(e.stdout, outDir)
+	return nil
+}
+
+// runImport imports notes into <vault>/imported and prints what was imported
+func runImport(e *env, args []string) error {
+	fs := e.newFlags("import")
+	from := fs.String("from", "auto", "source type: auto, notion, obsidian, json, csv, text or dir")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError("expected one file or folder to import")
+	}
+
+	source, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return notFoundError("no such file or folder: %s", args[0])
+	}
+
+	kind := *from
+	if kind == "auto" {
+		kind = detectImportKind(source, info)
+	}
+
+	importer := importpkg.NewImporter(e.store.VaultDir())
+	var imported []string
+	switch kind {
+	case "notion":
+		imported, err = importer.ImportFromNotion(source)
+	case "obsidian":
+		imported, err = importer.ImportFromObsidian(source)
+	case "json":
+		imported, err = importer.ImportFromJSON(source)
+	case "csv":
+		imported, err = importer.ImportFromCSV(source)
+	case "text":
+		err = importer.ImportFromPlainText(source)
+		imported = []string{filepath.Base(source)}
+	case "dir":
+		imported, err = importer.BatchImportFromDirectory(source)
+	default:
+		return usageError("unknown source type %q", *from)
+	}
+	if err != nil {
+		return err
+	}
+	if len(imported) == 0 {
+		return notFoundError("nothing to import in %s", args[0])
+	}
+
+	for _, name := range imported {
+		fmt.Fprintln(e.stdout, name)
+	}
+	fmt.Fprintf(e.stderr, "imported %d notes into %s\n", len(imported), filepath.Join(e.store.VaultDir(), "imported"))
+	return nil
+}
+
+// detectImportKind guesses the source type from the file or folder
+func detectImportKind(source string, info os.FileInfo) string {
+	if info.IsDir() {
+		if _, err := os.Stat(filepath.Join(source, ".obsidian")); err == nil {
+			return "obsidian"
+		}
+		return "dir"
+	}
+
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	}
+	return "text"
+}
+
+// runDaily creates today's daily note and prints its path (or its content)
+func runDaily(e *env, args []string) error {
+	fs := e.newFlags("daily")
+	print := fs.Bool("cat", false, "print the note instead of its path")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError("unexpected argument %q", args[0])
+	}
+
+	path, err := daily.NewDailyManager(e.store.VaultDir()).CreateTodayNote()
+	if err != nil {
+		return fmt.Errorf("error creating daily note: %w", err)
+	}
+	_, _ = e.store.NoteID(path)
+
+	if !*print {
+		fmt.Fprintln(e.stdout, path)
+		return nil
+	}
+	content, err := e.store.ReadNote(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(e.stdout, content)
+	return nil
+}
+
+// runCapture appends text from the arguments or stdin to the scratch pad or
+// today's daily note, and prints the note it went to
+func runCapture(e *env, args []string) error {
+	fs := e.newFlags("capture")
+	toDaily := fs.Bool("daily", false, "append to today's daily note instead of the scratch pad")
+	heading := fs.String("heading", "", "append under this heading (added if missing)")
+	tagList := fs.String("tags", "", "comma-separated tags to add to the entry")
+	noTime := fs.Bool("no-time", false, "leave out the timestamp")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	text := strings.Join(args, " ")
+	if text == "" {
+		if text, err = e.readStdin(); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return usageError("nothing to capture, give text or pipe it in")
+	}
+
+	var entryTags []string
+	if *tagList != "" {
+		entryTags = strings.Split(*tagList, ",")
+	}
+	layout := "2006-01-02 15:04"
+	if *toDaily {
+		layout = "15:04" // The note is already dated
+	}
+	if *noTime {
+		layout = ""
+	}
+	entry := capture.Entry(text, time.Now(), layout, entryTags)
+
+	if *toDaily {
+		path, err := daily.NewDailyManager(e.store.VaultDir()).CreateTodayNote()
+		if err != nil {
+			return fmt.Errorf("error creating daily note: %w", err)
+		}
+		// Holding the note's lock keeps concurrent captures from losing entries,
+		// and fails if totion has the note open for editing
+		err = e.store.WithLock(path, func() error {
+			content, err := e.store.ReadNote(path)
+			if err != nil {
+				return err
+			}
+			return e.store.WriteNote(path, capture.Append(content, *heading, entry))
+		})
+		if err != nil {
+			return fmt.Errorf("error capturing to daily note: %w", err)
+		}
+		_, _ = e.store.NoteID(path)
+		fmt.Fprintln(e.stdout, path)
+		return nil
+	}
+
+	scratch := quick.NewQuickNoteManager(e.store.VaultDir())
+	err = e.store.WithLock(scratch.GetScratchPath(), func() error {
+		content, err := scratch.LoadScratch()
+		if err != nil {
+			return err
+		}
+		return scratch.SaveScratch(capture.Append(content, *heading, entry))
+	})
+	if err != nil {
+		return fmt.Errorf("error capturing to scratch pad: %w", err)
+	}
+	fmt.Fprintln(e.stdout, scratch.GetScratchPath())
+	return nil
+}
+
+// relPath returns the vault path of a note for output
+func relPath(store *storage.Storage, path string) string {
+	rel, err := filepath.Rel(store.VaultDir(), path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,440 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/features/attachments"
+	"github.com/0xshariq/totion/internal/features/export"
+	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/features/templates"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/notebook"
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+// completeCommand is the hidden command the completion scripts call
+// It prints the candidates for the last word, one per line
+const completeCommand = "__complete"
+
+// completeFiles asks the shell to complete file names instead
+const completeFiles = ":files"
+
+// completer lists the candidates for a word
+type completer func(e *env) []string
+
+// flagValues says what the value of a flag completes to, by "command.flag" or just "flag"
+var flagValues = map[string]completer{
+	"notebook":      (*env).notebookNames,
+	"template":      (*env).templateNames,
+	"tag":           (*env).tagNames,
+	"format":        noteFormats,
+	"export.format": exportFormats,
+	"export.out":    files,
+	"import.from":   words("auto", "notion", "obsidian", "json", "csv", "text", "dir"),
+	"search.mode":   words("exact", "fuzzy", "regex"),
+}
+
+// argValues says what the arguments of a command complete to
+var argValues = map[string]completer{
+	"cat":        (*env).noteNames,
+	"tags":       (*env).noteNames,
+	"tasks":      (*env).noteNames,
+	"export":     (*env).noteNames,
+	"import":     files,
+	"completion": words("bash", "zsh", "fish"),
+	"help":       commandNames,
+}
+
+// runCompletion prints the completion script for a shell
+func runCompletion(e *env, args []string) error {
+	fs := e.newFlags("completion")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError("expected a shell: bash, zsh or fish")
+	}
+
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		return usageError("unsupported shell %q, use bash, zsh or fish", args[0])
+	}
+	_, err = io.WriteString(e.stdout, script)
+	return err
+}
+
+// runComplete prints the completions of a command line
+// words are the words after "totion", the last one being the word under the
+// cursor (empty when the cursor follows a space)
+func runComplete(e *env, words []string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	// Global --vault, which also picks the vault the names come from
+	for len(words) > 1 && isFlag(words[0], "vault") {
+		name, rest := "", words[1:]
+		if value, ok := flagValue(words[0]); ok {
+			name = value
+		} else if len(rest) == 1 {
+			return e.printCandidates(vaultNames(e), rest[0])
+		} else {
+			name, rest = rest[0], rest[1:]
+		}
+		if _, err := e.cfg.UseVault(name); err == nil {
+			if store, err := storage.NewWithVault(e.cfg.VaultDir); err == nil {
+				_ = e.store.Close()
+				e.store = store
+			}
+		}
+		words = rest
+	}
+
+	current := words[len(words)-1]
+	if len(words) == 1 {
+		if strings.HasPrefix(current, "-") {
+			return e.printCandidates([]string{"--vault"}, current)
+		}
+		return e.printCandidates(append(commandNames(e), "help"), current)
+	}
+
+	name, args := words[0], words[1:len(words)-1]
+	cmd, ok := lookup(name)
+	if !ok {
+		if name == "help" {
+			return e.printCandidates(commandNames(e), current)
+		}
+		return nil
+	}
+	fs := e.flagsOf(cmd)
+
+	// Value of the flag before the cursor: "--notebook <TAB>" or "--notebook=<TAB>"
+	// (bash splits "--notebook=x" into "--notebook", "=", "x")
+	if n := len(args); n > 0 && !afterDashes(args) {
+		prev := args[n-1]
+		if prev == "=" && n > 1 {
+			prev = args[n-2]
+		}
+		if f := lookupFlag(fs, prev); f != nil && !isBoolFlag(f) {
+			if _, inline := flagValue(prev); !inline {
+				return e.completeFlag(cmd.name, f.Name, "", current)
+			}
+		}
+	}
+	if !afterDashes(args) && strings.HasPrefix(current, "-") {
+		if value, ok := flagValue(current); ok {
+			flagName := strings.TrimLeft(current[:len(current)-len(value)-1], "-")
+			return e.completeFlag(cmd.name, flagName, current[:len(current)-len(value)], value)
+		}
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) {
+			names = append(names, "--"+f.Name)
+		})
+		return e.printCandidates(names, current)
+	}
+
+	switch {
+	case name == "search" && strings.HasPrefix(current, "#"):
+		return e.printCandidates(prefixed("#", e.tagNames()), current)
+	case argValues[name] != nil:
+		return e.printCandidates(argValues[name](e), current)
+	}
+	return nil
+}
+
+// completeFlag prints the candidates for a flag value; prefix is what comes
+// before the value in the word ("--notebook=" or "")
+func (e *env) completeFlag(command, name, prefix, current string) error {
+	values, ok := flagValues[command+"."+name]
+	if !ok {
+		values, ok = flagValues[name]
+	}
+
+	switch {
+	case name == "tags":
+		// Comma-separated list: complete the last item
+		done := current[:strings.LastIndex(current, ",")+1]
+		return e.printCandidates(prefixed(prefix+done, e.tagNames()), prefix+current)
+	case !ok:
+		return nil
+	}
+	return e.printCandidates(prefixed(prefix, values(e)), prefix+current)
+}
+
+// printCandidates prints the candidates starting with the current word
+func (e *env) printCandidates(candidates []string, current string) error {
+	if len(candidates) == 1 && candidates[0] == completeFiles {
+		_, err := fmt.Fprintln(e.stdout, completeFiles)
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if seen[c] || !strings.HasPrefix(c, current) {
+			continue
+		}
+		seen[c] = true
+		if _, err := fmt.Fprintln(e.stdout, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagsOf returns the flags of a command
+// Every command parses its flags before doing anything, so running it with -h
+// builds its flag set without side effects
+func (e *env) flagsOf(cmd command) *flag.FlagSet {
+	quiet := *e
+	quiet.stdout, quiet.stderr = io.Discard, io.Discard
+	quiet.lastFlags = nil
+	_ = cmd.run(&quiet, []string{"-h"})
+	if quiet.lastFlags == nil {
+		return flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	}
+	return quiet.lastFlags
+}
+
+// noteNames lists the vault paths of the notes
+func (e *env) noteNames() []string {
+	notes, err := e.notesIn(e.store.VaultDir())
+	if err != nil {
+		return nil
+	}
+	names := make([]string, len(notes))
+	for i, note := range notes {
+		names[i] = note.RelID
+	}
+	return names
+}
+
+// notebookNames lists the vault paths of the notebooks and their subfolders
+func (e *env) notebookNames() []string {
+	notebooks, err := notebook.NewNotebookManager(e.store).ListNotebooks()
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, nb := range notebooks {
+		names = append(names, relPath(e.store, nb.Path))
+		_ = e.store.Walk(nb.Path, func(path string, info backend.FileInfo) error {
+			if info.IsDir && path != nb.Path && info.Name() != attachments.DirName {
+				names = append(names, relPath(e.store, path))
+			}
+			return nil
+		})
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tagNames lists the tags in the vault, building the tag index if needed
+func (e *env) tagNames() []string {
+	tm := tags.NewTagManager(e.store, e.store.VaultDir())
+	all := tm.GetAllTags()
+	if len(all) == 0 {
+		if err := tm.RebuildIndex(); err != nil {
+			return nil
+		}
+		all = tm.GetAllTags()
+	}
+
+	names := make([]string, len(all))
+	for i, info := range all {
+		names[i] = info.Tag
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateNames lists the note templates
+func (e *env) templateNames() []string {
+	var names []string
+	for _, t := range templates.NewTemplateManager(e.store.VaultDir()).GetTemplates() {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// vaultNames lists the vault profiles
+func vaultNames(e *env) []string {
+	var names []string
+	for _, profile := range e.cfg.VaultProfiles() {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// commandNames lists the subcommands
+func commandNames(e *env) []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// noteFormats lists the note formats
+func noteFormats(e *env) []string {
+	var names []string
+	for _, info := range models.Formats() {
+		names = append(names, string(info.Format))
+	}
+	return names
+}
+
+// exportFormats lists the export formats
+func exportFormats(e *env) []string {
+	var names []string
+	for _, f := range export.NewExporter().GetExportFormats() {
+		names = append(names, string(f))
+	}
+	return names
+}
+
+// files lets the shell complete file names
+func files(e *env) []string {
+	return []string{completeFiles}
+}
+
+// words completes to a fixed list
+func words(list ...string) completer {
+	return func(e *env) []string {
+		return list
+	}
+}
+
+// prefixed puts a prefix before every candidate
+func prefixed(prefix string, candidates []string) []string {
+	if prefix == "" {
+		return candidates
+	}
+	result := make([]string, len(candidates))
+	for i, c := range candidates {
+		result[i] = prefix + c
+	}
+	return result
+}
+
+// isFlag checks if a word is the flag name, in any of its forms (-name, --name, --name=value)
+func isFlag(word, name string) bool {
+	word = strings.TrimLeft(word, "-")
+	return word == name || strings.HasPrefix(word, name+"=")
+}
+
+// flagValue returns the value of a --name=value word
+func flagValue(word string) (string, bool) {
+	if !strings.HasPrefix(word, "-") {
+		return "", false
+	}
+	i := strings.Index(word, "=")
+	if i < 0 {
+		return "", false
+	}
+	return word[i+1:], true
+}
+
+// lookupFlag finds the flag a word names, nil if it isn't one
+func lookupFlag(fs *flag.FlagSet, word string) *flag.Flag {
+	if !strings.HasPrefix(word, "-") || word == "-" || word == "--" {
+		return nil
+	}
+	name := strings.TrimLeft(word, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	return fs.Lookup(name)
+}
+
+// isBoolFlag checks if a flag takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// afterDashes checks if "--" ended the flags
+func afterDashes(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return true
+		}
+	}
+	return false
+}
+
+// completionScripts are the scripts printed by totion completion
+// They pass the command line to totion __complete, which does the matching
+var completionScripts = map[string]string{
+	"bash": `# bash completion for totion
+# Add to ~/.bashrc: source <(totion completion bash)
+
+_totion() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    local candidates=($(totion ` + completeCommand + ` "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+
+    if [[ "${candidates[0]}" == "` + completeFiles + `" ]]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+        compopt -o filenames 2>/dev/null
+        return
+    fi
+
+    COMPREPLY=()
+    local candidate
+    for candidate in "${candidates[@]}"; do
+        COMPREPLY+=("$(printf '%q' "$candidate")")
+    done
+    if [[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == */ ]]; then
+        compopt -o nospace 2>/dev/null
+    fi
+}
+
+complete -F _totion totion
+`,
+	"zsh": `#compdef totion
+# zsh completion for totion
+# Add to ~/.zshrc (after compinit): source <(totion completion zsh)
+
+_totion() {
+    local -a candidates
+    candidates=("${(@f)$(totion ` + completeCommand + ` "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+
+    if [[ "${candidates[1]}" == "` + completeFiles + `" ]]; then
+        _files
+        return
+    fi
+
+    candidates=(${candidates:#})
+    (( ${#candidates} )) && compadd -- "${candidates[@]}"
+}
+
+if [[ "${funcstack[1]}" == "_totion" ]]; then
+    _totion "$@"
+else
+    compdef _totion totion
+fi
+`,
+	"fish": `# fish completion for totion
+# Save as ~/.config/fish/completions/totion.fish: totion completion fish > ~/.config/fish/completions/totion.fish
+
+function __totion_complete
+    set -l words (commandline -opc)[2..-1] (commandline -ct)
+    set -l candidates (totion ` + completeCommand + ` $words 2>/dev/null)
+
+    if test "$candidates[1]" = "` + completeFiles + `"
+        __fish_complete_path (commandline -ct)
+        return
+    end
+    printf '%s\n' $candidates
+end
+
+complete -c totion -f -a '(__totion_complete)'
+`,
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/0xshariq/totion/internal/features/doctor"
+)
+
+// runDoctor reports problems in the vault and, with --fix, repairs them
+// The exit code is 1 while problems remain, so it can run in scripts and hooks
+func runDoctor(e *env, args []string) error {
+	fs := e.newFlags("doctor")
+	fix := fs.Bool("fix", false, "repair the problems that can be repaired automatically")
+	dryRun := fs.Bool("dry-run", false, "with --fix, print what would be repaired without changing anything")
+	out := e.newOutput(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError("unexpected argument %q", args[0])
+	}
+	if *dryRun && !*fix {
+		return usageError("--dry-run only makes sense with --fix")
+	}
+
+	doc := doctor.NewDoctor(e.store)
+	issues, err := doc.Scan()
+	if err != nil {
+		return err
+	}
+
+	left := make(map[doctor.Issue]bool) // Issues still found after fixing
+	if *fix && !*dryRun {
+		if _, err := doc.Fix(issues); err != nil {
+			return err
+		}
+		// Fix skips notes changed or opened since the scan, so scan again to tell
+		after, err := doc.Scan()
+		if err != nil {
+			return err
+		}
+		for _, issue := range after {
+			left[issue] = true
+		}
+	}
+
+	remaining, fixable, fixed := 0, 0, 0
+	for _, issue := range issues {
+		item := issueItem{
+			Category: string(issue.Category),
+			Path:     relPath(e.store, issue.Path),
+			Detail:   issue.Detail,
+			Fix:      issue.Fix,
+		}
+
+		if issue.Fixable() {
+			fixable++
+		}
+		text := fmt.Sprintf("[%s] %s: %s", item.Category, item.Path, item.Detail)
+		switch {
+		case !issue.Fixable():
+			text += " (fix by hand)"
+			remaining++
+		case *dryRun:
+			text += " -> would " + issue.Fix
+			remaining++
+		case *fix:
+			item.Fixed = !left[issue]
+			if item.Fixed {
+				text += " -> " + issue.Fix
+				fixed++
+			} else {
+				remaining++
+			}
+		default:
+			text += " -> fixable: " + issue.Fix
+			remaining++
+		}
+
+		if err := out.add(item, text); err != nil {
+			return err
+		}
+	}
+	if err := out.flush(); err != nil {
+		return err
+	}
+
+	if !out.structured() {
+		switch {
+		case len(issues) == 0:
+			fmt.Fprintln(e.stderr, "No problems found")
+		case *fix && !*dryRun:
+			fmt.Fprintf(e.stderr, "%d problem(s) found, %d fixed\n", len(issues), fixed)
+		case fixable > 0 && !*dryRun:
+			fmt.Fprintf(e.stderr, "%d problem(s) found, %d can be fixed with --fix\n", len(issues), fixable)
+		default:
+			fmt.Fprintf(e.stderr, "%d problem(s) found\n", len(issues))
+		}
+	}
+	if remaining > 0 {
+		return &exitError{code: ExitError, err: fmt.Errorf("%d problem(s) remain", remaining)}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SchemaVersion is the version of the --json/--ndjson output schema
+// Fields may be added within a version; renaming or removing one bumps it
+const SchemaVersion = 1
+
+// envelope wraps the items printed by --json
+type envelope struct {
+	SchemaVersion int           `json:"schema_version"`
+	Command       string        `json:"command"`
+	Count         int           `json:"count"`
+	Items         []interface{} `json:"items"`
+}
+
+// noteItem is a note in list output
+type noteItem struct {
+	ID       string    `json:"id"`
+	Path     string    `json:"path"` // Vault-relative, with forward slashes
+	Name     string    `json:"name"`
+	Title    string    `json:"title"`
+	Format   string    `json:"format"`
+	Notebook string    `json:"notebook"` // "" for the vault root
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Tags     []string  `json:"tags"`
+}
+
+// matchItem is a search match
+type matchItem struct {
+	ID      string     `json:"id"`
+	Path    string     `json:"path"`
+	Line    int        `json:"line"` // 1-based
+	Text    string     `json:"text"`
+	Snippet string     `json:"snippet"`
+	Matches []spanItem `json:"matches"` // Where the query matched in text
+}
+
+// spanItem is the part of a line from byte start up to end
+type spanItem struct {
+	Start  int         `json:"start"`
+	End    int         `json:"end"`
+	Groups []*spanItem `json:"groups,omitempty"` // Regex capture groups, null for those that didn't take part
+}
+
+// tagItem is a tag with the notes that use it
+type tagItem struct {
+	Tag   string   `json:"tag"`
+	Count int      `json:"count"`
+	Notes []string `json:"notes"`
+}
+
+// taskItem is a checkbox or Org TODO in a note
+type taskItem struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	Line int    `json:"line"` // 1-based
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// statsItem is the statistics of the vault or a notebook
+type statsItem struct {
+	Notes             int    `json:"notes"`
+	Notebooks         int    `json:"notebooks"`
+	Words             int    `json:"words"`
+	Characters        int    `json:"characters"`
+	ReadingMinutes    int    `json:"reading_minutes"`
+	Tags              int    `json:"tags"`
+	Tasks             int    `json:"tasks"`
+	TasksDone         int    `json:"tasks_done"`
+	CurrentStreak     int    `json:"current_streak"`
+	LongestStreak     int    `json:"longest_streak"`
+	MostProductiveDay string `json:"most_productive_day"`
+}
+
+// notebookItem is a notebook
+type notebookItem struct {
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	Notes       int       `json:"notes"`
+	Description string    `json:"description"`
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+}
+
+// issueItem is a problem found by doctor
+type issueItem struct {
+	Category string `json:"category"`
+	Path     string `json:"path"`
+	Detail   string `json:"detail"`
+	Fix      string `json:"fix"`   // "" if it needs fixing by hand
+	Fixed    bool   `json:"fixed"` // Only true with --fix
+}
+
+// output prints a command's results as text, one JSON document or JSON lines
+type output struct {
+	command string
+	w       io.Writer
+	json    *bool
+	ndjson  *bool
+	items   []interface{}
+}
+
+// newOutput adds the --json and --ndjson flags to a command
+func (e *env) newOutput(fs *flag.FlagSet) *output {
+	return &output{
+		command: fs.Name(),
+		w:       e.stdout,
+		json:    fs.Bool("json", false, fmt.Sprintf("print one JSON document (schema version %d)", SchemaVersion)),
+		ndjson:  fs.Bool("ndjson", false, "print one JSON object per line"),
+		items:   []interface{}{},
+	}
+}
+
+// validate checks the output flags once they are parsed
+func (o *output) validate() error {
+	if *o.json && *o.ndjson {
+		return usageError("--json and --ndjson can't be used together")
+	}
+	return nil
+}
+
+// structured checks if JSON output was asked for
+func (o *output) structured() bool {
+	return *o.json || *o.ndjson
+}
+
+// add prints a result: text as a line, or item as JSON
+// With --json items are collected and printed by flush; an empty text prints nothing
+func (o *output) add(item interface{}, text string) error {
+	switch {
+	case *o.ndjson:
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(o.w, "%s\n", data)
+		return err
+	case *o.json:
+		o.items = append(o.items, item)
+		return nil
+	case text != "":
+		_, err := fmt.Fprintln(o.w, text)
+		return err
+	}
+	return nil
+}
+
+// flush prints the --json document; it does nothing in the other modes
+func (o *output) flush() error {
+	if !*o.json {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(envelope{
+		SchemaVersion: SchemaVersion,
+		Command:       o.command,
+		Count:         len(o.items),
+		Items:         o.items,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.w, "%s\n", data)
+	return err
+}
@@ -0,0 +1,488 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/features/search"
+	"github.com/0xshariq/totion/internal/features/stats"
+	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/features/tasks"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/notebook"
+	"github.com/0xshariq/totion/internal/storage"
+)
+
+// runList prints the notes of the vault, one vault path per line
+func runList(e *env, args []string) error {
+	fs := e.newFlags("list")
+	notebook := fs.String("notebook", "", "only list notes in this notebook")
+	format := fs.String("format", "", "only list notes in this format (md, txt, org, adoc)")
+	tag := fs.String("tag", "", "only list notes with this tag")
+	long := fs.Bool("l", false, "long listing: modification time, size and path")
+	absolute := fs.Bool("abs", false, "print absolute paths")
+	out := e.newOutput(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError("unexpected argument %q", args[0])
+	}
+	if *format != "" && !models.IsValidFormat(*format) {
+		return usageError("unknown format %q", *format)
+	}
+
+	dir, err := e.notebookDir(*notebook)
+	if err != nil {
+		return err
+	}
+	notes, err := e.notesIn(dir)
+	if err != nil {
+		return err
+	}
+	if *format != "" {
+		notes = e.store.FilterByFormat(notes, models.FileFormat(*format))
+	}
+
+	wantTag := strings.ToLower(strings.TrimPrefix(*tag, "#"))
+	listed := 0
+	for _, note := range notes {
+		var noteTags []string
+		if wantTag != "" || out.structured() {
+			noteTags = noteTagsOf(e.store, note)
+		}
+		if wantTag != "" && !contains(noteTags, wantTag) {
+			continue
+		}
+
+		path := note.RelID
+		if *absolute {
+			path = note.Path
+		}
+		text := path
+		if *long {
+			text = fmt.Sprintf("%s\t%d\t%s", note.ModTime.Format("2006-01-02 15:04"), note.Size, path)
+		}
+
+		item := noteItem{
+			ID:       note.ID,
+			Path:     note.RelID,
+			Name:     note.Name,
+			Title:    note.DisplayTitle(),
+			Format:   string(note.Format),
+			Notebook: filepath.ToSlash(note.Notebook),
+			Size:     note.Size,
+			Modified: note.ModTime,
+			Tags:     noteTags,
+		}
+		if item.Tags == nil {
+			item.Tags = []string{}
+		}
+		if err := out.add(item, text); err != nil {
+			return err
+		}
+		listed++
+	}
+
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if listed == 0 {
+		return notFoundError("no notes")
+	}
+	return nil
+}
+
+// noteTagsOf returns the tags of a note
+func noteTagsOf(store *storage.Storage, note models.Note) []string {
+	content, err := store.ReadFile(note.Path)
+	if err != nil {
+		return nil
+	}
+	return tags.ExtractNoteTags(note.Path, string(content))
+}
+
+// contains checks if a list has a value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// spanItems converts where a search matched in a line for the JSON output
+func spanItems(matches []search.LineMatch) []spanItem {
+	items := []spanItem{}
+	for _, match := range matches {
+		item := spanItem{Start: match.Start, End: match.End}
+		for _, group := range match.Groups {
+			if group.Start < 0 {
+				item.Groups = append(item.Groups, nil)
+				continue
+			}
+			item.Groups = append(item.Groups, &spanItem{Start: group.Start, End: group.End})
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// runSearch prints matches as <path>:<line>: <text>, like grep
+func runSearch(e *env, args []string) error {
+	fs := e.newFlags("search")
+	notebook := fs.String("notebook", "", "only search notes in this notebook")
+	filesOnly := fs.Bool("files", false, "print each matching note once instead of every match")
+	modeName := fs.String("mode", "exact", "how the query matches: exact, fuzzy (typos too) or regex (a regular expression per line)")
+	timeout := fs.Duration("timeout", search.DefaultRegexLimits.Timeout, "with --mode regex, stop searching after this long (0 for no limit)")
+	maxMatches := fs.Int("max-matches", search.DefaultRegexLimits.MaxMatches, "with --mode regex, stop after this many matches (0 for no limit)")
+	out := e.newOutput(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usageError("expected a search query")
+	}
+	query := strings.Join(args, " ")
+	mode, err := search.ParseMode(*modeName)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if *timeout < 0 || *maxMatches < 0 {
+		return usageError("--timeout and --max-matches can't be negative")
+	}
+
+	dir, err := e.notebookDir(*notebook)
+	if err != nil {
+		return err
+	}
+	sm := search.NewSearchManagerForDir(e.store, dir)
+	sm.SetMode(mode)
+	sm.SetRegexLimits(search.RegexLimits{Timeout: *timeout, MaxMatches: *maxMatches})
+	results, err := sm.Search(query)
+	var queryErr *search.QueryError
+	if errors.As(err, &queryErr) {
+		return usageError("%v", err)
+	}
+	var limitErr *search.LimitError
+	if err != nil && !errors.As(err, &limitErr) {
+		return err
+	}
+
+	ids := e.store.IDs().IDs()
+	printed := make(map[string]bool)
+	for _, result := range results {
+		rel := relPath(e.store, result.NotePath)
+		text := fmt.Sprintf("%s:%d: %s", rel, result.LineNumber, strings.TrimSpace(result.FullLine))
+		if *filesOnly {
+			// One item per note, its first match
+			if printed[rel] {
+				continue
+			}
+			text = rel
+			printed[rel] = true
+		}
+
+		item := matchItem{
+			ID:      ids[result.NotePath],
+			Path:    rel,
+			Line:    result.LineNumber,
+			Text:    result.FullLine,
+			Snippet: result.MatchSnippet,
+			Matches: spanItems(result.LineMatches),
+		}
+		if err := out.add(item, text); err != nil {
+			return err
+		}
+	}
+
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if limitErr != nil {
+		fmt.Fprintf(e.stderr, "warning: %v, raise --timeout or --max-matches to find more\n", limitErr)
+	}
+	if len(results) == 0 {
+		return notFoundError("no matches for %q", query)
+	}
+	return nil
+}
+
+// runTags prints tags with their note counts, or the tags of one note
+func runTags(e *env, args []string) error {
+	fs := e.newFlags("tags")
+	notebook := fs.String("notebook", "", "only count notes in this notebook")
+	out := e.newOutput(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return usageError("expected at most one note")
+	}
+
+	var notes []models.Note
+	if len(args) == 1 {
+		note, err := e.resolveNote(args[0])
+		if err != nil {
+			return err
+		}
+		notes = []models.Note{note}
+	} else {
+		dir, err := e.notebookDir(*notebook)
+		if err != nil {
+			return err
+		}
+		if notes, err = e.notesIn(dir); err != nil {
+			return err
+		}
+	}
+
+	tagged := make(map[string][]string) // Tag -> vault paths of its notes
+	for _, note := range notes {
+		for _, tag := range noteTagsOf(e.store, note) {
+			tagged[tag] = append(tagged[tag], note.RelID)
+		}
+	}
+
+	names := make([]string, 0, len(tagged))
+	for tag := range tagged {
+		names = append(names, tag)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(tagged[names[i]]) != len(tagged[names[j]]) {
+			return len(tagged[names[i]]) > len(tagged[names[j]])
+		}
+		return names[i] < names[j]
+	})
+
+	for _, tag := range names {
+		text := fmt.Sprintf("%d\t%s", len(tagged[tag]), tag)
+		if len(args) == 1 {
+			text = tag
+		}
+		item := tagItem{Tag: tag, Count: len(tagged[tag]), Notes: tagged[tag]}
+		if err := out.add(item, text); err != nil {
+			return err
+		}
+	}
+
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if len(names) == 0 && len(args) == 0 {
+		return notFoundError("no tags")
+	}
+	return nil
+}
+
+// runTasks prints the tasks of the vault as "[ ] <path>:<line>: <text>"
+func runTasks(e *env, args []string) error {
+	fs := e.newFlags("tasks")
+	notebook := fs.String("notebook", "", "only list tasks in this notebook")
+	open := fs.Bool("open", false, "only list tasks that aren't done")
+	done := fs.Bool("done", false, "only list tasks that are done")
+	out := e.newOutput(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if *open && *done {
+		return usageError("--open and --done can't be used together")
+	}
+
+	var notes []models.Note
+	if len(args) > 0 {
+		for _, ref := range args {
+			note, err := e.resolveNote(ref)
+			if err != nil {
+				return err
+			}
+			notes = append(notes, note)
+		}
+	} else {
+		dir, err := e.notebookDir(*notebook)
+		if err != nil {
+			return err
+		}
+		if notes, err = e.notesIn(dir); err != nil {
+			return err
+		}
+	}
+
+	taskManager := tasks.NewTaskManager()
+	found := 0
+	for _, note := range notes {
+		content, err := e.store.ReadNote(note.Path)
+		if err != nil {
+			continue
+		}
+
+		for _, task := range taskManager.ParseTasksForFormat(content, note.Format) {
+			if (*open && task.Completed) || (*done && !task.Completed) {
+				continue
+			}
+			found++
+
+			box := "[ ]"
+			if task.Completed {
+				box = "[x]"
+			}
+			item := taskItem{
+				ID:   note.ID,
+				Path: note.RelID,
+				Line: task.Line + 1,
+				Text: task.Text,
+				Done: task.Completed,
+			}
+			text := fmt.Sprintf("%s %s:%d: %s", box, item.Path, item.Line, item.Text)
+			if err := out.add(item, text); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if found == 0 {
+		return notFoundError("no tasks")
+	}
+	return nil
+}
+
+// runStats prints statistics of the vault or a notebook
+// Unlike the stats view it doesn't record today's activity, so scripts can run it freely
+func runStats(e *env, args []string) error {
+	fs := e.newFlags("stats")
+	notebookName := fs.String("notebook", "", "only count notes in this notebook")
+	out := e.newOutput(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError("unexpected argument %q", args[0])
+	}
+
+	dir, err := e.notebookDir(*notebookName)
+	if err != nil {
+		return err
+	}
+	notes, err := e.notesIn(dir)
+	if err != nil {
+		return err
+	}
+
+	statsManager := stats.NewStatsManagerWithConfig(e.store.VaultDir())
+	taskManager := tasks.NewTaskManager()
+	notebooks := make(map[string]bool)
+	allTags := make(map[string]bool)
+
+	item := statsItem{
+		Notes:             len(notes),
+		CurrentStreak:     statsManager.GetStreak(),
+		LongestStreak:     statsManager.GetLongestStreak(),
+		MostProductiveDay: statsManager.GetMostProductiveDay(),
+	}
+	for _, note := range notes {
+		if note.Notebook != "" {
+			notebooks[note.Notebook] = true
+		}
+		content, err := e.store.ReadNote(note.Path)
+		if err != nil {
+			continue
+		}
+
+		noteStats := statsManager.Calculate(content)
+		item.Words += noteStats.WordCount
+		item.Characters += noteStats.CharCount
+		for _, tag := range tags.ExtractNoteTags(note.Path, content) {
+			allTags[tag] = true
+		}
+		total, completed := taskManager.GetTaskStats(taskManager.ParseTasksForFormat(content, note.Format))
+		item.Tasks += total
+		item.TasksDone += completed
+	}
+	item.Notebooks = len(notebooks)
+	item.Tags = len(allTags)
+	item.ReadingMinutes = (item.Words + 199) / 200
+
+	text := fmt.Sprintf("notes\t%d\nnotebooks\t%d\nwords\t%d\ncharacters\t%d\nreading time\t%d min\ntags\t%d\ntasks\t%d (%d done)\ncurrent streak\t%d days\nlongest streak\t%d days",
+		item.Notes, item.Notebooks, item.Words, item.Characters, item.ReadingMinutes,
+		item.Tags, item.Tasks, item.TasksDone, item.CurrentStreak, item.LongestStreak)
+	if item.MostProductiveDay != "" {
+		text += "\nmost productive day\t" + item.MostProductiveDay
+	}
+	if err := out.add(item, text); err != nil {
+		return err
+	}
+	return out.flush()
+}
+
+// runNotebooks prints the notebooks of the vault with their note counts
+func runNotebooks(e *env, args []string) error {
+	fs := e.newFlags("notebooks")
+	out := e.newOutput(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError("unexpected argument %q", args[0])
+	}
+
+	notebooks, err := notebook.NewNotebookManager(e.store).ListNotebooks()
+	if err != nil {
+		return err
+	}
+	sort.Slice(notebooks, func(i, j int) bool {
+		return notebooks[i].Name < notebooks[j].Name
+	})
+
+	for _, nb := range notebooks {
+		item := notebookItem{
+			Name:        nb.Name,
+			Path:        relPath(e.store, nb.Path),
+			Notes:       nb.NoteCount,
+			Description: nb.Description,
+			Created:     nb.CreatedAt,
+			Modified:    nb.ModifiedAt,
+		}
+		if err := out.add(item, fmt.Sprintf("%d\t%s", item.Notes, item.Path)); err != nil {
+			return err
+		}
+	}
+
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if len(notebooks) == 0 {
+		return notFoundError("no notebooks")
+	}
+	return nil
+}
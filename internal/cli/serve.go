@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/0xshariq/totion/internal/config"
+	"github.com/0xshariq/totion/internal/server"
+)
+
+// EnvAPIToken sets the token of totion serve
+const EnvAPIToken = "TOTION_API_TOKEN"
+
+// tokenFile is where totion serve keeps its token, next to the config file
+const tokenFile = "api-token"
+
+// runServe serves the vault over the HTTP JSON API until interrupted
+func runServe(e *env, args []string) error {
+	fs := e.newFlags("serve")
+	addr := fs.String("addr", "127.0.0.1:4747", "address to listen on")
+	token := fs.String("token", "", "API token (default: $"+EnvAPIToken+", or one kept next to the config file)")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError("unexpected argument %q", args[0])
+	}
+
+	host, _, err := net.SplitHostPort(*addr)
+	if err != nil {
+		return usageError("invalid address %q: %v", *addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		fmt.Fprintf(e.stderr, "warning: listening on %s, the API (and its token) is sent unencrypted over the network\n", *addr)
+	}
+
+	if *token == "" {
+		*token = os.Getenv(EnvAPIToken)
+	}
+	if *token == "" {
+		configPath := e.cfg.ConfigPath()
+		if configPath == "" {
+			if configPath, err = config.Path(); err != nil {
+				return err
+			}
+		}
+		path := filepath.Join(filepath.Dir(configPath), tokenFile)
+		if *token, err = loadToken(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stderr, "API token is in %s\n", path)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", *addr, err)
+	}
+
+	srv := &http.Server{
+		Handler:           server.NewServer(e.store, *token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(e.stderr, "Serving %s on http://%s%s\n", e.store.VaultDir(), listener.Addr(), server.APIPrefix)
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving: %w", err)
+	}
+	e.store.ReleaseLocks()
+	return nil
+}
+
+// loadToken reads the saved API token, creating one on first use
+func loadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("error reading API token: %w", err)
+	}
+
+	token := server.NewToken()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("error creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("error saving API token: %w", err)
+	}
+	return token, nil
+}
@@ -1,43 +1,391 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+// CurrentVersion is the config file format written by this version of Totion
+const CurrentVersion = 1
+
+// EnvConfigPath overrides the location of the config file
+const EnvConfigPath = "TOTION_CONFIG"
+
 // Config holds the application configuration
 type Config struct {
-	VaultDir      string
-	DefaultFormat string
+	Version       int               `yaml:"version"`
+	VaultDir      string            `yaml:"vault_dir"`
+	DefaultFormat string            `yaml:"default_format"`
+	Theme         string            `yaml:"theme"`
+	MaxRecent     int               `yaml:"max_recent"`
+	MaxPinned     int               `yaml:"max_pinned"`
+	AutoSave      AutoSaveConfig    `yaml:"autosave"`
+	Pomodoro      PomodoroConfig    `yaml:"pomodoro"`
+	Bridge        BridgeConfig      `yaml:"bridge"`
+	Trash         TrashConfig       `yaml:"trash"`
+	Watch         WatchConfig       `yaml:"watch"`
+	History       HistoryConfig     `yaml:"history"`
+	Attachments   AttachmentsConfig `yaml:"attachments"`
+	Search        SearchConfig      `yaml:"search"`
+	Storage       StorageConfig     `yaml:"storage"`
+	DefaultVault  string            `yaml:"default_vault,omitempty"`
+	Vaults        []VaultProfile    `yaml:"vaults,omitempty"`
+
+	path        string            // File the config was loaded from
+	overrides   map[string]string // File values of keys overridden by env vars or vault switching
+	activeVault string            // Name of the vault profile in use
+}
+
+// AutoSaveConfig holds auto-save timings
+type AutoSaveConfig struct {
+	Interval     time.Duration `yaml:"interval"`
+	SwapInterval time.Duration `yaml:"swap_interval"`
+}
+
+// PomodoroConfig holds pomodoro timer durations
+type PomodoroConfig struct {
+	Work       time.Duration `yaml:"work"`
+	ShortBreak time.Duration `yaml:"short_break"`
+	LongBreak  time.Duration `yaml:"long_break"`
+}
+
+// BridgeConfig holds Lingo.dev bridge server settings
+type BridgeConfig struct {
+	Port int `yaml:"port"`
+}
+
+// TrashConfig holds the trash retention policy
+type TrashConfig struct {
+	RetentionDays int `yaml:"retention_days"` // 0 keeps deleted items forever
+}
+
+// WatchConfig holds settings for watching the vault for external changes
+type WatchConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Debounce time.Duration `yaml:"debounce"` // Quiet period before a batch of changes is applied
+}
+
+// HistoryConfig holds the per-note version history policy
+type HistoryConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	MaxVersions   int  `yaml:"max_versions"`   // Per note, 0 keeps all
+	RetentionDays int  `yaml:"retention_days"` // 0 keeps versions forever
+}
+
+// Retention returns how long versions are kept (0 means forever)
+func (h HistoryConfig) Retention() time.Duration {
+	return time.Duration(h.RetentionDays) * 24 * time.Hour
+}
+
+// Retention returns how long trashed items are kept (0 means forever)
+func (t TrashConfig) Retention() time.Duration {
+	return time.Duration(t.RetentionDays) * 24 * time.Hour
+}
+
+// AttachmentsConfig holds where attached files are stored
+type AttachmentsConfig struct {
+	PerNotebook bool `yaml:"per_notebook"` // Notes in a notebook use <notebook>/attachments instead of <vault>/attachments
+}
+
+// SearchConfig holds full-text search settings
+type SearchConfig struct {
+	Index bool `yaml:"index"` // Keep a full-text index in the vault instead of reading every note per search
+	Stem  bool `yaml:"stem"`  // Match other forms of English words ("meeting" finds "meetings")
+}
+
+// Storage backends
+const (
+	StorageFS     = "fs"     // Notes are files in the vault directory
+	StorageSQLite = "sqlite" // The whole vault is kept in one SQLite database file
+)
+
+// StorageConfig holds how the vault is stored
+type StorageConfig struct {
+	Backend string `yaml:"backend"` // StorageFS or StorageSQLite
+	Path    string `yaml:"path"`    // Database of the sqlite backend, relative to the vault directory unless absolute
+}
+
+// DatabasePath returns the SQLite database file of a vault
+func (s StorageConfig) DatabasePath(vaultDir string) string {
+	if filepath.IsAbs(s.Path) {
+		return s.Path
+	}
+	return filepath.Join(vaultDir, s.Path)
 }
 
 var AppConfig *Config
 
 // Initialize sets up the application configuration
-func Initialize() error {
+// vault selects a vault profile by name or path; empty uses default_vault
+func Initialize(vault string) error {
 	// Load .env file from current directory or parent directories
 	// godotenv.Load() will silently fail if .env doesn't exist, which is fine
 	_ = godotenv.Load()
 
-	homeDir, err := os.UserHomeDir()
+	path, err := Path()
 	if err != nil {
-		return fmt.Errorf("error getting home directory: %w", err)
+		return err
 	}
 
-	vaultDir := filepath.Join(homeDir, ".totion")
+	cfg, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	// Write defaults on first run so the file is easy to discover and edit
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+	}
+
+	if vault == "" {
+		vault = cfg.DefaultVault
+	}
+	if vault != "" {
+		if _, err := cfg.UseVault(vault); err != nil {
+			return err
+		}
+	}
 
 	// Create vault directory if it doesn't exist
-	if err := os.MkdirAll(vaultDir, 0750); err != nil {
+	if err := os.MkdirAll(cfg.VaultDir, 0750); err != nil {
 		return fmt.Errorf("error creating vault directory: %w", err)
 	}
 
-	AppConfig = &Config{
-		VaultDir:      vaultDir,
+	AppConfig = cfg
+	return nil
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	homeDir, _ := os.UserHomeDir()
+
+	return &Config{
+		Version:       CurrentVersion,
+		VaultDir:      filepath.Join(homeDir, ".totion"),
 		DefaultFormat: "md",
+		Theme:         "default",
+		MaxRecent:     10,
+		MaxPinned:     10,
+		AutoSave: AutoSaveConfig{
+			Interval:     30 * time.Second,
+			SwapInterval: 5 * time.Second,
+		},
+		Pomodoro: PomodoroConfig{
+			Work:       25 * time.Minute,
+			ShortBreak: 5 * time.Minute,
+			LongBreak:  15 * time.Minute,
+		},
+		Bridge: BridgeConfig{
+			Port: 3737,
+		},
+		Trash: TrashConfig{
+			RetentionDays: 30,
+		},
+		Watch: WatchConfig{
+			Enabled:  true,
+			Debounce: 300 * time.Millisecond,
+		},
+		History: HistoryConfig{
+			Enabled:       true,
+			MaxVersions:   100,
+			RetentionDays: 90,
+		},
+		Attachments: AttachmentsConfig{
+			PerNotebook: false,
+		},
+		Search: SearchConfig{
+			Index: true,
+			Stem:  false,
+		},
+		Storage: StorageConfig{
+			Backend: StorageFS,
+			Path:    ".vault.db",
+		},
+		overrides: make(map[string]string),
+	}
+}
+
+// Path returns the config file location
+// Defaults to <user config dir>/totion/config.yaml, overridable with TOTION_CONFIG
+func Path() (string, error) {
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return expandHome(path), nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting config directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "totion", "config.yaml"), nil
+}
+
+// Load reads the config file at path on top of the defaults
+// A missing file is not an error. Env overrides are applied before validation
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	cfg.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
 
+	if err == nil {
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+		if err := cfg.migrate(); err != nil {
+			return nil, fmt.Errorf("error in config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	cfg.VaultDir = expandHome(cfg.VaultDir)
+	cfg.Storage.Path = expandHome(cfg.Storage.Path)
+	for i := range cfg.Vaults {
+		cfg.Vaults[i].Path = expandHome(cfg.Vaults[i].Path)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// migrate upgrades older config files to the current version
+func (c *Config) migrate() error {
+	if c.Version > CurrentVersion {
+		return fmt.Errorf("config version %d is newer than supported version %d", c.Version, CurrentVersion)
+	}
+
+	// Version 0 is a file written before versioning, the keys are unchanged
+	if c.Version < 1 {
+		c.Version = 1
+	}
+
+	return nil
+}
+
+// applyEnv applies TOTION_* environment overrides (e.g. TOTION_AUTOSAVE_INTERVAL)
+func (c *Config) applyEnv() error {
+	for _, field := range fields {
+		value, ok := os.LookupEnv(EnvName(field.Key))
+		if !ok || value == "" {
+			continue
+		}
+
+		original := field.get(c)
+		if err := field.set(c, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvName(field.Key), err)
+		}
+		c.overrides[field.Key] = original
+	}
+	return nil
+}
+
+// Validate checks that every setting has a usable value
+func (c *Config) Validate() error {
+	var problems []string
+	for _, field := range fields {
+		if err := field.validate(c); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", field.Key, err))
+		}
+	}
+
+	if err := c.validateVaults(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
 	return nil
 }
+
+// ConfigPath returns the file the config was loaded from
+func (c *Config) ConfigPath() string {
+	return c.path
+}
+
+// IsOverridden reports whether a key is currently set by an environment variable
+// (or, for vault_dir, by switching vaults)
+func (c *Config) IsOverridden(key string) bool {
+	_, ok := c.overrides[key]
+	return ok
+}
+
+// Save writes the config file, keeping file values for env-overridden keys
+func (c *Config) Save() error {
+	if c.path == "" {
+		path, err := Path()
+		if err != nil {
+			return err
+		}
+		c.path = path
+	}
+
+	// Don't persist values that only come from the environment
+	saved := *c
+	for key, original := range c.overrides {
+		if field, ok := lookupField(key); ok {
+			_ = field.set(&saved, original)
+		}
+	}
+	saved.Version = CurrentVersion
+
+	var buf bytes.Buffer
+	buf.WriteString("# Totion configuration\n")
+	buf.WriteString("# Durations use Go syntax (30s, 5m, 1h). Any key can be overridden with\n")
+	buf.WriteString("# a TOTION_<KEY> environment variable, e.g. TOTION_AUTOSAVE_INTERVAL=1m\n\n")
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&saved); err != nil {
+		return fmt.Errorf("error encoding config: %w", err)
+	}
+	_ = encoder.Close()
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0750); err != nil {
+		return fmt.Errorf("error creating config directory: %w", err)
+	}
+
+	// Write to a temp file and rename so a crash can't truncate the config
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+
+	return nil
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/themes"
+)
+
+// Field describes a single editable setting
+type Field struct {
+	Key         string
+	Description string
+	Restart     bool // Takes effect after restarting Totion
+
+	get      func(c *Config) string
+	set      func(c *Config, value string) error
+	validate func(c *Config) error
+}
+
+// fields lists every setting in the order shown in the settings view
+var fields = []Field{
+	{
+		Key:         "vault_dir",
+		Description: "Directory where notes are stored",
+		Restart:     true,
+		get:         func(c *Config) string { return c.VaultDir },
+		set: func(c *Config, value string) error {
+			c.VaultDir = expandHome(strings.TrimSpace(value))
+			return nil
+		},
+		validate: func(c *Config) error {
+			if c.VaultDir == "" {
+				return fmt.Errorf("must not be empty")
+			}
+			if !filepath.IsAbs(c.VaultDir) {
+				return fmt.Errorf("must be an absolute path")
+			}
+			return nil
+		},
+	},
+	{
+		Key:         "default_format",
+		Description: "Format for new notes (" + strings.Join(formatNames(), ", ") + ")",
+		get:         func(c *Config) string { return c.DefaultFormat },
+		set: func(c *Config, value string) error {
+			c.DefaultFormat = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), ".")
+			return nil
+		},
+		validate: func(c *Config) error {
+			if !models.IsValidFormat(c.DefaultFormat) {
+				return fmt.Errorf("must be one of %s, got %q", strings.Join(formatNames(), ", "), c.DefaultFormat)
+			}
+			return nil
+		},
+	},
+	{
+		Key:         "theme",
+		Description: "Color theme (" + strings.Join(themes.BuiltinThemes, ", ") + ")",
+		get:         func(c *Config) string { return c.Theme },
+		set: func(c *Config, value string) error {
+			c.Theme = strings.ToLower(strings.TrimSpace(value))
+			return nil
+		},
+		validate: func(c *Config) error {
+			if !themes.IsBuiltin(c.Theme) {
+				return fmt.Errorf("unknown theme %q", c.Theme)
+			}
+			return nil
+		},
+	},
+	{
+		Key:         "max_recent",
+		Description: "Number of recent notes to remember",
+		get:         func(c *Config) string { return strconv.Itoa(c.MaxRecent) },
+		set:         intSetter(func(c *Config) *int { return &c.MaxRecent }),
+		validate:    intRange(func(c *Config) int { return c.MaxRecent }, 1, 100),
+	},
+	{
+		Key:         "max_pinned",
+		Description: "Maximum number of pinned notes",
+		get:         func(c *Config) string { return strconv.Itoa(c.MaxPinned) },
+		set:         intSetter(func(c *Config) *int { return &c.MaxPinned }),
+		validate:    intRange(func(c *Config) int { return c.MaxPinned }, 1, 100),
+	},
+	{
+		Key:         "autosave.interval",
+		Description: "How often open notes are saved",
+		Restart:     true,
+		get:         func(c *Config) string { return c.AutoSave.Interval.String() },
+		set:         durationSetter(func(c *Config) *time.Duration { return &c.AutoSave.Interval }),
+		validate:    durationRange(func(c *Config) time.Duration { return c.AutoSave.Interval }, time.Second, time.Hour),
+	},
+	{
+		Key:         "autosave.swap_interval",
+		Description: "How often unsaved edits go to the swap file",
+		Restart:     true,
+		get:         func(c *Config) string { return c.AutoSave.SwapInterval.String() },
+		set:         durationSetter(func(c *Config) *time.Duration { return &c.AutoSave.SwapInterval }),
+		validate:    durationRange(func(c *Config) time.Duration { return c.AutoSave.SwapInterval }, time.Second, time.Hour),
+	},
+	{
+		Key:         "pomodoro.work",
+		Description: "Pomodoro work session length",
+		get:         func(c *Config) string { return c.Pomodoro.Work.String() },
+		set:         durationSetter(func(c *Config) *time.Duration { return &c.Pomodoro.Work }),
+		validate:    durationRange(func(c *Config) time.Duration { return c.Pomodoro.Work }, time.Minute, 4*time.Hour),
+	},
+	{
+		Key:         "pomodoro.short_break",
+		Description: "Pomodoro short break length",
+		get:         func(c *Config) string { return c.Pomodoro.ShortBreak.String() },
+		set:         durationSetter(func(c *Config) *time.Duration { return &c.Pomodoro.ShortBreak }),
+		validate:    durationRange(func(c *Config) time.Duration { return c.Pomodoro.ShortBreak }, time.Minute, 4*time.Hour),
+	},
+	{
+		Key:         "pomodoro.long_break",
+		Description: "Pomodoro long break length",
+		get:         func(c *Config) string { return c.Pomodoro.LongBreak.String() },
+		set:         durationSetter(func(c *Config) *time.Duration { return &c.Pomodoro.LongBreak }),
+		validate:    durationRange(func(c *Config) time.Duration { return c.Pomodoro.LongBreak }, time.Minute, 4*time.Hour),
+	},
+	{
+		Key:         "trash.retention_days",
+		Description: "Days to keep deleted notes in the trash (0 = forever)",
+		get:         func(c *Config) string { return strconv.Itoa(c.Trash.RetentionDays) },
+		set:         intSetter(func(c *Config) *int { return &c.Trash.RetentionDays }),
+		validate:    intRange(func(c *Config) int { return c.Trash.RetentionDays }, 0, 3650),
+	},
+	{
+		Key:         "watch.enabled",
+		Description: "Refresh notes and indexes when files change outside Totion",
+		Restart:     true,
+		get:         func(c *Config) string { return strconv.FormatBool(c.Watch.Enabled) },
+		set:         boolSetter(func(c *Config) *bool { return &c.Watch.Enabled }),
+		validate:    func(c *Config) error { return nil },
+	},
+	{
+		Key:         "watch.debounce",
+		Description: "Quiet period before external changes are applied",
+		Restart:     true,
+		get:         func(c *Config) string { return c.Watch.Debounce.String() },
+		set:         durationSetter(func(c *Config) *time.Duration { return &c.Watch.Debounce }),
+		validate:    durationRange(func(c *Config) time.Duration { return c.Watch.Debounce }, 50*time.Millisecond, time.Minute),
+	},
+	{
+		Key:         "history.enabled",
+		Description: "Keep previous versions of notes on every save",
+		get:         func(c *Config) string { return strconv.FormatBool(c.History.Enabled) },
+		set:         boolSetter(func(c *Config) *bool { return &c.History.Enabled }),
+		validate:    func(c *Config) error { return nil },
+	},
+	{
+		Key:         "history.max_versions",
+		Description: "Versions kept per note (0 = all)",
+		get:         func(c *Config) string { return strconv.Itoa(c.History.MaxVersions) },
+		set:         intSetter(func(c *Config) *int { return &c.History.MaxVersions }),
+		validate:    intRange(func(c *Config) int { return c.History.MaxVersions }, 0, 10000),
+	},
+	{
+		Key:         "history.retention_days",
+		Description: "Days to keep previous versions (0 = forever)",
+		get:         func(c *Config) string { return strconv.Itoa(c.History.RetentionDays) },
+		set:         intSetter(func(c *Config) *int { return &c.History.RetentionDays }),
+		validate:    intRange(func(c *Config) int { return c.History.RetentionDays }, 0, 3650),
+	},
+	{
+		Key:         "attachments.per_notebook",
+		Description: "Store attachments in each notebook instead of the vault root",
+		get:         func(c *Config) string { return strconv.FormatBool(c.Attachments.PerNotebook) },
+		set:         boolSetter(func(c *Config) *bool { return &c.Attachments.PerNotebook }),
+		validate:    func(c *Config) error { return nil },
+	},
+	{
+		Key:         "search.index",
+		Description: "Keep a full-text index so searches don't read every note",
+		get:         func(c *Config) string { return strconv.FormatBool(c.Search.Index) },
+		set:         boolSetter(func(c *Config) *bool { return &c.Search.Index }),
+		validate:    func(c *Config) error { return nil },
+	},
+	{
+		Key:         "search.stem",
+		Description: "Match other forms of English words (meeting finds meetings)",
+		get:         func(c *Config) string { return strconv.FormatBool(c.Search.Stem) },
+		set:         boolSetter(func(c *Config) *bool { return &c.Search.Stem }),
+		validate:    func(c *Config) error { return nil },
+	},
+	{
+		Key:         "storage.backend",
+		Description: "Where notes are kept: fs (files in vault_dir) or sqlite (one database file)",
+		Restart:     true,
+		get:         func(c *Config) string { return c.Storage.Backend },
+		set: func(c *Config, value string) error {
+			c.Storage.Backend = strings.ToLower(strings.TrimSpace(value))
+			return nil
+		},
+		validate: func(c *Config) error {
+			if c.Storage.Backend != StorageFS && c.Storage.Backend != StorageSQLite {
+				return fmt.Errorf("must be %s or %s, got %q", StorageFS, StorageSQLite, c.Storage.Backend)
+			}
+			return nil
+		},
+	},
+	{
+		Key:         "storage.path",
+		Description: "Database file of the sqlite backend, relative to vault_dir unless absolute",
+		Restart:     true,
+		get:         func(c *Config) string { return c.Storage.Path },
+		set: func(c *Config, value string) error {
+			c.Storage.Path = expandHome(strings.TrimSpace(value))
+			return nil
+		},
+		validate: func(c *Config) error {
+			if c.Storage.Backend == StorageSQLite && c.Storage.Path == "" {
+				return fmt.Errorf("must not be empty with the sqlite backend")
+			}
+			return nil
+		},
+	},
+	{
+		Key:         "bridge.port",
+		Description: "Port of the Lingo.dev bridge server",
+		Restart:     true,
+		get:         func(c *Config) string { return strconv.Itoa(c.Bridge.Port) },
+		set:         intSetter(func(c *Config) *int { return &c.Bridge.Port }),
+		validate:    intRange(func(c *Config) int { return c.Bridge.Port }, 1, 65535),
+	},
+}
+
+// Fields returns all editable settings
+func Fields() []Field {
+	return fields
+}
+
+// EnvName returns the environment variable that overrides a key
+func EnvName(key string) string {
+	return "TOTION_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Get returns the current value of a setting as text
+func (c *Config) Get(key string) (string, bool) {
+	field, ok := lookupField(key)
+	if !ok {
+		return "", false
+	}
+	return field.get(c), true
+}
+
+// Set parses and validates a new value for a setting
+// The old value is kept if the new one is invalid
+func (c *Config) Set(key, value string) error {
+	field, ok := lookupField(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+
+	original := field.get(c)
+	if err := field.set(c, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if err := field.validate(c); err != nil {
+		_ = field.set(c, original)
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	// An explicit change replaces the environment override
+	delete(c.overrides, key)
+	return nil
+}
+
+// lookupField finds a field by key
+func lookupField(key string) (Field, bool) {
+	for _, field := range fields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// intSetter returns a setter that parses an integer into a field
+func intSetter(target func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("not a number: %q", value)
+		}
+		*target(c) = n
+		return nil
+	}
+}
+
+// boolSetter returns a setter that parses a boolean (true/false, yes/no, on/off) into a field
+func boolSetter(target func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true", "yes", "on", "1":
+			*target(c) = true
+		case "false", "no", "off", "0":
+			*target(c) = false
+		default:
+			return fmt.Errorf("not a boolean (true or false): %q", value)
+		}
+		return nil
+	}
+}
+
+// durationSetter returns a setter that parses a duration (e.g. 30s, 5m) into a field
+func durationSetter(target func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("not a duration (e.g. 30s, 5m): %q", value)
+		}
+		*target(c) = d
+		return nil
+	}
+}
+
+// intRange returns a validator for an integer within [min, max]
+func intRange(value func(c *Config) int, min, max int) func(c *Config) error {
+	return func(c *Config) error {
+		if n := value(c); n < min || n > max {
+			return fmt.Errorf("must be between %d and %d, got %d", min, max, n)
+		}
+		return nil
+	}
+}
+
+// durationRange returns a validator for a duration within [min, max]
+func durationRange(value func(c *Config) time.Duration, min, max time.Duration) func(c *Config) error {
+	return func(c *Config) error {
+		if d := value(c); d < min || d > max {
+			return fmt.Errorf("must be between %s and %s, got %s", min, max, d)
+		}
+		return nil
+	}
+}
+
+// formatNames lists the registered note formats (md, txt, ...)
+func formatNames() []string {
+	names := []string{}
+	for _, info := range models.Formats() {
+		names = append(names, string(info.Format))
+	}
+	return names
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultVaultName is the profile name used for vault_dir
+const DefaultVaultName = "default"
+
+// VaultProfile is a named vault directory
+type VaultProfile struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+// VaultProfiles returns all vaults, starting with the vault_dir from the file
+func (c *Config) VaultProfiles() []VaultProfile {
+	defaultDir := c.VaultDir
+	if original, ok := c.overrides["vault_dir"]; ok {
+		defaultDir = original
+	}
+
+	profiles := []VaultProfile{{Name: DefaultVaultName, Path: defaultDir}}
+	for _, profile := range c.Vaults {
+		if profile.Name == DefaultVaultName {
+			continue
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+// FindVault returns the vault profile with the given name
+func (c *Config) FindVault(name string) (VaultProfile, bool) {
+	for _, profile := range c.VaultProfiles() {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, true
+		}
+	}
+	return VaultProfile{}, false
+}
+
+// ActiveVault returns the vault profile in use
+// Vaults opened by path get a profile named after their directory
+func (c *Config) ActiveVault() VaultProfile {
+	name := c.activeVault
+	if name == "" {
+		name = DefaultVaultName
+	}
+	return VaultProfile{Name: name, Path: c.VaultDir}
+}
+
+// UseVault switches to a vault profile by name, or to a directory by path
+// The switch is not written to the config file
+func (c *Config) UseVault(nameOrPath string) (VaultProfile, error) {
+	profile, ok := c.FindVault(nameOrPath)
+	if !ok {
+		if !strings.ContainsRune(nameOrPath, filepath.Separator) && !strings.HasPrefix(nameOrPath, "~") && nameOrPath != "." {
+			return VaultProfile{}, fmt.Errorf("unknown vault %q (add it under vaults: in %s)", nameOrPath, c.path)
+		}
+
+		path, err := filepath.Abs(expandHome(nameOrPath))
+		if err != nil {
+			return VaultProfile{}, fmt.Errorf("error resolving vault path: %w", err)
+		}
+		profile = VaultProfile{Name: filepath.Base(path), Path: path}
+	}
+
+	// Keep the file value of vault_dir so Save doesn't persist the switch
+	if _, overridden := c.overrides["vault_dir"]; !overridden {
+		c.overrides["vault_dir"] = c.VaultDir
+	}
+	if profile.Name == DefaultVaultName && profile.Path == c.overrides["vault_dir"] {
+		delete(c.overrides, "vault_dir")
+	}
+
+	c.VaultDir = profile.Path
+	c.activeVault = profile.Name
+	return profile, nil
+}
+
+// validateVaults checks vault profile names and paths
+func (c *Config) validateVaults() error {
+	seen := map[string]bool{DefaultVaultName: true}
+	for _, profile := range c.Vaults {
+		name := strings.ToLower(profile.Name)
+		switch {
+		case name == "":
+			return fmt.Errorf("vaults: every vault needs a name")
+		case seen[name]:
+			return fmt.Errorf("vaults: duplicate or reserved name %q", profile.Name)
+		case !filepath.IsAbs(profile.Path):
+			return fmt.Errorf("vaults: path for %q must be absolute", profile.Name)
+		}
+		seen[name] = true
+	}
+
+	if c.DefaultVault != "" && !seen[strings.ToLower(c.DefaultVault)] {
+		return fmt.Errorf("default_vault: unknown vault %q", c.DefaultVault)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+// DirName is the folder attachments are copied into, at the vault root or in a notebook
+const DirName = "attachments"
+
+// Link is a markdown link from a note to a local file that isn't a note
+type Link struct {
+	Text   string // Link text, or alt text for images
+	Target string // As written in the note, relative to the note's folder
+	Image  bool   // ![alt](target)
+}
+
+// MissingLink is a link to an attachment that doesn't exist
+type MissingLink struct {
+	NotePath string
+	Target   string
+}
+
+// Report lists attachments nothing links to and links to attachments that are gone
+type Report struct {
+	Attachments int           // Files found in attachment folders
+	Orphaned    []string      // Attachment paths no note links to
+	Missing     []MissingLink // Links whose file doesn't exist
+}
+
+// AttachmentManager copies files into the vault and keeps track of what notes link to
+type AttachmentManager struct {
+	store       *storage.Storage
+	perNotebook bool
+}
+
+// NewAttachmentManager creates an attachment manager for a vault
+// With perNotebook, notes in a notebook keep attachments in <notebook>/attachments
+// instead of <vault>/attachments
+func NewAttachmentManager(store *storage.Storage, perNotebook bool) *AttachmentManager {
+	return &AttachmentManager{
+		store:       store,
+		perNotebook: perNotebook,
+	}
+}
+
+// Dir returns the attachment folder used for a note
+func (am *AttachmentManager) Dir(notePath string) string {
+	vaultDir := am.store.VaultDir()
+	if !am.perNotebook {
+		return filepath.Join(vaultDir, DirName)
+	}
+
+	// Top-level notebook of the note, like models.Note.Notebook
+	rel, err := filepath.Rel(vaultDir, filepath.Dir(notePath))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return filepath.Join(vaultDir, DirName)
+	}
+	notebook := strings.Split(filepath.ToSlash(rel), "/")[0]
+	return filepath.Join(vaultDir, notebook, DirName)
+}
+
+// Add copies a file from disk into the note's attachment folder and returns a
+// markdown link to it, relative to the note
+// A file with the same name and content is reused; a different file with the same
+// name gets a numbered name
+func (am *AttachmentManager) Add(notePath, srcPath string) (string, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("error reading file: %w", err)
+	}
+
+	name := filepath.Base(srcPath)
+	if storage.IsHidden(name) {
+		name = strings.TrimLeft(name, ".")
+	}
+	if name == "" {
+		return "", fmt.Errorf("invalid file name: %s", filepath.Base(srcPath))
+	}
+
+	destPath, err := am.place(am.Dir(notePath), name, data)
+	if err != nil {
+		return "", err
+	}
+
+	target, err := filepath.Rel(filepath.Dir(notePath), destPath)
+	if err != nil {
+		return "", err
+	}
+	return MarkdownLink(name, filepath.ToSlash(target)), nil
+}
+
+// place writes data into dir under name, or a numbered variant of it, unless an
+// identical file is already there
+func (am *AttachmentManager) place(dir, name string, data []byte) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for i := 1; ; i++ {
+		candidate := filepath.Join(dir, name)
+		existing, err := am.store.ReadFile(candidate)
+		if errors.Is(err, fs.ErrNotExist) {
+			if err := am.store.WriteFile(candidate, data); err != nil {
+				return "", fmt.Errorf("error copying attachment: %w", err)
+			}
+			return candidate, nil
+		}
+		if err == nil && bytes.Equal(existing, data) {
+			return candidate, nil
+		}
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+// WriteToDir writes an attachment into a folder on disk (e.g. an export) and
+// returns the name used, numbered if a different file already has the name
+func WriteToDir(dir, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating attachments directory: %w", err)
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for i := 1; ; i++ {
+		destPath := filepath.Join(dir, name)
+		existing, err := os.ReadFile(destPath)
+		if os.IsNotExist(err) {
+			if err := os.WriteFile(destPath, data, 0644); err != nil {
+				return "", fmt.Errorf("error writing attachment: %w", err)
+			}
+			return name, nil
+		}
+		if err == nil && bytes.Equal(existing, data) {
+			return name, nil
+		}
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+// Scan checks every note in the vault for links to missing files and every
+// attachment folder for files no note links to
+func (am *AttachmentManager) Scan() (*Report, error) {
+	vaultDir := am.store.VaultDir()
+	report := &Report{
+		Orphaned: []string{},
+		Missing:  []MissingLink{},
+	}
+
+	notes, err := am.store.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	linked := make(map[string]bool)
+	for _, note := range notes {
+		content, err := am.store.ReadFile(note.Path)
+		if err != nil {
+			continue
+		}
+
+		for _, link := range Links(string(content)) {
+			target := Resolve(note.Path, link.Target)
+			linked[target] = true
+			if _, err := am.store.Stat(target); err != nil {
+				report.Missing = append(report.Missing, MissingLink{NotePath: note.Path, Target: link.Target})
+			}
+		}
+	}
+
+	err = am.store.Walk(vaultDir, func(path string, info backend.FileInfo) error {
+		if info.IsDir || !inAttachmentDir(vaultDir, path) || storage.IsHidden(info.Name()) {
+			return nil
+		}
+
+		report.Attachments++
+		if !linked[path] {
+			report.Orphaned = append(report.Orphaned, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning attachments: %w", err)
+	}
+
+	sort.Strings(report.Orphaned)
+	return report, nil
+}
+
+// inAttachmentDir checks if a vault path is inside an attachment folder
+func inAttachmentDir(vaultDir, path string) bool {
+	rel, err := filepath.Rel(vaultDir, filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if part == DirName {
+			return true
+		}
+	}
+	return false
+}
+
+// linkPattern matches [text](target) and ![alt](target), with an optional "title"
+// Targets with spaces are written as <target>
+var linkPattern = regexp.MustCompile(`(!?)\[([^\]]*)\]\((?:<([^>]+)>|([^)\s]+))(?:\s+"[^"]*")?\)`)
+
+// Links returns the links in content that point at local files other than notes
+func Links(content string) []Link {
+	links := []Link{}
+	for _, match := range linkPattern.FindAllStringSubmatch(content, -1) {
+		target := match[3] + match[4]
+		if !isLocalFile(target) {
+			continue
+		}
+		links = append(links, Link{
+			Text:   match[2],
+			Target: target,
+			Image:  match[1] == "!",
+		})
+	}
+	return links
+}
+
+// Rewrite replaces link targets in content; fn returns the new target, or false
+// to leave a link as it is
+func Rewrite(content string, fn func(link Link) (string, bool)) string {
+	return linkPattern.ReplaceAllStringFunc(content, func(s string) string {
+		match := linkPattern.FindStringSubmatch(s)
+		link := Link{Text: match[2], Target: match[3] + match[4], Image: match[1] == "!"}
+		if !isLocalFile(link.Target) {
+			return s
+		}
+
+		target, ok := fn(link)
+		if !ok {
+			return s
+		}
+		return markdownLink(link.Text, target, link.Image)
+	})
+}
+
+// isLocalFile checks if a link target is a relative path to a file that isn't a note
+func isLocalFile(target string) bool {
+	if target == "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+		return false
+	}
+	if u, err := url.Parse(target); err != nil || u.Scheme != "" {
+		return false // http:, mailto:, file: and the like
+	}
+	return !storage.IsNoteFile(strings.SplitN(target, "#", 2)[0])
+}
+
+// Resolve returns the path a link target in a note points at
+func Resolve(notePath, target string) string {
+	target = strings.SplitN(target, "#", 2)[0]
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	return filepath.Join(filepath.Dir(notePath), filepath.FromSlash(target))
+}
+
+// MarkdownLink returns a markdown link to a file, as an image for image files
+func MarkdownLink(name, target string) string {
+	return markdownLink(name, target, IsImage(name))
+}
+
+// markdownLink formats a link, wrapping targets with spaces in <>
+func markdownLink(text, target string, image bool) string {
+	if strings.ContainsAny(target, " ()") {
+		target = "<" + target + ">"
+	}
+	if image {
+		return fmt.Sprintf("![%s](%s)", text, target)
+	}
+	return fmt.Sprintf("[%s](%s)", text, target)
+}
+
+// imageExtensions are shown inline with ![alt](target)
+var imageExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".webp": true,
+	".svg":  true,
+	".bmp":  true,
+}
+
+// IsImage checks if a file name has an image extension
+func IsImage(name string) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(name))]
+}
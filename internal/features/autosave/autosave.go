@@ -1,6 +1,7 @@
 package autosave
 
 import (
+	"sync"
 	"time"
 )
 
@@ -10,20 +11,49 @@ type AutoSaver struct {
 	ticker       *time.Ticker
 	stopChan     chan bool
 	saveCallback func() error
+	swapInterval time.Duration
+	swapTicker   *time.Ticker
+	swapCallback func() error
+	running      bool
+	mu           sync.Mutex
 }
 
-// NewAutoSaver creates a new auto-saver with 30 second interval
-func NewAutoSaver(saveCallback func() error) *AutoSaver {
+// NewAutoSaver creates a new auto-saver
+// Non-positive intervals fall back to 30s saves and 5s swap writes
+func NewAutoSaver(interval, swapInterval time.Duration, saveCallback func() error) *AutoSaver {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if swapInterval <= 0 {
+		swapInterval = 5 * time.Second
+	}
+
 	return &AutoSaver{
-		interval:     30 * time.Second,
+		interval:     interval,
 		stopChan:     make(chan bool),
 		saveCallback: saveCallback,
+		swapInterval: swapInterval,
 	}
 }
 
+// SetSwapCallback sets a callback that writes the editor buffer to a swap file
+// It runs more often than the save callback so little work is lost on a crash
+func (a *AutoSaver) SetSwapCallback(swapCallback func() error) {
+	a.swapCallback = swapCallback
+}
+
 // Start begins the auto-save timer
 func (a *AutoSaver) Start() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.running {
+		return
+	}
+	a.running = true
+
 	a.ticker = time.NewTicker(a.interval)
+	a.swapTicker = time.NewTicker(a.swapInterval)
 	go func() {
 		for {
 			select {
@@ -31,6 +61,10 @@ func (a *AutoSaver) Start() {
 				if a.saveCallback != nil {
 					_ = a.saveCallback()
 				}
+			case <-a.swapTicker.C:
+				if a.swapCallback != nil {
+					_ = a.swapCallback()
+				}
 			case <-a.stopChan:
 				return
 			}
@@ -40,9 +74,20 @@ func (a *AutoSaver) Start() {
 
 // Stop stops the auto-save timer
 func (a *AutoSaver) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.running {
+		return
+	}
+	a.running = false
+
 	if a.ticker != nil {
 		a.ticker.Stop()
 	}
+	if a.swapTicker != nil {
+		a.swapTicker.Stop()
+	}
 	a.stopChan <- true
 }
 
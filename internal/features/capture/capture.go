@@ -0,0 +1,127 @@
+package capture
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Entry formats captured text as a list item, e.g. "- 14:05 call Sam #work"
+// Lines after the first are indented to stay part of the item; an empty
+// timeLayout leaves the timestamp out
+func Entry(text string, at time.Time, timeLayout string, tags []string) string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n")), "\n")
+
+	first := strings.TrimSpace(lines[0])
+	if timeLayout != "" {
+		first = at.Format(timeLayout) + " " + first
+	}
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if tag != "" {
+			first += " #" + tag
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("- " + first + "\n")
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString("  " + strings.TrimRight(line, " \t") + "\n")
+	}
+	return b.String()
+}
+
+// Append adds an entry to the end of a Markdown section, or to the end of the
+// note when heading is ""
+// The heading matches case-insensitively, ignoring its # markers and a leading
+// emoji ("Notes" finds "## 📝 Notes"); a missing heading is added at the end
+func Append(content, heading, entry string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	if heading == "" {
+		return appendLines(lines, len(lines), entry)
+	}
+
+	start, level := findHeading(lines, heading)
+	if start < 0 {
+		lines = append(lines, "", "## "+strings.TrimSpace(strings.TrimLeft(heading, "#")), "")
+		return appendLines(lines, len(lines), entry)
+	}
+
+	// The section ends at the next heading of the same or a higher level
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if l := headingLevel(lines[i]); l > 0 && l <= level {
+			end = i
+			break
+		}
+	}
+	return appendLines(lines, end, entry)
+}
+
+// appendLines inserts an entry after the last non-blank line before end
+func appendLines(lines []string, end int, entry string) string {
+	at := end
+	for at > 0 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+
+	result := make([]string, 0, len(lines)+4)
+	result = append(result, lines[:at]...)
+	if at > 0 && !isListLine(lines[at-1]) {
+		result = append(result, "") // Start a new list after a heading or paragraph
+	}
+	result = append(result, strings.Split(strings.TrimRight(entry, "\n"), "\n")...)
+	if at == end && end < len(lines) {
+		result = append(result, "") // Keep the next heading apart
+	}
+	result = append(result, lines[at:]...)
+	return strings.Join(result, "\n") + "\n"
+}
+
+// findHeading returns the line and level of a heading, or -1
+func findHeading(lines []string, heading string) (int, int) {
+	want := normalizeHeading(heading)
+	for i, line := range lines {
+		if level := headingLevel(line); level > 0 && normalizeHeading(line) == want {
+			return i, level
+		}
+	}
+	return -1, 0
+}
+
+// headingLevel returns the level of a Markdown heading line, or 0
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0
+	}
+	return level
+}
+
+// normalizeHeading strips # markers, leading symbols and case from a heading
+func normalizeHeading(heading string) string {
+	heading = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(heading), "#"))
+	heading = strings.TrimLeftFunc(heading, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.ToLower(strings.TrimSpace(heading))
+}
+
+// isListLine checks if a line is a list item or the continuation of one
+func isListLine(line string) bool {
+	if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+		return true
+	}
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "+ ")
+}
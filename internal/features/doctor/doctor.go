@@ -0,0 +1,364 @@
+package doctor
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/features/attachments"
+	"github.com/0xshariq/totion/internal/features/linking"
+	"github.com/0xshariq/totion/internal/features/pinned"
+	"github.com/0xshariq/totion/internal/features/recent"
+	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/notebook"
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+// Category groups problems found in a vault
+type Category string
+
+const (
+	CategoryPinned     Category = "pinned"             // Pins pointing at notes that are gone or moved
+	CategoryRecent     Category = "recent"             // Recent entries pointing at notes that are gone or moved
+	CategoryTagIndex   Category = "tag-index"          // Tag index entries that don't match the notes
+	CategoryNotebook   Category = "notebook-metadata"  // .notebook.json files that don't parse
+	CategoryLink       Category = "broken-link"        // [[links]] to notes that don't exist
+	CategoryAttachment Category = "missing-attachment" // Links to attachments that don't exist
+	CategoryEmpty      Category = "empty-note"         // Notes with no content
+)
+
+// Categories lists the categories in the order problems are reported
+var Categories = []Category{
+	CategoryPinned,
+	CategoryRecent,
+	CategoryTagIndex,
+	CategoryNotebook,
+	CategoryLink,
+	CategoryAttachment,
+	CategoryEmpty,
+}
+
+// Issue is a problem found in a vault
+type Issue struct {
+	Category Category `json:"category"`
+	Path     string   `json:"path"`          // File the problem is in or about
+	Detail   string   `json:"detail"`        // What is wrong
+	Fix      string   `json:"fix,omitempty"` // What Fix would do, "" if it needs fixing by hand
+}
+
+// Fixable checks if Fix can repair the issue
+func (i Issue) Fixable() bool {
+	return i.Fix != ""
+}
+
+// Doctor checks a vault for broken indexes, metadata, links and notes
+type Doctor struct {
+	store *storage.Storage
+}
+
+// NewDoctor creates a doctor for a vault
+func NewDoctor(store *storage.Storage) *Doctor {
+	return &Doctor{store: store}
+}
+
+// Scan checks the vault and returns every problem found, by category
+func (d *Doctor) Scan() ([]Issue, error) {
+	notes, err := d.store.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []Issue{}
+	issues = append(issues, d.checkPinned()...)
+	issues = append(issues, d.checkRecent()...)
+	issues = append(issues, d.checkTagIndex(notes)...)
+
+	notebookIssues, err := d.checkNotebooks()
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, notebookIssues...)
+	issues = append(issues, d.checkLinks(notes)...)
+
+	report, err := attachments.NewAttachmentManager(d.store, false).Scan()
+	if err != nil {
+		return nil, err
+	}
+	for _, missing := range report.Missing {
+		issues = append(issues, Issue{
+			Category: CategoryAttachment,
+			Path:     missing.NotePath,
+			Detail:   fmt.Sprintf("links to missing file %s", missing.Target),
+		})
+	}
+
+	issues = append(issues, d.checkEmpty(notes)...)
+	return issues, nil
+}
+
+// Fix repairs the fixable issues and returns how many were fixed
+// Issues needing a human (broken links, missing attachments) are left alone
+func (d *Doctor) Fix(issues []Issue) (int, error) {
+	vaultDir := d.store.VaultDir()
+	fixed := 0
+	byCategory := make(map[Category][]Issue)
+	for _, issue := range issues {
+		if issue.Fixable() {
+			byCategory[issue.Category] = append(byCategory[issue.Category], issue)
+		}
+	}
+
+	if found := byCategory[CategoryPinned]; len(found) > 0 {
+		pm := pinned.NewPinnedManager(vaultDir, 0)
+		if _, err := pm.Resolve(d.store.ResolveNote); err != nil {
+			return fixed, fmt.Errorf("error updating pins: %w", err)
+		}
+		if _, err := pm.Prune(d.store.ResolveNote); err != nil {
+			return fixed, fmt.Errorf("error removing pins: %w", err)
+		}
+		fixed += len(found)
+	}
+
+	if found := byCategory[CategoryRecent]; len(found) > 0 {
+		rm := recent.NewRecentManager(vaultDir, 0)
+		if _, err := rm.Resolve(d.store.ResolveNote); err != nil {
+			return fixed, fmt.Errorf("error updating recent notes: %w", err)
+		}
+		if _, err := rm.Prune(d.store.ResolveNote); err != nil {
+			return fixed, fmt.Errorf("error removing recent notes: %w", err)
+		}
+		fixed += len(found)
+	}
+
+	if found := byCategory[CategoryTagIndex]; len(found) > 0 {
+		if err := tags.NewTagManager(d.store, vaultDir).RebuildIndex(); err != nil {
+			return fixed, fmt.Errorf("error rebuilding tag index: %w", err)
+		}
+		fixed += len(found)
+	}
+
+	nm := notebook.NewNotebookManager(d.store)
+	for _, issue := range byCategory[CategoryNotebook] {
+		if err := nm.RepairMetadata(filepath.Dir(issue.Path)); err != nil {
+			return fixed, err
+		}
+		fixed++
+	}
+
+	for _, issue := range byCategory[CategoryEmpty] {
+		// Check again: the note may have been written or opened since the scan
+		if !d.isEmpty(issue.Path) {
+			continue
+		}
+		if _, locked := d.store.LockHolder(issue.Path); locked {
+			continue
+		}
+		if err := d.store.DeleteNote(issue.Path); err != nil {
+			return fixed, err
+		}
+		fixed++
+	}
+
+	return fixed, nil
+}
+
+// checkPinned finds pins whose note is gone or has moved
+func (d *Doctor) checkPinned() []Issue {
+	issues := []Issue{}
+	for _, p := range pinned.NewPinnedManager(d.store.VaultDir(), 0).GetPinned() {
+		if issue, ok := d.checkEntry(CategoryPinned, "pinned", p.ID, p.Path); ok {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// checkRecent finds recent entries whose note is gone or has moved
+func (d *Doctor) checkRecent() []Issue {
+	issues := []Issue{}
+	for _, n := range recent.NewRecentManager(d.store.VaultDir(), 0).GetRecent() {
+		if issue, ok := d.checkEntry(CategoryRecent, "recent", n.ID, n.Path); ok {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// checkEntry checks that a pinned or recent entry still points at its note
+func (d *Doctor) checkEntry(category Category, list, id, path string) (Issue, bool) {
+	note, ok := d.store.ResolveNote(id, path)
+	if !ok {
+		return Issue{
+			Category: category,
+			Path:     path,
+			Detail:   fmt.Sprintf("%s note no longer exists", list),
+			Fix:      fmt.Sprintf("remove from %s notes", list),
+		}, true
+	}
+	if note.Path != path {
+		return Issue{
+			Category: category,
+			Path:     path,
+			Detail:   fmt.Sprintf("%s note moved to %s", list, note.RelID),
+			Fix:      fmt.Sprintf("point the %s entry at %s", list, note.RelID),
+		}, true
+	}
+	return Issue{}, false
+}
+
+// checkTagIndex compares the saved tag index with the tags in the notes
+func (d *Doctor) checkTagIndex(notes []models.Note) []Issue {
+	issues := []Issue{}
+	if _, err := d.store.Stat(filepath.Join(d.store.VaultDir(), ".tags.json")); err != nil {
+		return issues // Never built, nothing to be stale
+	}
+
+	actual := make(map[string]map[string]bool) // Tag -> note paths
+	for _, note := range notes {
+		content, err := d.store.ReadFile(note.Path)
+		if err != nil {
+			continue
+		}
+		for _, tag := range tags.ExtractNoteTags(note.Path, string(content)) {
+			if actual[tag] == nil {
+				actual[tag] = make(map[string]bool)
+			}
+			actual[tag][note.Path] = true
+		}
+	}
+
+	stale := func(path, detail string) {
+		issues = append(issues, Issue{Category: CategoryTagIndex, Path: path, Detail: detail, Fix: "rebuild the tag index"})
+	}
+
+	indexed := make(map[string]map[string]bool)
+	for _, info := range tags.NewTagManager(d.store, d.store.VaultDir()).GetAllTags() {
+		indexed[info.Tag] = make(map[string]bool)
+		for _, path := range info.Notes {
+			indexed[info.Tag][path] = true
+			if _, err := d.store.Stat(path); err != nil {
+				stale(path, fmt.Sprintf("#%s lists a note that no longer exists", info.Tag))
+			} else if !actual[info.Tag][path] {
+				stale(path, fmt.Sprintf("#%s lists a note that no longer has the tag", info.Tag))
+			}
+		}
+	}
+	for tag, paths := range actual {
+		for path := range paths {
+			if !indexed[tag][path] {
+				stale(path, fmt.Sprintf("#%s is missing from the index", tag))
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Path < issues[j].Path })
+	return issues
+}
+
+// checkNotebooks finds .notebook.json files that don't parse
+func (d *Doctor) checkNotebooks() ([]Issue, error) {
+	issues := []Issue{}
+	nm := notebook.NewNotebookManager(d.store)
+
+	err := d.store.Walk(d.store.VaultDir(), func(path string, info backend.FileInfo) error {
+		if info.IsDir || info.Name() != notebook.MetadataFile {
+			return nil
+		}
+		if err := nm.CheckMetadata(filepath.Dir(path)); err != nil {
+			issues = append(issues, Issue{
+				Category: CategoryNotebook,
+				Path:     path,
+				Detail:   fmt.Sprintf("metadata doesn't parse: %v", err),
+				Fix:      "replace with default metadata (the old file is kept as .bak)",
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning notebooks: %w", err)
+	}
+	return issues, nil
+}
+
+// checkLinks finds [[links]] to notes that don't exist
+// Links resolve like renames rewrite them: by vault path or file name, with or
+// without extension and ignoring case, or by note ID
+func (d *Doctor) checkLinks(notes []models.Note) []Issue {
+	targets := make(map[string]bool)
+	for _, note := range notes {
+		rel := strings.ToLower(note.RelID)
+		name := strings.ToLower(note.Name)
+		targets[rel] = true
+		targets[strings.TrimSuffix(rel, filepath.Ext(rel))] = true
+		targets[name] = true
+		targets[strings.TrimSuffix(name, filepath.Ext(name))] = true
+	}
+
+	issues := []Issue{}
+	linker := linking.NewLinkManager()
+	for _, note := range notes {
+		content, err := d.store.ReadFile(note.Path)
+		if err != nil {
+			continue
+		}
+
+		for _, link := range linker.ParseLinks(string(content), note.Path) {
+			target := link.Target
+			if i := strings.Index(target, "#"); i >= 0 {
+				target = target[:i] // [[Note#Heading]]
+			}
+			target = strings.TrimSpace(target)
+			if target == "" {
+				continue // Link to a heading in the same note
+			}
+
+			if id, ok := linking.IDTarget(target); ok {
+				if _, found := d.store.ResolveNote(id, ""); found {
+					continue
+				}
+			} else if targets[strings.ToLower(filepath.ToSlash(target))] {
+				continue
+			}
+
+			issues = append(issues, Issue{
+				Category: CategoryLink,
+				Path:     note.Path,
+				Detail:   fmt.Sprintf("line %d: [[%s]] doesn't match any note", link.Line+1, link.Target),
+			})
+		}
+	}
+	return issues
+}
+
+// checkEmpty finds notes without content
+// Notes open in a totion editor are skipped, a new note is empty until it is saved
+func (d *Doctor) checkEmpty(notes []models.Note) []Issue {
+	issues := []Issue{}
+	for _, note := range notes {
+		if !d.isEmpty(note.Path) {
+			continue
+		}
+		if _, locked := d.store.LockHolder(note.Path); locked {
+			continue
+		}
+		issues = append(issues, Issue{
+			Category: CategoryEmpty,
+			Path:     note.Path,
+			Detail:   "note is empty",
+			Fix:      "move to trash",
+		})
+	}
+	return issues
+}
+
+// isEmpty checks if a note has nothing but whitespace (or front matter)
+func (d *Doctor) isEmpty(path string) bool {
+	content, err := d.store.ReadNote(path)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(models.StripFrontMatter(content)) == ""
+}
@@ -9,6 +9,10 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/0xshariq/totion/internal/features/attachments"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage"
 )
 
 // ExportFormat represents the export format type
@@ -66,6 +70,8 @@ func (e *Exporter) ExportToHTML(content, title, outputPath string) error {
 </body>
 </html>`
 
+	content, title = applyFrontMatter(content, title)
+
 	tmpl, err := template.New("export").Parse(htmlTemplate)
 	if err != nil {
 		return fmt.Errorf("error parsing template: %w", err)
@@ -94,8 +100,8 @@ func (e *Exporter) ExportToHTML(content, title, outputPath string) error {
 
 // ExportToPlainText exports content to plain text
 func (e *Exporter) ExportToPlainText(content, outputPath string) error {
-	// Remove markdown formatting for plain text
-	plainContent := e.stripMarkdown(content)
+	// Remove front matter and markdown formatting for plain text
+	plainContent := e.stripMarkdown(models.StripFrontMatter(content))
 
 	if err := os.WriteFile(outputPath, []byte(plainContent), 0644); err != nil {
 		return fmt.Errorf("error writing file: %w", err)
@@ -120,6 +126,8 @@ func (e *Exporter) ExportToPDF(content, title, outputPath string) error {
 		return fmt.Errorf("wkhtmltopdf not installed. Install it with: sudo apt-get install wkhtmltopdf")
 	}
 
+	content, title = applyFrontMatter(content, title)
+
 	// Generate HTML first with proper styling for PDF
 	htmlContent := fmt.Sprintf(`<!DOCTYPE html>
 <html>
@@ -251,6 +259,18 @@ func (e *Exporter) ExportToPDF(content, title, outputPath string) error {
 	return nil
 }
 
+// applyFrontMatter strips front matter from content and prefers its title
+func applyFrontMatter(content, title string) (string, string) {
+	meta, body, err := models.ParseFrontMatter(content)
+	if err != nil || meta == nil {
+		return content, title
+	}
+	if meta.Title != "" {
+		title = meta.Title
+	}
+	return body, title
+}
+
 // convertMarkdownToHTML converts basic markdown to HTML
 func (e *Exporter) convertMarkdownToHTML(content string) string {
 	html := content
@@ -321,6 +341,25 @@ func (e *Exporter) ExportToJSON(content, title, outputPath string) error {
 		"format":      "markdown",
 	}
 
+	// Include front matter as structured metadata
+	if meta, _, err := models.ParseFrontMatter(content); err == nil && meta != nil {
+		if meta.Title != "" {
+			data["title"] = meta.Title
+		}
+		metadata := map[string]interface{}{
+			"aliases":    meta.Aliases,
+			"tags":       meta.Tags,
+			"properties": meta.Properties,
+		}
+		if !meta.Created.IsZero() {
+			metadata["created"] = meta.Created.Format(time.RFC3339)
+		}
+		if !meta.Updated.IsZero() {
+			metadata["updated"] = meta.Updated.Format(time.RFC3339)
+		}
+		data["metadata"] = metadata
+	}
+
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error marshaling JSON: %w", err)
@@ -333,45 +372,150 @@ func (e *Exporter) ExportToJSON(content, title, outputPath string) error {
 	return nil
 }
 
-// BatchExport exports multiple notes to a directory
-func (e *Exporter) BatchExport(notes []NoteData, outputDir string, format ExportFormat) error {
+// Exported is a note written by BatchExport
+type Exported struct {
+	NotePath string // The note in the vault
+	File     string // The file written, relative to the output directory
+	Renamed  bool   // Another note had the same name, so the file got a numbered one
+}
+
+// BatchExport exports multiple notes to a directory, keeping the notebook
+// folders of the vault so notes with the same name in different notebooks
+// don't overwrite each other
+// Notes whose files would still clash (e.g. plan.md and plan.txt) get numbered names
+func (e *Exporter) BatchExport(notes []NoteData, outputDir string, format ExportFormat) ([]Exported, error) {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("error creating output directory: %w", err)
+		return nil, fmt.Errorf("error creating output directory: %w", err)
 	}
 
+	exported := make([]Exported, 0, len(notes))
+	taken := make(map[string]bool) // Lowercase, for case-insensitive file systems
 	for _, note := range notes {
-		filename := note.Title
-		var err error
+		name := note.Name
+		if name == "" {
+			name = note.Title
+		}
+		file, renamed := name+"."+string(format), false
+		for n := 2; taken[strings.ToLower(file)]; n++ {
+			file, renamed = fmt.Sprintf("%s (%d).%s", name, n, format), true
+		}
+		taken[strings.ToLower(file)] = true
+
+		outputPath := filepath.Join(outputDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return nil, fmt.Errorf("error creating output directory: %w", err)
+		}
+
+		// Attachments are shared in <outputDir>/attachments, linked relative to the note
+		up := strings.Repeat("../", strings.Count(file, "/"))
+		content, err := e.writeAttachments(ToMarkdown(note.Content, note.Format), note.Attachments, outputDir, up)
+		if err != nil {
+			return nil, fmt.Errorf("error exporting attachments of %s: %w", note.Title, err)
+		}
+		note.Content = content
 
 		switch format {
 		case FormatHTML:
-			outputPath := filepath.Join(outputDir, filename+".html")
 			err = e.ExportToHTML(note.Content, note.Title, outputPath)
 		case FormatPDF:
-			outputPath := filepath.Join(outputDir, filename+".pdf")
 			err = e.ExportToPDF(note.Content, note.Title, outputPath)
 		case FormatPlainText:
-			outputPath := filepath.Join(outputDir, filename+".txt")
 			err = e.ExportToPlainText(note.Content, outputPath)
 		case FormatMarkdown:
-			outputPath := filepath.Join(outputDir, filename+".md")
 			err = e.ExportToMarkdown(note.Content, outputPath)
 		case FormatJSON:
-			outputPath := filepath.Join(outputDir, filename+".json")
 			err = e.ExportToJSON(note.Content, note.Title, outputPath)
 		}
 
 		if err != nil {
-			return fmt.Errorf("error exporting %s: %w", note.Title, err)
+			return nil, fmt.Errorf("error exporting %s: %w", note.Title, err)
 		}
+		exported = append(exported, Exported{NotePath: note.Path, File: file, Renamed: renamed})
 	}
 
-	return nil
+	return exported, nil
 }
 
 // NoteData represents note data for batch export
 type NoteData struct {
-	Title   string
-	Content string
-	Path    string
+	Title       string
+	Name        string // Exported file without extension, e.g. "Work/Meeting"; Title if empty
+	Content     string
+	Path        string
+	Format      models.FileFormat // Org and AsciiDoc notes are converted to Markdown first
+	Attachments map[string][]byte // Files the note links to, by link target
+}
+
+// WriteAttachments copies attachments into <outputDir>/attachments and returns
+// content with its links pointing at the copies
+// Files with the same name but different content get numbered names
+func (e *Exporter) WriteAttachments(content string, files map[string][]byte, outputDir string) (string, error) {
+	return e.writeAttachments(content, files, outputDir, "")
+}
+
+// writeAttachments is WriteAttachments for a note in a subfolder of outputDir,
+// up being the way back to outputDir (e.g. "../")
+func (e *Exporter) writeAttachments(content string, files map[string][]byte, outputDir, up string) (string, error) {
+	if len(files) == 0 {
+		return content, nil
+	}
+
+	dir := filepath.Join(outputDir, attachments.DirName)
+	targets := make(map[string]string, len(files))
+	for link, data := range files {
+		name, err := attachments.WriteToDir(dir, filepath.Base(attachments.Resolve("", link)), data)
+		if err != nil {
+			return "", err
+		}
+		targets[link] = up + attachments.DirName + "/" + name
+	}
+
+	return attachments.Rewrite(content, func(link attachments.Link) (string, bool) {
+		target, ok := targets[link.Target]
+		return target, ok
+	}), nil
+}
+
+// LoadAttachments reads the files a note links to, skipping links to missing files
+func LoadAttachments(store *storage.Storage, notePath, content string) map[string][]byte {
+	files := make(map[string][]byte)
+	for _, link := range attachments.Links(content) {
+		if _, ok := files[link.Target]; ok {
+			continue
+		}
+		data, err := store.ReadFile(attachments.Resolve(notePath, link.Target))
+		if err != nil {
+			continue
+		}
+		files[link.Target] = data
+	}
+	return files
+}
+
+// LoadNotes reads notes from the vault for BatchExport, skipping notes that can't be read
+// Titles are the file names without extension, names their vault paths without
+// extension, and the files notes link to are included
+func LoadNotes(store *storage.Storage, notePaths []string) []NoteData {
+	notes := make([]NoteData, 0, len(notePaths))
+	for _, notePath := range notePaths {
+		content, err := store.ReadFile(notePath)
+		if err != nil {
+			continue
+		}
+
+		name := ""
+		if rel, err := filepath.Rel(store.VaultDir(), notePath); err == nil && !strings.HasPrefix(rel, "..") {
+			name = filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+		}
+
+		notes = append(notes, NoteData{
+			Title:       strings.TrimSuffix(filepath.Base(notePath), filepath.Ext(notePath)),
+			Name:        name,
+			Content:     string(content),
+			Path:        notePath,
+			Format:      models.FormatForPath(notePath),
+			Attachments: LoadAttachments(store, notePath, string(content)),
+		})
+	}
+	return notes
 }
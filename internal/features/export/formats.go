@@ -0,0 +1,220 @@
+package export
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/features/attachments"
+	"github.com/0xshariq/totion/internal/models"
+)
+
+// ToMarkdown converts a note to Markdown so every export format can handle it
+// Org-mode and AsciiDoc structure (headings, lists, TODOs, code blocks, links and
+// emphasis) is converted; Markdown and plain text are returned unchanged
+func ToMarkdown(content string, format models.FileFormat) string {
+	switch format {
+	case models.FormatOrg:
+		return orgToMarkdown(content)
+	case models.FormatAsciiDoc:
+		return adocToMarkdown(content)
+	}
+	return content
+}
+
+var (
+	orgHeadline  = regexp.MustCompile(`^(\*+)\s+(?:(TODO|DONE)\s+)?(.*?)(?:\s+(:(?:[\w@#%]+:)+))?\s*$`)
+	orgKeyword   = regexp.MustCompile(`(?i)^#\+(\w+):\s*(.*)$`)
+	orgBlock     = regexp.MustCompile(`(?i)^#\+(BEGIN|END)_(\w+)\s*(\S*)`)
+	orgLink      = regexp.MustCompile(`\[\[([^\]]+)\](?:\[([^\]]+)\])?\]`)
+	orgBold      = regexp.MustCompile(`(^|[\s(])\*([^\s*](?:[^*]*[^\s*])?)\*($|[\s).,;:!?])`)
+	orgItalic    = regexp.MustCompile(`(^|[\s(])/([^\s/](?:[^/]*[^\s/])?)/($|[\s).,;:!?])`)
+	orgCode      = regexp.MustCompile(`(^|[\s(])[~=]([^\s~=](?:[^~=]*[^\s~=])?)[~=]($|[\s).,;:!?])`)
+	orgCheckItem = regexp.MustCompile(`^(\s*)[-+]\s+\[([ Xx-])\]\s+(.*)$`)
+)
+
+// orgToMarkdown converts Org-mode markup to Markdown
+func orgToMarkdown(content string) string {
+	var out []string
+	inCode := false
+	inQuote := false
+
+	for _, line := range strings.Split(content, "\n") {
+		if match := orgBlock.FindStringSubmatch(line); match != nil {
+			kind := strings.ToUpper(match[2])
+			begin := strings.EqualFold(match[1], "BEGIN")
+			switch kind {
+			case "SRC", "EXAMPLE":
+				inCode = begin
+				if begin {
+					out = append(out, "```"+match[3])
+				} else {
+					out = append(out, "```")
+				}
+			case "QUOTE":
+				inQuote = begin
+			}
+			continue
+		}
+		if inCode {
+			out = append(out, line)
+			continue
+		}
+
+		switch match := orgKeyword.FindStringSubmatch(line); {
+		case match != nil && strings.EqualFold(match[1], "TITLE"):
+			out = append(out, "# "+match[2])
+			continue
+		case match != nil:
+			continue // Other #+KEYWORD: settings have no Markdown equivalent
+		case strings.HasPrefix(line, "# "):
+			continue // Comment
+		}
+
+		if match := orgHeadline.FindStringSubmatch(line); match != nil {
+			text := orgInline(match[3])
+			for _, tag := range strings.Split(strings.Trim(match[4], ":"), ":") {
+				if tag != "" {
+					text += " #" + tag
+				}
+			}
+
+			switch match[2] {
+			case "TODO":
+				out = append(out, "- [ ] "+text)
+			case "DONE":
+				out = append(out, "- [x] "+text)
+			default:
+				out = append(out, strings.Repeat("#", len(match[1]))+" "+text)
+			}
+			continue
+		}
+
+		if match := orgCheckItem.FindStringSubmatch(line); match != nil {
+			mark := " "
+			if match[2] != " " {
+				mark = "x"
+			}
+			line = match[1] + "- [" + mark + "] " + match[3]
+		} else if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "+ ") {
+			line = line[:len(line)-len(trimmed)] + "- " + trimmed[2:]
+		}
+
+		line = orgInline(line)
+		if inQuote {
+			line = "> " + line
+		}
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// orgInline converts Org links and emphasis within a line
+func orgInline(line string) string {
+	line = orgLink.ReplaceAllStringFunc(line, func(s string) string {
+		match := orgLink.FindStringSubmatch(s)
+		target, desc := match[1], match[2]
+
+		switch {
+		case strings.HasPrefix(target, "file:"):
+			target = strings.TrimPrefix(target, "file:")
+			if desc == "" && attachments.IsImage(target) {
+				return "![](" + target + ")"
+			}
+		case !strings.Contains(target, ":"):
+			// [[Note]] links another note, like a wiki link
+			if desc == "" {
+				return "[[" + target + "]]"
+			}
+			return "[[" + target + "|" + desc + "]]"
+		}
+
+		if desc == "" {
+			desc = target
+		}
+		return "[" + desc + "](" + target + ")"
+	})
+
+	line = orgBold.ReplaceAllString(line, "$1**$2**$3")
+	line = orgItalic.ReplaceAllString(line, "$1*$2*$3")
+	return orgCode.ReplaceAllString(line, "$1`$2`$3")
+}
+
+var (
+	adocHeading   = regexp.MustCompile(`^(=+)\s+(.*)$`)
+	adocAttribute = regexp.MustCompile(`^:[\w-]+!?:.*$`)
+	adocSource    = regexp.MustCompile(`^\[source(?:,\s*([\w+#-]+))?.*\]$`)
+	adocListItem  = regexp.MustCompile(`^(\*+|\.+)\s+(.*)$`)
+	adocImage     = regexp.MustCompile(`image::?([^\s\[]+)\[([^\]]*)\]`)
+	adocLink      = regexp.MustCompile(`(?:link:)?((?:https?|mailto|ftp)://[^\s\[]+|link:[^\s\[]+)\[([^\]]*)\]`)
+	adocBold      = regexp.MustCompile(`(^|[\s(])\*([^\s*](?:[^*]*[^\s*])?)\*($|[\s).,;:!?])`)
+	adocItalic    = regexp.MustCompile(`(^|[\s(])_([^\s_](?:[^_]*[^\s_])?)_($|[\s).,;:!?])`)
+)
+
+// adocToMarkdown converts AsciiDoc markup to Markdown
+func adocToMarkdown(content string) string {
+	var out []string
+	inCode := false
+	lang := ""
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if match := adocSource.FindStringSubmatch(trimmed); match != nil && !inCode {
+			lang = match[1]
+			continue
+		}
+		if trimmed == "----" || trimmed == "...." {
+			inCode = !inCode
+			if inCode {
+				out = append(out, "```"+lang)
+			} else {
+				out = append(out, "```")
+			}
+			lang = ""
+			continue
+		}
+		if inCode {
+			out = append(out, line)
+			continue
+		}
+
+		if adocAttribute.MatchString(line) || strings.HasPrefix(line, "//") {
+			continue // Document attributes and comments
+		}
+
+		if match := adocHeading.FindStringSubmatch(line); match != nil {
+			out = append(out, strings.Repeat("#", len(match[1]))+" "+adocInline(match[2]))
+			continue
+		}
+
+		if match := adocListItem.FindStringSubmatch(line); match != nil {
+			indent := strings.Repeat("  ", len(match[1])-1)
+			marker := "- "
+			if strings.HasPrefix(match[1], ".") {
+				marker = "1. "
+			}
+			line = indent + marker + match[2]
+		}
+
+		out = append(out, adocInline(line))
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// adocInline converts AsciiDoc images, links and emphasis within a line
+func adocInline(line string) string {
+	line = adocImage.ReplaceAllString(line, "![$2]($1)")
+	line = adocLink.ReplaceAllStringFunc(line, func(s string) string {
+		match := adocLink.FindStringSubmatch(s)
+		target := strings.TrimPrefix(match[1], "link:")
+		text := match[2]
+		if text == "" {
+			text = target
+		}
+		return "[" + text + "](" + target + ")"
+	})
+	line = adocBold.ReplaceAllString(line, "$1**$2**$3")
+	return adocItalic.ReplaceAllString(line, "$1*$2*$3")
+}
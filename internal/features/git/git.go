@@ -27,7 +27,7 @@ func (gm *GitManager) Initialize() error {
 
 	// Create .gitignore
 	gitignorePath := filepath.Join(gm.vaultDir, ".gitignore")
-	gitignoreContent := []byte("*.tmp\n.DS_Store\n")
+	gitignoreContent := []byte("*.tmp\n.DS_Store\n.*.lock\n.search-index.json\n")
 
 	if err := exec.Command("sh", "-c", fmt.Sprintf("echo '%s' > %s", gitignoreContent, gitignorePath)).Run(); err != nil {
 		return fmt.Errorf("error creating .gitignore: %w", err)
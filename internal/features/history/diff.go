@@ -0,0 +1,127 @@
+package history
+
+import "strings"
+
+// DiffOp is the kind of a diff line
+type DiffOp int
+
+const (
+	Same DiffOp = iota
+	Added
+	Removed
+)
+
+// DiffLine is a line of a line-based diff
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// String renders the line with a diff prefix (" ", "+" or "-")
+func (l DiffLine) String() string {
+	switch l.Op {
+	case Added:
+		return "+ " + l.Text
+	case Removed:
+		return "- " + l.Text
+	}
+	return "  " + l.Text
+}
+
+// maxDiffCells caps the size of the comparison table; larger notes are
+// shown as fully replaced rather than stalling the UI
+const maxDiffCells = 4_000_000
+
+// Diff compares two texts line by line, from oldText to newText
+func Diff(oldText, newText string) []DiffLine {
+	a := strings.Split(oldText, "\n")
+	b := strings.Split(newText, "\n")
+
+	// Lines shared at both ends don't need the table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: Same, Text: line})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: Same, Text: line})
+	}
+	return lines
+}
+
+// diffMiddle diffs the changed part using the longest common subsequence
+func diffMiddle(a, b []string) []DiffLine {
+	lines := []DiffLine{}
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			lines = append(lines, DiffLine{Op: Removed, Text: line})
+		}
+		for _, line := range b {
+			lines = append(lines, DiffLine{Op: Added, Text: line})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: Same, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: Removed, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: Added, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: Removed, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: Added, Text: b[j]})
+	}
+	return lines
+}
+
+// Stats counts added and removed lines
+func Stats(lines []DiffLine) (added, removed int) {
+	for _, line := range lines {
+		switch line.Op {
+		case Added:
+			added++
+		case Removed:
+			removed++
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,331 @@
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+// DirName is the history directory inside the vault
+const DirName = ".history"
+
+// Version is a saved snapshot of a note
+type Version struct {
+	ID      string    `json:"id"` // SHA-256 of the content, shared by identical snapshots
+	SavedAt time.Time `json:"saved_at"`
+	Words   int       `json:"words"`
+	Size    int       `json:"size"`
+}
+
+// Policy limits how much history is kept
+type Policy struct {
+	Enabled     bool
+	MaxVersions int           // Per note, 0 keeps all
+	MaxAge      time.Duration // 0 keeps versions forever
+}
+
+// DefaultPolicy keeps the last 50 versions of each note for 90 days
+var DefaultPolicy = Policy{
+	Enabled:     true,
+	MaxVersions: 50,
+	MaxAge:      90 * 24 * time.Hour,
+}
+
+// HistoryManager keeps compressed snapshots of notes in <vault>/.history
+// Snapshots are stored once per content as .history/objects/<id>.gz and listed
+// per note (by vault-relative path) in .history/index.json
+type HistoryManager struct {
+	vaultDir  string
+	backend   backend.Backend
+	indexPath string // Vault-relative
+
+	mu     sync.Mutex
+	policy Policy
+}
+
+// NewHistoryManager creates a history manager for a vault kept in a storage backend
+func NewHistoryManager(vaultDir string, b backend.Backend) *HistoryManager {
+	return &HistoryManager{
+		vaultDir:  vaultDir,
+		backend:   b,
+		indexPath: DirName + "/index.json",
+		policy:    DefaultPolicy,
+	}
+}
+
+// SetPolicy changes the retention policy; it is applied on the next snapshot or Prune
+func (hm *HistoryManager) SetPolicy(policy Policy) {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	hm.policy = policy
+}
+
+// Enabled reports whether snapshots are taken
+func (hm *HistoryManager) Enabled() bool {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	return hm.policy.Enabled
+}
+
+// Snapshot records content as the latest version of a note
+// Nothing is recorded when history is disabled, the note is outside the vault,
+// or the content matches the latest version
+func (hm *HistoryManager) Snapshot(notePath string, content []byte) error {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	if !hm.policy.Enabled {
+		return nil
+	}
+	rel, ok := hm.rel(notePath)
+	if !ok {
+		return nil
+	}
+
+	index, err := hm.load()
+	if err != nil {
+		return err
+	}
+
+	id := contentID(content)
+	versions := index[rel]
+	if len(versions) > 0 && versions[len(versions)-1].ID == id {
+		return nil
+	}
+
+	if _, err := hm.backend.Stat(objectPath(id)); errors.Is(err, fs.ErrNotExist) {
+		if err := hm.writeObject(id, content); err != nil {
+			return err
+		}
+	}
+
+	index[rel] = append(versions, Version{
+		ID:      id,
+		SavedAt: time.Now(),
+		Words:   len(strings.Fields(string(content))),
+		Size:    len(content),
+	})
+
+	return hm.prune(index)
+}
+
+// Versions returns the saved versions of a note, newest first
+func (hm *HistoryManager) Versions(notePath string) ([]Version, error) {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	rel, ok := hm.rel(notePath)
+	if !ok {
+		return []Version{}, nil
+	}
+
+	index, err := hm.load()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := append([]Version{}, index[rel]...)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].SavedAt.After(versions[j].SavedAt)
+	})
+	return versions, nil
+}
+
+// Read returns the content of a version
+func (hm *HistoryManager) Read(version Version) (string, error) {
+	data, err := hm.backend.Read(objectPath(version.ID))
+	if err != nil {
+		return "", fmt.Errorf("error reading version: %w", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("error reading version: %w", err)
+	}
+	defer zr.Close()
+
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("error reading version: %w", err)
+	}
+	return string(content), nil
+}
+
+// MoveNote keeps the history of a renamed or moved note (or notebook) with it
+func (hm *HistoryManager) MoveNote(oldPath, newPath string) error {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	oldRel, ok := hm.rel(oldPath)
+	if !ok {
+		return nil
+	}
+	newRel, ok := hm.rel(newPath)
+	if !ok {
+		return nil
+	}
+
+	index, err := hm.load()
+	if err != nil {
+		return err
+	}
+
+	moved := false
+	for rel, versions := range index {
+		if rel != oldRel && !strings.HasPrefix(rel, oldRel+"/") {
+			continue
+		}
+		target := newRel + strings.TrimPrefix(rel, oldRel)
+		delete(index, rel)
+		index[target] = append(index[target], versions...)
+		moved = true
+	}
+
+	if !moved {
+		return nil
+	}
+	return hm.save(index)
+}
+
+// Prune applies the retention policy to every note and removes unused snapshots
+func (hm *HistoryManager) Prune() error {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	index, err := hm.load()
+	if err != nil {
+		return err
+	}
+	return hm.prune(index)
+}
+
+// prune drops versions outside the policy, saves the index and deletes
+// snapshots no note refers to anymore (caller holds mu)
+func (hm *HistoryManager) prune(index map[string][]Version) error {
+	cutoff := time.Time{}
+	if hm.policy.MaxAge > 0 {
+		cutoff = time.Now().Add(-hm.policy.MaxAge)
+	}
+
+	for rel, versions := range index {
+		kept := versions[:0]
+		for _, version := range versions {
+			if version.SavedAt.After(cutoff) {
+				kept = append(kept, version)
+			}
+		}
+		if limit := hm.policy.MaxVersions; limit > 0 && len(kept) > limit {
+			kept = kept[len(kept)-limit:]
+		}
+
+		if len(kept) == 0 {
+			delete(index, rel)
+		} else {
+			index[rel] = kept
+		}
+	}
+
+	if err := hm.save(index); err != nil {
+		return err
+	}
+
+	used := make(map[string]bool)
+	for _, versions := range index {
+		for _, version := range versions {
+			used[version.ID] = true
+		}
+	}
+
+	objects, err := hm.backend.ReadDir(path.Join(DirName, "objects"))
+	if err != nil {
+		return nil // No snapshots yet
+	}
+	for _, object := range objects {
+		if id := strings.TrimSuffix(object.Name(), ".gz"); !used[id] {
+			_ = hm.backend.Delete(object.Path)
+		}
+	}
+	return nil
+}
+
+// load reads the history index (caller holds mu)
+func (hm *HistoryManager) load() (map[string][]Version, error) {
+	index := make(map[string][]Version)
+
+	data, err := hm.backend.Read(hm.indexPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("error reading history index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("error parsing history index: %w", err)
+	}
+	return index, nil
+}
+
+// save writes the history index (caller holds mu)
+func (hm *HistoryManager) save(index map[string][]Version) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := hm.backend.Write(hm.indexPath, data); err != nil {
+		return fmt.Errorf("error writing history index: %w", err)
+	}
+	return nil
+}
+
+// writeObject stores compressed content under its ID
+func (hm *HistoryManager) writeObject(id string, content []byte) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		return fmt.Errorf("error compressing version: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("error compressing version: %w", err)
+	}
+
+	if err := hm.backend.Write(objectPath(id), buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing version: %w", err)
+	}
+	return nil
+}
+
+// rel returns the vault-relative path of a note, or false if it is outside the vault
+func (hm *HistoryManager) rel(notePath string) (string, bool) {
+	rel, err := filepath.Rel(hm.vaultDir, notePath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// contentID returns the snapshot ID for content
+func contentID(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// objectPath returns the vault-relative path of a snapshot
+func objectPath(id string) string {
+	return path.Join(DirName, "objects", id+".gz")
+}
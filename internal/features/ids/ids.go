@@ -0,0 +1,390 @@
+package ids
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage/backend"
+	"github.com/0xshariq/totion/internal/storage/lock"
+)
+
+// IndexFile is the ID index inside the vault
+const IndexFile = ".ids.json"
+
+// FrontMatterKey is the front matter field that pins a note's ID, e.g. "id: 3f9a0c12b7e4"
+// Notes that carry it keep their ID even when moved by other tools
+const FrontMatterKey = "id"
+
+// entry is where a note with an ID lives
+type entry struct {
+	Path string `json:"path"`           // Vault-relative slash path
+	Hash string `json:"hash,omitempty"` // Content hash, used to find notes moved outside Totion
+}
+
+// IDManager gives every note in a vault a persistent ID
+// IDs are kept in <vault>/.ids.json, keyed by ID, so pins, recents, the tag index
+// and links can reference a note however it's renamed or moved
+type IDManager struct {
+	vaultDir string
+	backend  backend.Backend
+
+	mu     sync.Mutex
+	byID   map[string]entry
+	byPath map[string]string // Vault-relative path -> ID
+	stamp  backend.FileInfo  // Index file the cache was loaded from
+	loaded bool
+}
+
+// NewIDManager creates an ID manager for a vault kept in a storage backend
+func NewIDManager(vaultDir string, b backend.Backend) *IDManager {
+	return &IDManager{
+		vaultDir: vaultDir,
+		backend:  b,
+	}
+}
+
+// NewID returns a new random note ID
+func NewID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
+
+// ID returns the ID of a note, or "" if it doesn't have one yet
+func (im *IDManager) ID(notePath string) string {
+	rel, ok := im.rel(notePath)
+	if !ok {
+		return ""
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	im.refresh()
+	return im.byPath[rel]
+}
+
+// IDs returns the ID of every indexed note by absolute path
+func (im *IDManager) IDs() map[string]string {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	im.refresh()
+
+	ids := make(map[string]string, len(im.byPath))
+	for rel, id := range im.byPath {
+		ids[im.abs(rel)] = id
+	}
+	return ids
+}
+
+// Path returns where the note with an ID currently lives
+// The note may have been deleted since; callers check it exists
+func (im *IDManager) Path(id string) (string, bool) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	im.refresh()
+
+	e, ok := im.byID[id]
+	if !ok {
+		return "", false
+	}
+	return im.abs(e.Path), true
+}
+
+// Assign returns the ID of a note, giving it one if it has none
+// An "id" in the note's front matter is used when present
+func (im *IDManager) Assign(notePath string, content []byte) (string, error) {
+	rel, ok := im.rel(notePath)
+	if !ok {
+		return "", fmt.Errorf("note is outside the vault: %s", notePath)
+	}
+	if id := im.ID(notePath); id != "" {
+		return id, nil
+	}
+
+	var id string
+	err := im.update(func(index map[string]entry) bool {
+		id = idOf(index, rel)
+		if id != "" {
+			return false // Assigned by another process meanwhile
+		}
+		id = frontMatterID(content)
+		if _, taken := index[id]; id == "" || taken {
+			id = NewID()
+		}
+		index[id] = entry{Path: rel, Hash: contentHash(content)}
+		return true
+	})
+	return id, err
+}
+
+// Update records a note's new content, so it can still be recognised if it is
+// moved outside Totion
+func (im *IDManager) Update(notePath string, content []byte) error {
+	rel, ok := im.rel(notePath)
+	if !ok || im.ID(notePath) == "" {
+		return nil
+	}
+
+	hash := contentHash(content)
+	return im.update(func(index map[string]entry) bool {
+		id := idOf(index, rel)
+		if id == "" || index[id].Hash == hash {
+			return false
+		}
+		index[id] = entry{Path: rel, Hash: hash}
+		return true
+	})
+}
+
+// MoveNote keeps the IDs of a moved note, or of every note in a moved folder
+func (im *IDManager) MoveNote(oldPath, newPath string) error {
+	oldRel, ok := im.rel(oldPath)
+	if !ok {
+		return nil
+	}
+	newRel, ok := im.rel(newPath)
+	if !ok {
+		return nil
+	}
+
+	return im.update(func(index map[string]entry) bool {
+		moved := false
+		for id, e := range index {
+			if e.Path != oldRel && !strings.HasPrefix(e.Path, oldRel+"/") {
+				continue
+			}
+			e.Path = newRel + strings.TrimPrefix(e.Path, oldRel)
+			index[id] = e
+			moved = true
+		}
+		return moved
+	})
+}
+
+// Sync brings the index in line with the notes in the vault:
+// notes moved outside Totion get their IDs back (matched by front matter ID or
+// content), notes without an ID get a new one, and the IDs of notes that are
+// gone are dropped. Notes and notebooks in the trash (trashedPaths, where they
+// were deleted from) keep their IDs until they are restored or purged
+// Returns the number of notes whose ID was found again at a new path
+func (im *IDManager) Sync(notePaths, trashedPaths []string) (int, error) {
+	relinked := 0
+	err := im.update(func(index map[string]entry) bool {
+		present := make(map[string]bool, len(notePaths))
+		for _, notePath := range notePaths {
+			if rel, ok := im.rel(notePath); ok {
+				present[rel] = true
+			}
+		}
+		trashed := []string{}
+		for _, trashedPath := range trashedPaths {
+			if rel, ok := im.rel(trashedPath); ok {
+				trashed = append(trashed, rel)
+			}
+		}
+
+		tracked := make(map[string]bool, len(index))
+		gone := make(map[string]bool)      // IDs of notes no longer where the index says
+		missing := make(map[string]string) // Content hash -> ID of those notes
+		for id, e := range index {
+			switch {
+			case present[e.Path]:
+				tracked[e.Path] = true
+			case inTrash(e.Path, trashed):
+			default:
+				gone[id] = true
+				if e.Hash != "" {
+					missing[e.Hash] = id
+				}
+			}
+		}
+
+		changed := false
+		for _, notePath := range notePaths {
+			rel, ok := im.rel(notePath)
+			if !ok || tracked[rel] {
+				continue
+			}
+			content, err := im.backend.Read(rel)
+			if err != nil {
+				continue
+			}
+			hash := contentHash(content)
+
+			id := frontMatterID(content)
+			if e, taken := index[id]; id != "" && taken && present[e.Path] {
+				id = "" // Copied note, the original keeps the ID
+			}
+			if id == "" {
+				// Only notes that went missing since the last sync, so a new
+				// note with the content of one deleted long ago gets a new ID
+				if moved, ok := missing[hash]; ok && gone[moved] {
+					id = moved
+					delete(missing, hash)
+				}
+			}
+			if id != "" {
+				if _, known := index[id]; known {
+					relinked++
+				}
+			} else {
+				id = NewID()
+			}
+
+			index[id] = entry{Path: rel, Hash: hash}
+			delete(gone, id)
+			tracked[rel] = true
+			changed = true
+		}
+
+		for id := range gone {
+			delete(index, id)
+			changed = true
+		}
+		return changed
+	})
+	return relinked, err
+}
+
+// inTrash checks if a vault-relative path is a trashed note or inside a trashed notebook
+func inTrash(rel string, trashed []string) bool {
+	for _, t := range trashed {
+		if rel == t || strings.HasPrefix(rel, t+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// update applies a change to the index on disk while holding its lock,
+// so IDs assigned by another totion on the same vault aren't lost
+func (im *IDManager) update(fn func(index map[string]entry) bool) error {
+	apply := func() error {
+		im.mu.Lock()
+		defer im.mu.Unlock()
+
+		index, err := im.load()
+		if err != nil {
+			return err
+		}
+		if !fn(index) {
+			return nil
+		}
+		return im.save(index)
+	}
+
+	if fsb, ok := im.backend.(*backend.FS); ok {
+		return lock.Do(fsb.Abs(IndexFile), lock.DefaultTimeout, apply)
+	}
+	return apply()
+}
+
+// refresh reloads the cached index if the file changed (caller holds mu)
+// A broken index is kept out of the cache rather than failing lookups
+func (im *IDManager) refresh() {
+	info, err := im.backend.Stat(IndexFile)
+	if err == nil && im.loaded && info.ModTime.Equal(im.stamp.ModTime) && info.Size == im.stamp.Size {
+		return
+	}
+	if index, err := im.load(); err == nil {
+		im.cache(index)
+	}
+}
+
+// load reads the index from the backend and caches it (caller holds mu)
+func (im *IDManager) load() (map[string]entry, error) {
+	index := make(map[string]entry)
+
+	data, err := im.backend.Read(IndexFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error reading ID index: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("error parsing ID index: %w", err)
+		}
+	}
+
+	im.cache(index)
+	return index, nil
+}
+
+// save writes the index (caller holds mu)
+func (im *IDManager) save(index map[string]entry) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := im.backend.Write(IndexFile, data); err != nil {
+		return fmt.Errorf("error writing ID index: %w", err)
+	}
+	im.cache(index)
+	return nil
+}
+
+// cache keeps a copy of the index for lookups (caller holds mu)
+func (im *IDManager) cache(index map[string]entry) {
+	im.byID = make(map[string]entry, len(index))
+	im.byPath = make(map[string]string, len(index))
+	for id, e := range index {
+		im.byID[id] = e
+		im.byPath[e.Path] = id
+	}
+	im.stamp, _ = im.backend.Stat(IndexFile)
+	im.loaded = true
+}
+
+// rel returns the vault-relative path of a note, or false if it is outside the vault
+func (im *IDManager) rel(notePath string) (string, bool) {
+	rel, err := filepath.Rel(im.vaultDir, notePath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// abs returns the absolute path of a vault-relative path
+func (im *IDManager) abs(rel string) string {
+	return filepath.Join(im.vaultDir, filepath.FromSlash(rel))
+}
+
+// idOf returns the ID indexed for a path
+func idOf(index map[string]entry, rel string) string {
+	for id, e := range index {
+		if e.Path == rel {
+			return id
+		}
+	}
+	return ""
+}
+
+// frontMatterID returns the ID set in a note's front matter, if any
+func frontMatterID(content []byte) string {
+	meta, _, err := models.ParseFrontMatter(string(content))
+	if err != nil || meta == nil {
+		return ""
+	}
+	value, ok := meta.Get(FrontMatterKey)
+	if !ok || value == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// contentHash fingerprints note content to recognise it after a move
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
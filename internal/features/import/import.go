@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
+
+	"github.com/0xshariq/totion/internal/features/attachments"
+	"github.com/0xshariq/totion/internal/models"
 )
 
 // Importer handles importing notes from various sources
@@ -66,8 +70,10 @@ func (i *Importer) ImportFromNotion(jsonPath string) ([]string, error) {
 }
 
 // ImportFromObsidian imports notes from Obsidian vault
+// Files the notes link to are copied into imported/attachments
 func (i *Importer) ImportFromObsidian(obsidianVaultPath string) ([]string, error) {
 	imported := []string{}
+	files := indexFiles(obsidianVaultPath)
 
 	err := filepath.Walk(obsidianVaultPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -84,6 +90,7 @@ func (i *Importer) ImportFromObsidian(obsidianVaultPath string) ([]string, error
 		}
 
 		destPath := filepath.Join(i.vaultDir, "imported", info.Name())
+		content = []byte(i.importAttachments(string(content), path, destPath, files))
 
 		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
 			return nil
@@ -125,6 +132,77 @@ func (i *Importer) ImportFromPlainText(txtPath string) error {
 	return nil
 }
 
+// embedPattern matches Obsidian embeds such as ![[diagram.png]] or ![[scan.pdf|400]]
+var embedPattern = regexp.MustCompile(`!\[\[([^\]|#]+)(?:[|#][^\]]*)?\]\]`)
+
+// indexFiles maps the names of files other than notes under a folder to their paths,
+// for resolving Obsidian embeds, which refer to files by name only
+func indexFiles(root string) map[string]string {
+	files := make(map[string]string)
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir // .obsidian, .trash
+			}
+			return nil
+		}
+		if _, ok := files[info.Name()]; !ok && !strings.HasSuffix(info.Name(), ".md") {
+			files[info.Name()] = path
+		}
+		return nil
+	})
+	return files
+}
+
+// importAttachments copies the files a note links to into imported/attachments and
+// returns the content with its links pointing at the copies
+// Embeds of files other than notes become markdown links; links to missing files are kept as they are
+func (i *Importer) importAttachments(content, srcPath, destPath string, files map[string]string) string {
+	dir := filepath.Join(i.vaultDir, "imported", attachments.DirName)
+
+	copyFile := func(path string) (string, bool) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false
+		}
+		name, err := attachments.WriteToDir(dir, filepath.Base(path), data)
+		if err != nil {
+			return "", false
+		}
+		target, err := filepath.Rel(filepath.Dir(destPath), filepath.Join(dir, name))
+		if err != nil {
+			return "", false
+		}
+		return filepath.ToSlash(target), true
+	}
+
+	content = attachments.Rewrite(content, func(link attachments.Link) (string, bool) {
+		if target, ok := copyFile(attachments.Resolve(srcPath, link.Target)); ok {
+			return target, true
+		}
+		if path, ok := files[filepath.Base(attachments.Resolve("", link.Target))]; ok {
+			return copyFile(path)
+		}
+		return "", false
+	})
+
+	return embedPattern.ReplaceAllStringFunc(content, func(embed string) string {
+		name := strings.TrimSpace(embedPattern.FindStringSubmatch(embed)[1])
+		path, ok := files[filepath.Base(name)]
+		if !ok {
+			return embed // A note embed, or a missing file
+		}
+		target, ok := copyFile(path)
+		if !ok {
+			return embed
+		}
+		return attachments.MarkdownLink(filepath.Base(name), target)
+	})
+}
+
 // sanitizeFilename removes invalid characters from filename
 func (i *Importer) sanitizeFilename(name string) string {
 	// Replace invalid characters
@@ -248,7 +326,8 @@ func (i *Importer) ImportFromCSV(csvPath string) ([]string, error) {
 	return imported, nil
 }
 
-// BatchImportFromDirectory imports all markdown/text files from a directory
+// BatchImportFromDirectory imports all note files (Markdown, text, Org, AsciiDoc...) from a directory
+// Files the notes link to are copied into imported/attachments
 func (i *Importer) BatchImportFromDirectory(sourcePath string) ([]string, error) {
 	imported := []string{}
 
@@ -262,7 +341,7 @@ func (i *Importer) BatchImportFromDirectory(sourcePath string) ([]string, error)
 		}
 
 		ext := filepath.Ext(info.Name())
-		if ext != ".md" && ext != ".txt" {
+		if !models.IsNoteExt(ext) {
 			return nil
 		}
 
@@ -274,6 +353,7 @@ func (i *Importer) BatchImportFromDirectory(sourcePath string) ([]string, error)
 		// Preserve relative directory structure
 		relPath, _ := filepath.Rel(sourcePath, path)
 		destPath := filepath.Join(i.vaultDir, "imported", relPath)
+		content = []byte(i.importAttachments(string(content), path, destPath, nil))
 
 		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
 			return nil
@@ -320,7 +400,7 @@ func (i *Importer) BatchImportWithStats(sourcePath string) (*ImportStats, error)
 		}
 
 		ext := filepath.Ext(info.Name())
-		if ext != ".md" && ext != ".txt" {
+		if !models.IsNoteExt(ext) {
 			stats.SkippedFiles++
 			return nil
 		}
@@ -335,6 +415,7 @@ func (i *Importer) BatchImportWithStats(sourcePath string) (*ImportStats, error)
 
 		relPath, _ := filepath.Rel(sourcePath, path)
 		destPath := filepath.Join(i.vaultDir, "imported", relPath)
+		content = []byte(i.importAttachments(string(content), path, destPath, nil))
 
 		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
 			stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %v", info.Name(), err))
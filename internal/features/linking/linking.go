@@ -12,6 +12,23 @@ type Link struct {
 	Line   int
 }
 
+// wikiLinkPattern matches [[target]], [[target|display]] and [[target#heading|display]]
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\]|#]+)(#[^\]|]*)?(\|[^\]]+)?\]\]`)
+
+// IDLinkPrefix marks a link to a note by its persistent ID, e.g. [[id:3f9a0c12b7e4]]
+// ID links keep pointing at the note however it is renamed or moved
+const IDLinkPrefix = "id:"
+
+// IDTarget returns the note ID of a link target like "id:3f9a0c12b7e4"
+func IDTarget(target string) (string, bool) {
+	target = strings.TrimSpace(target)
+	if !strings.HasPrefix(strings.ToLower(target), IDLinkPrefix) {
+		return "", false
+	}
+	id := strings.TrimSpace(target[len(IDLinkPrefix):])
+	return id, id != ""
+}
+
 // LinkManager handles note linking and backlinks
 type LinkManager struct {
 	links map[string][]Link // Map of note -> outgoing links
@@ -53,6 +70,36 @@ func (lm *LinkManager) AddLinks(noteName string, links []Link) {
 	lm.links[noteName] = links
 }
 
+// IndexNote re-parses a note's outgoing links from its content
+func (lm *LinkManager) IndexNote(noteName, content string) {
+	links := lm.ParseLinks(content, noteName)
+	if len(links) == 0 {
+		delete(lm.links, noteName)
+		return
+	}
+	lm.links[noteName] = links
+}
+
+// RemoveNote drops a note's outgoing links
+func (lm *LinkManager) RemoveNote(noteName string) {
+	delete(lm.links, noteName)
+}
+
+// RemoveNotesWithPrefix drops outgoing links of every note whose name starts with prefix
+// (e.g. all notes of a deleted notebook)
+func (lm *LinkManager) RemoveNotesWithPrefix(prefix string) {
+	for name := range lm.links {
+		if strings.HasPrefix(name, prefix) {
+			delete(lm.links, name)
+		}
+	}
+}
+
+// Count returns the number of notes with outgoing links
+func (lm *LinkManager) Count() int {
+	return len(lm.links)
+}
+
 // GetOutgoingLinks returns outgoing links from a note
 func (lm *LinkManager) GetOutgoingLinks(noteName string) []Link {
 	return lm.links[noteName]
@@ -83,6 +130,32 @@ func (lm *LinkManager) CreateWikiLinkWithDisplay(target, displayText string) str
 	return "[[" + target + "|" + displayText + "]]"
 }
 
+// CreateIDLink creates a wiki-style link to a note by its persistent ID
+func (lm *LinkManager) CreateIDLink(id, displayText string) string {
+	if displayText == "" {
+		return "[[" + IDLinkPrefix + id + "]]"
+	}
+	return "[[" + IDLinkPrefix + id + "|" + displayText + "]]"
+}
+
+// RewriteLinks replaces link targets in content using rewrite
+// rewrite receives the trimmed target and returns the new target and whether to change it.
+// Headings (#section) and display text (|text) are preserved.
+// Returns the new content and the number of links changed.
+func (lm *LinkManager) RewriteLinks(content string, rewrite func(target string) (string, bool)) (string, int) {
+	changed := 0
+	result := wikiLinkPattern.ReplaceAllStringFunc(content, func(link string) string {
+		match := wikiLinkPattern.FindStringSubmatch(link)
+		newTarget, ok := rewrite(strings.TrimSpace(match[1]))
+		if !ok {
+			return link
+		}
+		changed++
+		return "[[" + newTarget + match[2] + match[3] + "]]"
+	})
+	return result, changed
+}
+
 // IsWikiLink checks if text contains a wiki-style link
 func (lm *LinkManager) IsWikiLink(text string) bool {
 	linkRegex := regexp.MustCompile(`\[\[[^\]]+\]\]`)
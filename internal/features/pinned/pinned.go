@@ -5,26 +5,46 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage/lock"
 )
 
 // PinnedNote represents a pinned note
 type PinnedNote struct {
 	Path     string    `json:"path"`
 	Name     string    `json:"name"`
+	Notebook string    `json:"notebook,omitempty"`
+	RelID    string    `json:"rel_id,omitempty"`
+	ID       string    `json:"id,omitempty"` // Persistent note ID, Path is its last known location
 	PinnedAt time.Time `json:"pinned_at"`
 }
 
+// DisplayName returns the note name prefixed with its notebook path
+func (p PinnedNote) DisplayName() string {
+	if p.Notebook == "" {
+		return p.Name
+	}
+	return p.Notebook + "/" + p.Name
+}
+
 // PinnedManager manages pinned notes
 type PinnedManager struct {
 	configPath string
 	pinned     []PinnedNote
+	maxPinned  int
 }
 
 // NewPinnedManager creates a new pinned notes manager
-func NewPinnedManager(configDir string) *PinnedManager {
+func NewPinnedManager(configDir string, maxPinned int) *PinnedManager {
+	if maxPinned <= 0 {
+		maxPinned = 10
+	}
+
 	pm := &PinnedManager{
 		configPath: filepath.Join(configDir, ".pinned_notes.json"),
 		pinned:     []PinnedNote{},
+		maxPinned:  maxPinned,
 	}
 	pm.load()
 	return pm
@@ -53,39 +73,59 @@ func (pm *PinnedManager) save() error {
 	return os.WriteFile(pm.configPath, data, 0644)
 }
 
+// update applies a change to the pins saved on disk while holding their lock,
+// so pins made by another totion on the same vault aren't lost
+func (pm *PinnedManager) update(fn func() bool) error {
+	return lock.Do(pm.configPath, lock.DefaultTimeout, func() error {
+		if err := pm.load(); err != nil {
+			return err
+		}
+		if !fn() {
+			return nil
+		}
+		return pm.save()
+	})
+}
+
 // Pin pins a note
-func (pm *PinnedManager) Pin(path, name string) error {
-	// Check if already pinned
-	for _, p := range pm.pinned {
-		if p.Path == path {
-			return nil // Already pinned
+func (pm *PinnedManager) Pin(note *models.Note) error {
+	return pm.update(func() bool {
+		// Check if already pinned
+		for _, p := range pm.pinned {
+			if p.Path == note.Path {
+				return false // Already pinned
+			}
 		}
-	}
 
-	// Limit to 10 pinned notes
-	if len(pm.pinned) >= 10 {
-		// Remove oldest
-		pm.pinned = pm.pinned[1:]
-	}
+		// Limit the number of pinned notes
+		if len(pm.pinned) >= pm.maxPinned {
+			// Remove oldest
+			pm.pinned = pm.pinned[len(pm.pinned)-pm.maxPinned+1:]
+		}
 
-	pm.pinned = append(pm.pinned, PinnedNote{
-		Path:     path,
-		Name:     name,
-		PinnedAt: time.Now(),
+		pm.pinned = append(pm.pinned, PinnedNote{
+			Path:     note.Path,
+			Name:     note.Name,
+			Notebook: note.Notebook,
+			RelID:    note.RelID,
+			ID:       note.ID,
+			PinnedAt: time.Now(),
+		})
+		return true
 	})
-
-	return pm.save()
 }
 
 // Unpin unpins a note
 func (pm *PinnedManager) Unpin(path string) error {
-	for i, p := range pm.pinned {
-		if p.Path == path {
-			pm.pinned = append(pm.pinned[:i], pm.pinned[i+1:]...)
-			return pm.save()
+	return pm.update(func() bool {
+		for i, p := range pm.pinned {
+			if p.Path == path {
+				pm.pinned = append(pm.pinned[:i], pm.pinned[i+1:]...)
+				return true
+			}
 		}
-	}
-	return nil
+		return false
+	})
 }
 
 // IsPinned checks if a note is pinned
@@ -104,19 +144,87 @@ func (pm *PinnedManager) GetPinned() []PinnedNote {
 }
 
 // Toggle toggles pin status of a note
-func (pm *PinnedManager) Toggle(path, name string) (bool, error) {
-	if pm.IsPinned(path) {
-		err := pm.Unpin(path)
+func (pm *PinnedManager) Toggle(note *models.Note) (bool, error) {
+	if pm.IsPinned(note.Path) {
+		err := pm.Unpin(note.Path)
 		return false, err
 	}
-	err := pm.Pin(path, name)
+	err := pm.Pin(note)
 	return true, err
 }
 
+// UpdatePath points a pinned note at its new location after a rename or move
+// Returns false if the note was not pinned
+func (pm *PinnedManager) UpdatePath(oldPath string, note *models.Note) (bool, error) {
+	updated := false
+	err := pm.update(func() bool {
+		for i, p := range pm.pinned {
+			if p.Path == oldPath {
+				pm.pinned[i].Path = note.Path
+				pm.pinned[i].Name = note.Name
+				pm.pinned[i].Notebook = note.Notebook
+				pm.pinned[i].RelID = note.RelID
+				if note.ID != "" {
+					pm.pinned[i].ID = note.ID
+				}
+				updated = true
+				return true
+			}
+		}
+		return false
+	})
+	return updated, err
+}
+
+// Resolve points pins at the current location of their notes, by note ID
+// resolve looks a note up by ID, falling back to its last known path
+// Returns the number of pins that were updated
+func (pm *PinnedManager) Resolve(resolve func(id, path string) (models.Note, bool)) (int, error) {
+	updated := 0
+	err := pm.update(func() bool {
+		updated = 0
+		for i, p := range pm.pinned {
+			note, ok := resolve(p.ID, p.Path)
+			if !ok || (note.Path == p.Path && note.ID == p.ID) {
+				continue
+			}
+			pm.pinned[i].Path = note.Path
+			pm.pinned[i].Name = note.Name
+			pm.pinned[i].Notebook = note.Notebook
+			pm.pinned[i].RelID = note.RelID
+			pm.pinned[i].ID = note.ID
+			updated++
+		}
+		return updated > 0
+	})
+	return updated, err
+}
+
+// Prune removes pins whose note no longer exists
+// resolve looks a note up by ID, falling back to its last known path
+// Returns the number of pins that were removed
+func (pm *PinnedManager) Prune(resolve func(id, path string) (models.Note, bool)) (int, error) {
+	removed := 0
+	err := pm.update(func() bool {
+		kept := pm.pinned[:0]
+		for _, p := range pm.pinned {
+			if _, ok := resolve(p.ID, p.Path); ok {
+				kept = append(kept, p)
+			}
+		}
+		removed = len(pm.pinned) - len(kept)
+		pm.pinned = kept
+		return removed > 0
+	})
+	return removed, err
+}
+
 // Clear removes all pinned notes
 func (pm *PinnedManager) Clear() error {
-	pm.pinned = []PinnedNote{}
-	return pm.save()
+	return pm.update(func() bool {
+		pm.pinned = []PinnedNote{}
+		return true
+	})
 }
 
 // Count returns the number of pinned notes
@@ -26,11 +26,22 @@ type PomodoroTimer struct {
 }
 
 // NewPomodoroTimer creates a new pomodoro timer
-func NewPomodoroTimer() *PomodoroTimer {
+// Non-positive durations fall back to the classic 25/5/15 minutes
+func NewPomodoroTimer(work, shortBreak, longBreak time.Duration) *PomodoroTimer {
+	if work <= 0 {
+		work = 25 * time.Minute
+	}
+	if shortBreak <= 0 {
+		shortBreak = 5 * time.Minute
+	}
+	if longBreak <= 0 {
+		longBreak = 15 * time.Minute
+	}
+
 	return &PomodoroTimer{
-		workDuration:       25 * time.Minute,
-		shortBreakDuration: 5 * time.Minute,
-		longBreakDuration:  15 * time.Minute,
+		workDuration:       work,
+		shortBreakDuration: shortBreak,
+		longBreakDuration:  longBreak,
 		currentState:       StateIdle,
 		pomodorosCompleted: 0,
 	}
@@ -13,6 +13,9 @@ type RecentNote struct {
 Path     string `json:"path"`
 Name     string `json:"name"`
 Format   string `json:"format"`
+Notebook string `json:"notebook,omitempty"`
+RelID    string `json:"rel_id,omitempty"`
+ID       string `json:"id,omitempty"` // Persistent note ID, Path is its last known location
 OpenedAt time.Time `json:"opened_at"`
 }
 
@@ -21,17 +24,20 @@ configPath string
 maxRecent  int
 }
 
-func NewRecentManager(configDir string) *RecentManager {
+func NewRecentManager(configDir string, maxRecent int) *RecentManager {
+if maxRecent <= 0 {
+maxRecent = 10
+}
 return &RecentManager{
 configPath: filepath.Join(configDir, ".recent_notes.json"),
-maxRecent:  10,
+maxRecent:  maxRecent,
 }
 }
 
 func (r *RecentManager) AddRecent(note *models.Note) error {
 recent := r.GetRecent()
 for i, n := range recent {
-if n.Path == note.Path {
+if n.Path == note.Path || (note.ID != "" && n.ID == note.ID) {
 recent = append(recent[:i], recent[i+1:]...)
 break
 }
@@ -40,6 +46,9 @@ newRecent := RecentNote{
 Path:     note.Path,
 Name:     note.Name,
 Format:   string(note.Format),
+Notebook: note.Notebook,
+RelID:    note.RelID,
+ID:       note.ID,
 OpenedAt: time.Now(),
 }
 recent = append([]RecentNote{newRecent}, recent...)
@@ -65,6 +74,91 @@ return []RecentNote{}
 return recent
 }
 
+// DisplayName returns the note name prefixed with its notebook path
+func (n RecentNote) DisplayName() string {
+if n.Notebook == "" {
+return n.Name
+}
+return n.Notebook + "/" + n.Name
+}
+
+// UpdatePath points a recent entry at its new location after a rename or move
+// Returns false if the note was not in the recent list
+func (r *RecentManager) UpdatePath(oldPath string, note *models.Note) (bool, error) {
+recent := r.GetRecent()
+found := false
+for i, n := range recent {
+if n.Path == oldPath {
+recent[i].Path = note.Path
+recent[i].Name = note.Name
+recent[i].Notebook = note.Notebook
+recent[i].RelID = note.RelID
+if note.ID != "" {
+recent[i].ID = note.ID
+}
+found = true
+}
+}
+if !found {
+return false, nil
+}
+data, err := json.MarshalIndent(recent, "", "  ")
+if err != nil {
+return true, err
+}
+return true, os.WriteFile(r.configPath, data, 0644)
+}
+
+// Resolve points recent entries at the current location of their notes, by note ID
+// resolve looks a note up by ID, falling back to its last known path
+// Returns the number of entries that were updated
+func (r *RecentManager) Resolve(resolve func(id, path string) (models.Note, bool)) (int, error) {
+recent := r.GetRecent()
+updated := 0
+for i, n := range recent {
+note, ok := resolve(n.ID, n.Path)
+if !ok || (note.Path == n.Path && note.ID == n.ID) {
+continue
+}
+recent[i].Path = note.Path
+recent[i].Name = note.Name
+recent[i].Notebook = note.Notebook
+recent[i].RelID = note.RelID
+recent[i].ID = note.ID
+updated++
+}
+if updated == 0 {
+return 0, nil
+}
+data, err := json.MarshalIndent(recent, "", "  ")
+if err != nil {
+return updated, err
+}
+return updated, os.WriteFile(r.configPath, data, 0644)
+}
+
+// Prune removes recent entries whose note no longer exists
+// resolve looks a note up by ID, falling back to its last known path
+// Returns the number of entries that were removed
+func (r *RecentManager) Prune(resolve func(id, path string) (models.Note, bool)) (int, error) {
+recent := r.GetRecent()
+kept := make([]RecentNote, 0, len(recent))
+for _, n := range recent {
+if _, ok := resolve(n.ID, n.Path); ok {
+kept = append(kept, n)
+}
+}
+removed := len(recent) - len(kept)
+if removed == 0 {
+return 0, nil
+}
+data, err := json.MarshalIndent(kept, "", "  ")
+if err != nil {
+return 0, err
+}
+return removed, os.WriteFile(r.configPath, data, 0644)
+}
+
 func (r *RecentManager) Clear() error {
 return os.Remove(r.configPath)
 }
@@ -0,0 +1,340 @@
+package rename
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/features/linking"
+	"github.com/0xshariq/totion/internal/features/pinned"
+	"github.com/0xshariq/totion/internal/features/recent"
+	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/notebook"
+	"github.com/0xshariq/totion/internal/storage"
+)
+
+// Change is a note whose path changed
+type Change struct {
+	OldPath string
+	NewPath string
+}
+
+// Report describes everything a rename or move changed
+type Report struct {
+	Moved          []Change
+	LinksRewritten int
+	UpdatedNotes   []string // Notes whose wiki links were rewritten
+	PinsUpdated    int
+	RecentsUpdated int
+	TagsUpdated    int
+
+	targets map[string]string // Lowercased old link target -> new link target
+}
+
+// Renamer renames and moves notes and notebooks, keeping links, pins,
+// recents and the tag index pointing at the new locations
+type Renamer struct {
+	storage *storage.Storage
+	tags    *tags.TagManager
+	pinned  *pinned.PinnedManager
+	recent  *recent.RecentManager
+	linker  *linking.LinkManager
+}
+
+// NewRenamer creates a new renamer; any of the managers may be nil
+func NewRenamer(store *storage.Storage, tagManager *tags.TagManager, pinnedManager *pinned.PinnedManager, recentManager *recent.RecentManager) *Renamer {
+	return &Renamer{
+		storage: store,
+		tags:    tagManager,
+		pinned:  pinnedManager,
+		recent:  recentManager,
+		linker:  linking.NewLinkManager(),
+	}
+}
+
+// RenameNote renames a note within its notebook
+// The extension is kept when newName doesn't have one
+func (r *Renamer) RenameNote(oldPath, newName string) (*Report, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" || strings.ContainsAny(newName, `/\`) {
+		return nil, fmt.Errorf("invalid note name %q", newName)
+	}
+	if !storage.IsNoteFile(newName) {
+		newName += filepath.Ext(oldPath)
+	}
+
+	return r.MoveNote(oldPath, filepath.Join(filepath.Dir(oldPath), newName))
+}
+
+// MoveNote moves a note to a new path inside the vault, creating notebooks as needed
+func (r *Renamer) MoveNote(oldPath, newPath string) (*Report, error) {
+	info, err := r.storage.Stat(oldPath)
+	if err != nil || info.IsDir {
+		return nil, fmt.Errorf("note not found: %s", oldPath)
+	}
+	if !storage.IsNoteFile(newPath) {
+		return nil, fmt.Errorf("unsupported note extension: %s", filepath.Base(newPath))
+	}
+	if err := r.checkTarget(oldPath, newPath); err != nil {
+		return nil, err
+	}
+
+	before, err := r.storage.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Missing notebooks are created by the move
+	if err := r.storage.Move(oldPath, newPath); err != nil {
+		return nil, fmt.Errorf("error moving note: %w", err)
+	}
+	r.moveSwap(oldPath, newPath)
+
+	return r.afterMove([]Change{{OldPath: oldPath, NewPath: newPath}}, before)
+}
+
+// RenameNotebook renames a notebook and updates everything pointing at its notes
+func (r *Renamer) RenameNotebook(oldPath, newName string) (*Report, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" || strings.ContainsAny(newName, `/\`) || storage.IsHidden(newName) {
+		return nil, fmt.Errorf("invalid notebook name %q", newName)
+	}
+
+	newPath := filepath.Join(filepath.Dir(oldPath), newName)
+	if err := r.checkTarget(oldPath, newPath); err != nil {
+		return nil, err
+	}
+
+	before, err := r.storage.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Notes inside the notebook, at any depth
+	changes := []Change{}
+	prefix := oldPath + string(filepath.Separator)
+	for _, note := range before {
+		if strings.HasPrefix(note.Path, prefix) {
+			changes = append(changes, Change{
+				OldPath: note.Path,
+				NewPath: filepath.Join(newPath, strings.TrimPrefix(note.Path, prefix)),
+			})
+		}
+	}
+
+	nbManager := notebook.NewNotebookManager(r.storage)
+	if err := nbManager.RenameNotebook(oldPath, newName); err != nil {
+		// The folder may be renamed even if its metadata could not be updated
+		if _, statErr := r.storage.Stat(newPath); statErr != nil {
+			return nil, err
+		}
+	}
+
+	return r.afterMove(changes, before)
+}
+
+// RewriteLinks applies the report's link changes to content (e.g. an unsaved editor buffer)
+func (rep *Report) RewriteLinks(content string) (string, int) {
+	return linking.NewLinkManager().RewriteLinks(content, rep.rewriteTarget)
+}
+
+// Summary returns a one-line description of the changes
+func (rep *Report) Summary() string {
+	parts := []string{fmt.Sprintf("moved %d %s", len(rep.Moved), plural(len(rep.Moved), "note", "notes"))}
+	if rep.LinksRewritten > 0 {
+		parts = append(parts, fmt.Sprintf("updated %d %s in %d %s",
+			rep.LinksRewritten, plural(rep.LinksRewritten, "link", "links"),
+			len(rep.UpdatedNotes), plural(len(rep.UpdatedNotes), "note", "notes")))
+	}
+	if rep.PinsUpdated > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s", rep.PinsUpdated, plural(rep.PinsUpdated, "pin", "pins")))
+	}
+	if rep.RecentsUpdated > 0 {
+		parts = append(parts, fmt.Sprintf("%d recent %s", rep.RecentsUpdated, plural(rep.RecentsUpdated, "entry", "entries")))
+	}
+	if rep.TagsUpdated > 0 {
+		parts = append(parts, fmt.Sprintf("%d tag %s", rep.TagsUpdated, plural(rep.TagsUpdated, "entry", "entries")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// NewPath returns where a note moved to, or "" if it didn't move
+func (rep *Report) NewPath(oldPath string) string {
+	for _, change := range rep.Moved {
+		if change.OldPath == oldPath {
+			return change.NewPath
+		}
+	}
+	return ""
+}
+
+// checkTarget makes sure a rename stays inside the vault and doesn't overwrite anything
+func (r *Renamer) checkTarget(oldPath, newPath string) error {
+	vaultDir := r.storage.VaultDir()
+	rel, err := filepath.Rel(vaultDir, newPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is outside the vault", newPath)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if storage.IsIgnoredDir(part) {
+			return fmt.Errorf("cannot move into hidden folder %s", part)
+		}
+	}
+
+	if _, err := r.storage.Stat(newPath); err == nil && !r.storage.SameFile(oldPath, newPath) {
+		return fmt.Errorf("%s already exists", rel)
+	}
+	return nil
+}
+
+// afterMove rewrites links and updates pins, recents and tags for moved notes
+func (r *Renamer) afterMove(changes []Change, before []models.Note) (*Report, error) {
+	report := &Report{Moved: changes}
+
+	after, err := r.storage.ListNotes()
+	if err != nil {
+		return report, err
+	}
+
+	report.targets = linkTargets(r.storage, changes, before, after)
+
+	// Rewrite links across the vault
+	if len(report.targets) > 0 {
+		for _, note := range after {
+			content, err := r.storage.ReadFile(note.Path)
+			if err != nil {
+				continue
+			}
+
+			updated, count := r.linker.RewriteLinks(string(content), report.rewriteTarget)
+			if count == 0 {
+				continue
+			}
+
+			if err := r.storage.WriteNote(note.Path, updated); err != nil {
+				return report, fmt.Errorf("error updating links in %s: %w", note.RelID, err)
+			}
+			report.LinksRewritten += count
+			report.UpdatedNotes = append(report.UpdatedNotes, note.Path)
+		}
+	}
+
+	for _, change := range changes {
+		note, err := r.storage.GetNote(change.NewPath)
+		if err != nil {
+			continue
+		}
+
+		if r.pinned != nil {
+			if ok, err := r.pinned.UpdatePath(change.OldPath, &note); err != nil {
+				return report, err
+			} else if ok {
+				report.PinsUpdated++
+			}
+		}
+		if r.recent != nil {
+			if ok, err := r.recent.UpdatePath(change.OldPath, &note); err != nil {
+				return report, err
+			} else if ok {
+				report.RecentsUpdated++
+			}
+		}
+		if r.tags != nil {
+			count, err := r.tags.RenameNote(change.OldPath, change.NewPath)
+			if err != nil {
+				return report, err
+			}
+			report.TagsUpdated += count
+		}
+	}
+
+	return report, nil
+}
+
+// rewriteTarget maps an old link target to its new form
+func (rep *Report) rewriteTarget(target string) (string, bool) {
+	newTarget, ok := rep.targets[strings.ToLower(filepath.ToSlash(target))]
+	return newTarget, ok
+}
+
+// linkTargets builds the map of old link targets to new ones
+// Links by vault path ([[Work/Note]]) always resolve; links by bare name ([[Note]])
+// are only rewritten when the old name was unique in the vault
+func linkTargets(store *storage.Storage, changes []Change, before, after []models.Note) map[string]string {
+	targets := make(map[string]string)
+
+	for _, change := range changes {
+		oldRel := relID(store, change.OldPath)
+		newRel := relID(store, change.NewPath)
+		oldBase := filepath.Base(change.OldPath)
+		newBase := filepath.Base(change.NewPath)
+
+		// Path forms, with and without extension
+		targets[strings.ToLower(oldRel)] = newRel
+		targets[strings.ToLower(trimExt(oldRel))] = trimExt(newRel)
+
+		// Bare name forms
+		if countBase(before, oldBase) == 1 {
+			newName := newBase
+			if countBase(after, newBase) > 1 {
+				newName = newRel // Bare name would be ambiguous after the move
+			}
+			if _, taken := targets[strings.ToLower(oldBase)]; !taken {
+				targets[strings.ToLower(oldBase)] = newName
+			}
+			if _, taken := targets[strings.ToLower(trimExt(oldBase))]; !taken {
+				targets[strings.ToLower(trimExt(oldBase))] = trimExt(newName)
+			}
+		}
+	}
+
+	// Drop entries that don't change anything, links resolve case-insensitively
+	for oldTarget, newTarget := range targets {
+		if strings.EqualFold(oldTarget, newTarget) {
+			delete(targets, oldTarget)
+		}
+	}
+
+	return targets
+}
+
+// relID returns the vault-relative slash path of a note
+func relID(store *storage.Storage, path string) string {
+	rel, err := filepath.Rel(store.VaultDir(), path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// countBase counts notes with the given file name, ignoring case and extension
+func countBase(notes []models.Note, name string) int {
+	count := 0
+	for _, note := range notes {
+		if strings.EqualFold(trimExt(note.Name), trimExt(name)) {
+			count++
+		}
+	}
+	return count
+}
+
+// trimExt removes the file extension from a name or path
+func trimExt(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// moveSwap moves a note's swap file along with it (best effort)
+func (r *Renamer) moveSwap(oldPath, newPath string) {
+	if _, err := r.storage.Stat(storage.SwapPath(oldPath)); err == nil {
+		_ = r.storage.Move(storage.SwapPath(oldPath), storage.SwapPath(newPath))
+	}
+}
+
+// plural picks the singular or plural form of a word
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
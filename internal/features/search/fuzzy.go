@@ -0,0 +1,232 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode is how a search query matches the text of notes
+type Mode int
+
+const (
+	ModeExact Mode = iota // Words match where they appear, also inside longer words
+	ModeFuzzy             // Words also match with typos or left out letters, in note names too
+	ModeRegex             // The query is a regular expression matched against each line
+)
+
+// Modes lists the search modes in the order the search view cycles through them
+var Modes = []Mode{ModeExact, ModeFuzzy, ModeRegex}
+
+func (m Mode) String() string {
+	switch m {
+	case ModeFuzzy:
+		return "fuzzy"
+	case ModeRegex:
+		return "regex"
+	}
+	return "exact"
+}
+
+// ParseMode parses the name of a search mode
+func ParseMode(name string) (Mode, error) {
+	for _, mode := range Modes {
+		if strings.EqualFold(name, mode.String()) {
+			return mode, nil
+		}
+	}
+	return ModeExact, fmt.Errorf("unknown search mode %q, expected exact, fuzzy or regex", name)
+}
+
+// termMatcher decides if a word of a note matches a word of a query
+type termMatcher struct {
+	stem  bool // Other forms of English words match ("meeting" finds "meetings")
+	fuzzy bool // Words with typos or left out letters match
+}
+
+// weight returns how well a note term matches a query term: 1 for an exact
+// match, less for a fuzzy one and 0 if it doesn't match
+// Like the search itself, part of a word matches ("meet" finds "meetings")
+func (m termMatcher) weight(noteTerm, queryTerm string) float64 {
+	if strings.Contains(noteTerm, queryTerm) || (m.stem && Stem(noteTerm) == Stem(queryTerm)) {
+		return 1
+	}
+	if m.fuzzy {
+		return fuzzyWeight(noteTerm, queryTerm)
+	}
+	return 0
+}
+
+// matches checks if a note term matches a query term
+func (m termMatcher) matches(noteTerm, queryTerm string) bool {
+	return m.weight(noteTerm, queryTerm) > 0
+}
+
+// maxEdits is how many typos a query word may have: letters changed, added,
+// left out or swapped with the next one. Short words must be spelled right
+func maxEdits(letters int) int {
+	switch {
+	case letters < 3:
+		return 0
+	case letters < 6:
+		return 1
+	}
+	return 2
+}
+
+// fuzzyWeight scores a note term against a query term with typos
+// ("meetnig" finds "meeting" and "meetings") or left out letters ("mtg" finds
+// "meeting"), from just below 1 down to 0 for no match
+func fuzzyWeight(noteTerm, queryTerm string) float64 {
+	note, query := []rune(noteTerm), []rune(queryTerm)
+
+	if limit := maxEdits(len(query)); limit > 0 {
+		edits := editDistance(query, note, limit)
+		if len(note) > len(query) {
+			// Or a typo in the start of a longer word
+			if prefix := editDistance(query, note[:len(query)], limit); prefix < edits {
+				edits = prefix
+			}
+		}
+		if edits <= limit {
+			return 1 - float64(edits)/float64(len(query)+1)
+		}
+	}
+
+	// Abbreviations: the letters in order, starting with the same one
+	if len(query) >= 3 && len(query) < len(note) && query[0] == note[0] && isSubsequence(query, note) {
+		return 0.5 * float64(len(query)) / float64(len(note))
+	}
+	return 0
+}
+
+// editDistance counts the edits between two words (the optimal string
+// alignment distance), or returns limit+1 once it is sure to be more than limit
+func editDistance(a, b []rune, limit int) int {
+	if diff := len(a) - len(b); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	// Three rows of the distance matrix: two rows back, the last one and this one
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		best := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := prev[j-1] + cost
+			if prev[j]+1 < d {
+				d = prev[j] + 1
+			}
+			if cur[j-1]+1 < d {
+				d = cur[j-1] + 1
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && prev2[j-2]+1 < d {
+				d = prev2[j-2] + 1 // Swapped letters
+			}
+			cur[j] = d
+			if d < best {
+				best = d
+			}
+		}
+		if best > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	if prev[len(b)] > limit {
+		return limit + 1
+	}
+	return prev[len(b)]
+}
+
+// isSubsequence checks if the letters of a word appear in order in another
+func isSubsequence(letters, word []rune) bool {
+	i := 0
+	for _, r := range word {
+		if i < len(letters) && letters[i] == r {
+			i++
+		}
+	}
+	return i == len(letters)
+}
+
+// fuzzyName matches a query text against a note name (without the extension)
+// and returns the parts of the name that matched: either every word of the
+// text matches a word of the name, or its letters appear in order from the
+// start of a word of the name ("mtgnotes" finds "meeting-notes")
+func fuzzyName(name, text string) ([]Span, bool) {
+	lower := strings.ToLower(name)
+	if len(lower) != len(name) {
+		lower = name // Can't map lowercase positions back, match as written
+	}
+	m := termMatcher{fuzzy: true}
+
+	words := Tokenize(text)
+	if len(words) == 0 {
+		return nil, false
+	}
+	nameSpans := termSpans(lower)
+	spans := []Span{}
+	for _, word := range words {
+		found := false
+		for _, span := range nameSpans {
+			if m.matches(lower[span.Start:span.End], word) {
+				spans = append(spans, span)
+				found = true
+			}
+		}
+		if !found {
+			spans = nil
+			break
+		}
+	}
+	if spans != nil {
+		return mergeSpans(spans), true
+	}
+
+	letters := []rune(strings.Join(words, ""))
+	if len(letters) < 3 {
+		return nil, false
+	}
+	for _, start := range nameSpans {
+		if spans, ok := subsequenceSpans(letters, lower, start.Start); ok {
+			return spans, true
+		}
+	}
+	return nil, false
+}
+
+// subsequenceSpans finds the letters of a word in order in a text from a
+// position, which must have the first letter, and returns where they are
+func subsequenceSpans(letters []rune, text string, from int) ([]Span, bool) {
+	spans := []Span{}
+	i := 0
+	for pos, r := range text[from:] {
+		if i == len(letters) {
+			break
+		}
+		if r != letters[i] {
+			if i == 0 {
+				return nil, false
+			}
+			continue
+		}
+		start := from + pos
+		spans = append(spans, Span{start, start + len(string(r))})
+		i++
+	}
+	if i < len(letters) {
+		return nil, false
+	}
+	return mergeSpans(spans), true
+}
@@ -0,0 +1,517 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+// IndexFile is the full-text index inside the vault
+const IndexFile = ".search-index.json"
+
+// indexVersion changes with the index format or the tokenizer; other versions are rebuilt
+const indexVersion = 1
+
+// IndexOptions controls how searches use the index
+type IndexOptions struct {
+	Enabled bool // Serve searches from the index instead of reading every note
+	Stem    bool // Also match other forms of English words ("meeting" finds "meetings")
+}
+
+// indexedNote is what the index knows about a note
+type indexedNote struct {
+	ModTime time.Time `json:"modified"`
+	Size    int64     `json:"size"`
+	Hash    string    `json:"hash"`           // Content hash, so touched but unchanged notes aren't re-indexed
+	Terms   int       `json:"terms"`          // Number of terms in the note
+	Tags    []string  `json:"tags,omitempty"` // Tags of the note, lowercase
+}
+
+// indexData is the index as saved in IndexFile
+type indexData struct {
+	Version int                       `json:"version"`
+	Notes   map[string]*indexedNote   `json:"notes"` // By vault-relative path
+	Terms   map[string]map[string]int `json:"terms"` // Term -> note path -> occurrences
+}
+
+// Index is a persistent inverted index of the words in a vault's notes
+// It is kept in <vault>/.search-index.json and brought up to date before each
+// search: notes whose modification time or size changed are read again, and
+// re-indexed only if their content hash changed. Several totions may share a
+// vault; each saves a complete index atomically and the next refresh of the
+// others picks up whatever they missed
+type Index struct {
+	store *storage.Storage
+
+	refreshMu sync.Mutex // Held while the index is brought up to date
+
+	mu        sync.Mutex
+	options   IndexOptions
+	data      indexData
+	noteTerms map[string][]string // Note path -> its terms, to remove a note quickly
+	ready     bool                // data reflects the vault as of the last refresh
+	dirty     bool                // data changed since it was saved
+}
+
+var (
+	indexesMu sync.Mutex
+	indexes   = make(map[*storage.Storage]*Index)
+)
+
+// IndexFor returns the index of a storage's vault, shared by every search on it
+func IndexFor(store *storage.Storage) *Index {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+
+	idx, ok := indexes[store]
+	if !ok {
+		idx = &Index{
+			store:   store,
+			options: IndexOptions{Enabled: true},
+		}
+		idx.reset()
+		indexes[store] = idx
+	}
+	return idx
+}
+
+// Forget drops the index of a storage that is no longer used, e.g. after
+// switching vaults, saving what changed since its last refresh
+func Forget(store *storage.Storage) {
+	indexesMu.Lock()
+	idx, ok := indexes[store]
+	delete(indexes, store)
+	indexesMu.Unlock()
+
+	if ok {
+		_ = idx.save()
+	}
+}
+
+// SetOptions changes how searches use the index
+func (idx *Index) SetOptions(options IndexOptions) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.options = options
+}
+
+// Options returns how searches use the index
+func (idx *Index) Options() IndexOptions {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.options
+}
+
+// Refresh brings the index up to date with the vault and saves it
+// The saved index is loaded first; without one every note is indexed
+func (idx *Index) Refresh() error {
+	idx.refreshMu.Lock()
+	defer idx.refreshMu.Unlock()
+	return idx.refresh()
+}
+
+// RefreshInBackground brings the index up to date without waiting for it
+// Searches made meanwhile use the index as it was, or read the notes if it
+// was never built
+func (idx *Index) RefreshInBackground() {
+	go func() {
+		_ = idx.Refresh()
+	}()
+}
+
+// Rebuild indexes every note again from scratch
+func (idx *Index) Rebuild() error {
+	idx.refreshMu.Lock()
+	defer idx.refreshMu.Unlock()
+
+	idx.mu.Lock()
+	idx.reset()
+	idx.ready = true // Don't load the saved index
+	idx.dirty = true
+	idx.mu.Unlock()
+	return idx.refresh()
+}
+
+// Update re-indexes a note that was saved, e.g. when the vault watcher reports it
+// Notes outside the vault are ignored
+func (idx *Index) Update(path string) error {
+	rel, ok := idx.rel(path)
+	if !ok || !storage.IsNoteFile(path) {
+		return nil
+	}
+	info, err := idx.store.Stat(path)
+	if err != nil {
+		idx.Remove(path)
+		return nil
+	}
+	return idx.indexNote(rel, path, info)
+}
+
+// Remove drops a deleted note from the index
+func (idx *Index) Remove(path string) {
+	rel, ok := idx.rel(path)
+	if !ok {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, ok := idx.data.Notes[rel]; ok {
+		idx.removeLocked(rel)
+		idx.dirty = true
+	}
+}
+
+// usable checks if searches can use the index right now
+// A refresh is started if none is running; while one is, the index is used
+// as it is, or not at all if it was never built
+func (idx *Index) usable() bool {
+	if !idx.Options().Enabled {
+		return false
+	}
+
+	if idx.refreshMu.TryLock() {
+		defer idx.refreshMu.Unlock()
+		return idx.refresh() == nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.ready
+}
+
+// refresh brings the index up to date; refreshMu must be held
+func (idx *Index) refresh() error {
+	idx.mu.Lock()
+	ready := idx.ready
+	idx.mu.Unlock()
+	if !ready {
+		idx.load()
+	}
+
+	vaultDir := idx.store.VaultDir()
+	seen := make(map[string]bool)
+	err := idx.store.Walk(vaultDir, func(path string, info backend.FileInfo) error {
+		if info.IsDir || storage.IsHidden(info.Name()) || !storage.IsNoteFile(path) {
+			return nil
+		}
+		rel, ok := idx.rel(path)
+		if !ok {
+			return nil
+		}
+		seen[rel] = true
+
+		idx.mu.Lock()
+		note := idx.data.Notes[rel]
+		idx.mu.Unlock()
+		if note != nil && note.ModTime.Equal(info.ModTime) && note.Size == info.Size {
+			return nil
+		}
+		return idx.indexNote(rel, path, info)
+	})
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	for rel := range idx.data.Notes {
+		if !seen[rel] {
+			idx.removeLocked(rel)
+			idx.dirty = true
+		}
+	}
+	idx.ready = true
+	idx.mu.Unlock()
+
+	return idx.save()
+}
+
+// indexNote reads a note and indexes it if its content changed
+func (idx *Index) indexNote(rel, path string, info backend.FileInfo) error {
+	content, err := idx.store.ReadFile(path)
+	if err != nil {
+		return nil // Deleted meanwhile, the next refresh drops it
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if note, ok := idx.data.Notes[rel]; ok && note.Hash == hash {
+		note.ModTime, note.Size = info.ModTime, info.Size
+		idx.dirty = true
+		return nil
+	}
+
+	text := string(content)
+	counts := make(map[string]int)
+	all := Tokenize(text)
+	for _, term := range all {
+		counts[term]++
+	}
+	noteTags := tags.ExtractNoteTags(path, text)
+	for i, tag := range noteTags {
+		noteTags[i] = strings.ToLower(tag)
+	}
+
+	idx.removeLocked(rel)
+	terms := make([]string, 0, len(counts))
+	for term, count := range counts {
+		postings := idx.data.Terms[term]
+		if postings == nil {
+			postings = make(map[string]int)
+			idx.data.Terms[term] = postings
+		}
+		postings[rel] = count
+		terms = append(terms, term)
+	}
+	idx.noteTerms[rel] = terms
+	idx.data.Notes[rel] = &indexedNote{
+		ModTime: info.ModTime,
+		Size:    info.Size,
+		Hash:    hash,
+		Terms:   len(all),
+		Tags:    noteTags,
+	}
+	idx.dirty = true
+	return nil
+}
+
+// removeLocked drops a note from the index; mu must be held
+func (idx *Index) removeLocked(rel string) {
+	for _, term := range idx.noteTerms[rel] {
+		postings := idx.data.Terms[term]
+		delete(postings, rel)
+		if len(postings) == 0 {
+			delete(idx.data.Terms, term)
+		}
+	}
+	delete(idx.noteTerms, rel)
+	delete(idx.data.Notes, rel)
+}
+
+// reset empties the index; mu must be held (or the index not shared yet)
+func (idx *Index) reset() {
+	idx.data = indexData{
+		Version: indexVersion,
+		Notes:   make(map[string]*indexedNote),
+		Terms:   make(map[string]map[string]int),
+	}
+	idx.noteTerms = make(map[string][]string)
+	idx.ready = false
+}
+
+// load reads the saved index; a missing, unreadable or outdated one is rebuilt
+func (idx *Index) load() {
+	var data indexData
+	raw, err := idx.store.ReadFile(idx.indexPath())
+	if err != nil || json.Unmarshal(raw, &data) != nil || data.Version != indexVersion ||
+		data.Notes == nil || data.Terms == nil {
+		idx.mu.Lock()
+		idx.reset()
+		idx.dirty = true
+		idx.mu.Unlock()
+		return
+	}
+
+	noteTerms := make(map[string][]string, len(data.Notes))
+	for term, postings := range data.Terms {
+		for rel := range postings {
+			noteTerms[rel] = append(noteTerms[rel], term)
+		}
+	}
+
+	idx.mu.Lock()
+	idx.data = data
+	idx.noteTerms = noteTerms
+	idx.mu.Unlock()
+}
+
+// save writes the index if it changed
+func (idx *Index) save() error {
+	idx.mu.Lock()
+	if !idx.dirty {
+		idx.mu.Unlock()
+		return nil
+	}
+	raw, err := json.Marshal(idx.data)
+	idx.dirty = false
+	idx.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return idx.store.WriteFile(idx.indexPath(), raw)
+}
+
+// indexPath returns where the index is saved
+func (idx *Index) indexPath() string {
+	return filepath.Join(idx.store.VaultDir(), IndexFile)
+}
+
+// rel returns the vault-relative slash path of a note
+func (idx *Index) rel(path string) (string, bool) {
+	rel, err := filepath.Rel(idx.store.VaultDir(), path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// abs returns the absolute path of a vault-relative note path
+func (idx *Index) abs(rel string) string {
+	return filepath.Join(idx.store.VaultDir(), filepath.FromSlash(rel))
+}
+
+// prefix returns the vault-relative prefix of the notes inside a folder
+func (idx *Index) prefix(root string) string {
+	if rel, ok := idx.rel(root); ok && rel != "." {
+		return rel + "/"
+	}
+	return ""
+}
+
+// under lists the notes inside a folder as absolute paths, sorted
+func (idx *Index) under(root string, rels map[string]bool) []string {
+	prefix := idx.prefix(root)
+	paths := []string{}
+	for rel := range rels {
+		if strings.HasPrefix(rel, prefix) {
+			paths = append(paths, idx.abs(rel))
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// all lists the notes under root
+func (idx *Index) all(root string) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	found := make(map[string]bool, len(idx.data.Notes))
+	for rel := range idx.data.Notes {
+		found[rel] = true
+	}
+	return idx.under(root, found)
+}
+
+// matching lists the notes under root that may match a query
+func (idx *Index) matching(root string, q *Query, m termMatcher) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	found := q.root.candidates(idx, m)
+	if found == nil {
+		found = make(map[string]bool, len(idx.data.Notes))
+		for rel := range idx.data.Notes {
+			found[rel] = true
+		}
+	}
+	return idx.under(root, found)
+}
+
+// candidatesLocked lists the notes that may contain a text: those with every
+// term of the text, or part of a term at its edges ("ello wor" finds notes
+// with "hello" and "world"); nil if the text has no terms; mu must be held
+func (idx *Index) candidatesLocked(text string, m termMatcher) map[string]bool {
+	var found map[string]bool
+	for _, term := range Tokenize(text) {
+		notes := make(map[string]bool)
+		for _, indexed := range idx.matchingTerms(term, m) {
+			for rel := range idx.data.Terms[indexed] {
+				if found == nil || found[rel] {
+					notes[rel] = true
+				}
+			}
+		}
+		found = notes
+		if len(found) == 0 {
+			break
+		}
+	}
+	return found
+}
+
+// matchingTerms lists the indexed terms matching a term: those containing it,
+// with its stem when stemming is on, or like it in fuzzy mode; mu must be held
+func (idx *Index) matchingTerms(term string, m termMatcher) []string {
+	matches := []string{}
+	for indexed := range idx.data.Terms {
+		if m.matches(indexed, term) {
+			matches = append(matches, indexed)
+		}
+	}
+	return matches
+}
+
+// corpus returns the statistics a ranker needs about the notes under root
+func (idx *Index) corpus(root string, r *ranker) corpus {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	prefix := idx.prefix(root)
+	stats := corpus{docFreq: make(map[string]int)}
+	for rel, note := range idx.data.Notes {
+		if strings.HasPrefix(rel, prefix) {
+			stats.notes++
+			stats.terms += note.Terms
+		}
+	}
+
+	for _, queryTerm := range r.terms {
+		notes := make(map[string]bool)
+		for term, postings := range idx.data.Terms {
+			if !r.match.matches(term, queryTerm) {
+				continue
+			}
+			for rel := range postings {
+				if strings.HasPrefix(rel, prefix) {
+					notes[rel] = true
+				}
+			}
+		}
+		stats.docFreq[queryTerm] = len(notes)
+	}
+	return stats
+}
+
+// withTags lists the notes under root that have every tag
+func (idx *Index) withTags(root string, tagNames []string) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	found := make(map[string]bool)
+	for rel, note := range idx.data.Notes {
+		all := true
+		for _, tag := range tagNames {
+			if !containsString(note.Tags, tag) {
+				all = false
+				break
+			}
+		}
+		if all {
+			found[rel] = true
+		}
+	}
+	return idx.under(root, found)
+}
+
+// containsString checks if a list has a string
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,746 @@
+package search
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/features/tasks"
+	"github.com/0xshariq/totion/internal/models"
+)
+
+// Filters lists the field filters a query can use, e.g. tag:work
+var Filters = []string{"tag", "notebook", "format", "modified", "has", "title"}
+
+// QueryError is a malformed search query
+type QueryError struct {
+	Column int // 1-based position of the problem in the query
+	Msg    string
+	Regex  bool // The query is a regular expression (ModeRegex)
+}
+
+func (e *QueryError) Error() string {
+	what := "query"
+	if e.Regex {
+		what = "regular expression"
+	}
+	return fmt.Sprintf("invalid %s at column %d: %s", what, e.Column, e.Msg)
+}
+
+// Query is a parsed search query
+//
+// Words must all appear in a note ("meeting notes" finds notes with both words),
+// "quoted phrases" must appear as written, and AND, OR, NOT and parentheses
+// combine them. #tag and the field filters narrow down the notes:
+//
+//	tag:work notebook:projects format:md modified:>2026-01-01 has:task title:plan
+type Query struct {
+	root  node
+	terms []string // Words and phrases the notes should contain, for ranking and the lines shown
+	plain []string // The terms that aren't phrases, which may match fuzzily
+	words []string // The words of plain
+	tags  []string // Tags the notes should have
+	tasks bool     // Task lines are shown
+}
+
+// ParseQuery parses a search query, see Query for the syntax
+func ParseQuery(text string) (*Query, error) {
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, &QueryError{Column: 1, Msg: "expected something to search for"}
+	}
+
+	p := &queryParser{tokens: tokens, end: len([]rune(text)) + 1}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		if tok.kind == tokRParen {
+			return nil, &QueryError{Column: tok.column, Msg: "unexpected ), there is no ( to close"}
+		}
+		return nil, &QueryError{Column: tok.column, Msg: fmt.Sprintf("unexpected %s", tok.text)}
+	}
+
+	q := &Query{root: root}
+	q.collect(root, false)
+	return q, nil
+}
+
+// collect gathers the words, tags and filters a note should match (those not under a NOT)
+func (q *Query) collect(n node, negated bool) {
+	switch n := n.(type) {
+	case *andNode:
+		for _, child := range n.children {
+			q.collect(child, negated)
+		}
+	case *orNode:
+		for _, child := range n.children {
+			q.collect(child, negated)
+		}
+	case *notNode:
+		q.collect(n.child, !negated)
+	case *termNode:
+		if !negated {
+			q.terms = append(q.terms, n.text)
+			if !n.phrase {
+				q.plain = append(q.plain, n.text)
+				q.words = append(q.words, Tokenize(n.text)...)
+			}
+		}
+	case *tagNode:
+		if !negated {
+			q.tags = append(q.tags, n.tag)
+		}
+	case *hasTaskNode:
+		q.tasks = q.tasks || !negated
+	}
+}
+
+// document is a note being matched against a query
+type document struct {
+	path     string
+	rel      string // Vault-relative slash path
+	content  string // Lowercase
+	meta     *models.FrontMatter
+	body     string
+	offset   int // Line number the body starts at
+	match    termMatcher
+	stems    map[string]bool // Stems of the note's words, built when first needed
+	words    []string        // The note's words, each once, built when first needed
+	tags     []string        // Built when first needed
+	tasks    []tasks.Task    // Built when first needed
+	modified func() time.Time
+}
+
+// hasStem checks if the note has a word with a stem
+func (d *document) hasStem(stem string) bool {
+	if d.stems == nil {
+		d.stems = make(map[string]bool)
+		for _, term := range Tokenize(d.content) {
+			d.stems[Stem(term)] = true
+		}
+	}
+	return d.stems[stem]
+}
+
+// hasWord checks if the note has a word matching a query word
+func (d *document) hasWord(word string) bool {
+	if d.words == nil {
+		seen := make(map[string]bool)
+		d.words = []string{}
+		for _, term := range Tokenize(d.content) {
+			if !seen[term] {
+				seen[term] = true
+				d.words = append(d.words, term)
+			}
+		}
+	}
+	for _, term := range d.words {
+		if d.match.matches(term, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// noteTags returns the note's tags, lowercase
+func (d *document) noteTags() []string {
+	if d.tags == nil {
+		d.tags = []string{}
+		for _, tag := range tags.ExtractNoteTags(d.path, d.content) {
+			d.tags = append(d.tags, strings.ToLower(tag))
+		}
+	}
+	return d.tags
+}
+
+// noteTasks returns the tasks in the note's body
+func (d *document) noteTasks() []tasks.Task {
+	if d.tasks == nil {
+		d.tasks = tasks.NewTaskManager().ParseTasksForFormat(d.body, models.FormatForPath(d.path))
+	}
+	return d.tasks
+}
+
+// node is a part of a parsed query
+type node interface {
+	// match checks if a note matches
+	match(d *document) bool
+	// candidates lists the indexed notes that may match, nil if any note may;
+	// the index's mu must be held
+	candidates(idx *Index, m termMatcher) map[string]bool
+}
+
+// andNode matches notes matching all of its children
+type andNode struct{ children []node }
+
+func (n *andNode) match(d *document) bool {
+	for _, child := range n.children {
+		if !child.match(d) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *andNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	var found map[string]bool
+	for _, child := range n.children {
+		notes := child.candidates(idx, m)
+		if notes == nil {
+			continue
+		}
+		if found == nil {
+			found = notes
+			continue
+		}
+		for rel := range found {
+			if !notes[rel] {
+				delete(found, rel)
+			}
+		}
+	}
+	return found
+}
+
+// orNode matches notes matching any of its children
+type orNode struct{ children []node }
+
+func (n *orNode) match(d *document) bool {
+	for _, child := range n.children {
+		if child.match(d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *orNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	found := make(map[string]bool)
+	for _, child := range n.children {
+		notes := child.candidates(idx, m)
+		if notes == nil {
+			return nil
+		}
+		for rel := range notes {
+			found[rel] = true
+		}
+	}
+	return found
+}
+
+// notNode matches notes not matching its child
+type notNode struct{ child node }
+
+func (n *notNode) match(d *document) bool {
+	return !n.child.match(d)
+}
+
+func (n *notNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	return nil
+}
+
+// termNode matches notes containing a word or phrase
+// With stemming on, a word also matches other forms of it; in fuzzy mode
+// words match with typos, and also match the note name
+type termNode struct {
+	text   string // Lowercase
+	phrase bool
+}
+
+func (n *termNode) match(d *document) bool {
+	if strings.Contains(d.content, n.text) {
+		return true
+	}
+	if n.phrase {
+		return false
+	}
+	if d.match.stem && n.matchStems(d) {
+		return true
+	}
+	if !d.match.fuzzy {
+		return false
+	}
+
+	base := filepath.Base(d.path)
+	if _, ok := fuzzyName(strings.TrimSuffix(base, filepath.Ext(base)), n.text); ok {
+		return true
+	}
+	for _, word := range Tokenize(n.text) {
+		if !d.hasWord(word) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchStems checks if the note has every word of the term in some form
+func (n *termNode) matchStems(d *document) bool {
+	for _, term := range Tokenize(n.text) {
+		if !d.hasStem(Stem(term)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *termNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	if n.phrase {
+		return idx.candidatesLocked(n.text, termMatcher{})
+	}
+	found := idx.candidatesLocked(n.text, m)
+	if !m.fuzzy || found == nil {
+		return found
+	}
+
+	// Notes whose name matches
+	for rel := range idx.data.Notes {
+		base := path.Base(rel)
+		if _, ok := fuzzyName(strings.TrimSuffix(base, path.Ext(base)), n.text); ok {
+			found[rel] = true
+		}
+	}
+	return found
+}
+
+// tagNode matches notes with a tag
+type tagNode struct{ tag string }
+
+func (n *tagNode) match(d *document) bool {
+	return containsString(d.noteTags(), n.tag)
+}
+
+func (n *tagNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	found := make(map[string]bool)
+	for rel, note := range idx.data.Notes {
+		if containsString(note.Tags, n.tag) {
+			found[rel] = true
+		}
+	}
+	return found
+}
+
+// notebookNode matches notes inside a notebook (a folder path from the vault root)
+type notebookNode struct{ path string }
+
+func (n *notebookNode) match(d *document) bool {
+	return strings.HasPrefix(strings.ToLower(d.rel), n.path+"/")
+}
+
+func (n *notebookNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	return nil
+}
+
+// formatNode matches notes of a format
+type formatNode struct{ format models.FileFormat }
+
+func (n *formatNode) match(d *document) bool {
+	return models.FormatForPath(d.path) == n.format
+}
+
+func (n *formatNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	return nil
+}
+
+// modifiedNode matches notes modified before or after a time
+type modifiedNode struct {
+	from, to time.Time // Zero for no bound; from is inclusive, to exclusive
+}
+
+func (n *modifiedNode) match(d *document) bool {
+	modified := d.modified()
+	if !n.from.IsZero() && modified.Before(n.from) {
+		return false
+	}
+	if !n.to.IsZero() && !modified.Before(n.to) {
+		return false
+	}
+	return true
+}
+
+func (n *modifiedNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	return nil
+}
+
+// hasTaskNode matches notes with tasks (checkboxes, or TODO headlines in Org)
+type hasTaskNode struct{}
+
+func (n *hasTaskNode) match(d *document) bool {
+	return len(d.noteTasks()) > 0
+}
+
+func (n *hasTaskNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	return nil
+}
+
+// titleNode matches notes whose name, title or an alias contains a text
+type titleNode struct{ text string }
+
+func (n *titleNode) match(d *document) bool {
+	name := strings.TrimSuffix(filepath.Base(d.path), filepath.Ext(d.path))
+	if strings.Contains(strings.ToLower(name), n.text) {
+		return true
+	}
+	if d.meta == nil {
+		return false
+	}
+	if strings.Contains(strings.ToLower(d.meta.Title), n.text) {
+		return true
+	}
+	for _, alias := range d.meta.Aliases {
+		if strings.Contains(strings.ToLower(alias), n.text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *titleNode) candidates(idx *Index, m termMatcher) map[string]bool {
+	return nil
+}
+
+// Query tokens
+const (
+	tokWord = iota
+	tokPhrase
+	tokFilter
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+// queryToken is a word, phrase, filter, parenthesis or operator in a query
+type queryToken struct {
+	kind   int
+	text   string // As written, for errors
+	value  string // Word or phrase; the value of a filter
+	field  string // Filter name, lowercase
+	column int
+}
+
+// lexQuery splits a query into tokens
+func lexQuery(text string) ([]queryToken, error) {
+	runes := []rune(text)
+	tokens := []queryToken{}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokLParen, text: "(", column: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokRParen, text: ")", column: i + 1})
+			i++
+		case r == '"':
+			phrase, next, err := lexPhrase(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: tokPhrase, text: string(runes[i:next]), value: phrase, column: i + 1})
+			i = next
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				i++
+			}
+			word := string(runes[start:i])
+
+			// field:"quoted value"
+			if i < len(runes) && runes[i] == '"' && strings.HasSuffix(word, ":") && isFilter(strings.TrimSuffix(word, ":")) {
+				value, next, err := lexPhrase(runes, i)
+				if err != nil {
+					return nil, err
+				}
+				field := strings.ToLower(strings.TrimSuffix(word, ":"))
+				tokens = append(tokens, queryToken{kind: tokFilter, text: string(runes[start:next]), field: field, value: value, column: start + 1})
+				i = next
+				continue
+			}
+
+			tokens = append(tokens, wordToken(word, start+1))
+		}
+	}
+	return tokens, nil
+}
+
+// lexPhrase reads a quoted phrase starting at runes[start] and returns it with
+// the position after the closing quote
+func lexPhrase(runes []rune, start int) (string, int, error) {
+	end := start + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	if end == len(runes) {
+		return "", 0, &QueryError{Column: start + 1, Msg: `missing closing " for this phrase`}
+	}
+	phrase := string(runes[start+1 : end])
+	if strings.TrimSpace(phrase) == "" {
+		return "", 0, &QueryError{Column: start + 1, Msg: "empty phrase"}
+	}
+	return phrase, end + 1, nil
+}
+
+// wordToken classifies a word as an operator, a filter or a word to find
+// Words with an unknown prefix ("TODO:", "10:30", "re:budget", URLs) are words to find
+func wordToken(word string, column int) queryToken {
+	switch word {
+	case "AND":
+		return queryToken{kind: tokAnd, text: word, column: column}
+	case "OR":
+		return queryToken{kind: tokOr, text: word, column: column}
+	case "NOT":
+		return queryToken{kind: tokNot, text: word, column: column}
+	}
+
+	i := strings.Index(word, ":")
+	if i <= 0 || !isFilter(word[:i]) || strings.HasPrefix(word[i+1:], "//") {
+		return queryToken{kind: tokWord, text: word, value: word, column: column}
+	}
+	return queryToken{kind: tokFilter, text: word, field: strings.ToLower(word[:i]), value: word[i+1:], column: column}
+}
+
+// isFilter checks if a word is the name of a filter, in any case
+func isFilter(s string) bool {
+	return containsString(Filters, strings.ToLower(s))
+}
+
+// queryParser builds a query from its tokens
+//
+//	or      = and { OR and }
+//	and     = unary { [AND] unary }
+//	unary   = NOT unary | primary
+//	primary = ( or ) | word | phrase | filter
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	end    int // Column just past the query, for errors at the end
+}
+
+// peek returns the next token without consuming it
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// parseOr parses terms separated by OR
+func (p *queryParser) parseOr() (node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokOr {
+			break
+		}
+		p.pos++
+		if err := p.expectTerm(tok); err != nil {
+			return nil, err
+		}
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &orNode{children: children}, nil
+}
+
+// parseAnd parses terms separated by AND or nothing
+func (p *queryParser) parseAnd() (node, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokOr || tok.kind == tokRParen {
+			break
+		}
+		if tok.kind == tokAnd {
+			p.pos++
+			if err := p.expectTerm(tok); err != nil {
+				return nil, err
+			}
+		}
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &andNode{children: children}, nil
+}
+
+// parseUnary parses a term, possibly negated with NOT
+func (p *queryParser) parseUnary() (node, error) {
+	tok, ok := p.peek()
+	if ok && tok.kind == tokNot {
+		p.pos++
+		if err := p.expectTerm(tok); err != nil {
+			return nil, err
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a word, phrase, filter or parenthesized query
+func (p *queryParser) parsePrimary() (node, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, &QueryError{Column: p.end, Msg: "expected something to search for"}
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokLParen:
+		if next, ok := p.peek(); ok && next.kind == tokRParen {
+			return nil, &QueryError{Column: tok.column, Msg: "empty parentheses"}
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokRParen {
+			return nil, &QueryError{Column: tok.column, Msg: "missing ) to close this ("}
+		}
+		p.pos++
+		return inner, nil
+	case tokRParen:
+		return nil, &QueryError{Column: tok.column, Msg: "unexpected ), there is no ( to close"}
+	case tokAnd, tokOr:
+		return nil, &QueryError{Column: tok.column, Msg: fmt.Sprintf("%s needs something to search for before it", tok.text)}
+	case tokPhrase:
+		return &termNode{text: strings.ToLower(tok.value), phrase: true}, nil
+	case tokFilter:
+		return parseFilter(tok)
+	}
+
+	if strings.HasPrefix(tok.value, "#") && len(tok.value) > 1 {
+		return &tagNode{tag: strings.ToLower(strings.TrimPrefix(tok.value, "#"))}, nil
+	}
+	return &termNode{text: strings.ToLower(tok.value)}, nil
+}
+
+// expectTerm checks that an operator is followed by something to search for
+func (p *queryParser) expectTerm(op queryToken) error {
+	tok, ok := p.peek()
+	if !ok || tok.kind == tokRParen || tok.kind == tokAnd || tok.kind == tokOr {
+		return &QueryError{Column: op.column, Msg: fmt.Sprintf("%s needs something to search for after it", op.text)}
+	}
+	return nil
+}
+
+// parseFilter builds the node of a field filter
+func parseFilter(tok queryToken) (node, error) {
+	value := strings.TrimSpace(tok.value)
+	fail := func(format string, args ...interface{}) error {
+		return &QueryError{Column: tok.column, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	switch tok.field {
+	case "tag":
+		tag := strings.ToLower(strings.TrimPrefix(value, "#"))
+		if tag == "" {
+			return nil, fail("tag: needs a tag name, e.g. tag:work")
+		}
+		return &tagNode{tag: tag}, nil
+
+	case "notebook":
+		path := strings.Trim(strings.ToLower(filepath.ToSlash(value)), "/")
+		if path == "" {
+			return nil, fail("notebook: needs a notebook name, e.g. notebook:work")
+		}
+		return &notebookNode{path: path}, nil
+
+	case "format":
+		if value == "" {
+			return nil, fail("format: needs a format, e.g. format:md")
+		}
+		format, ok := models.FormatForExt("." + strings.TrimPrefix(value, "."))
+		if !ok {
+			names := []string{}
+			for _, info := range models.Formats() {
+				names = append(names, string(info.Format))
+			}
+			return nil, fail("unknown format %q, expected one of %s", value, strings.Join(names, ", "))
+		}
+		return &formatNode{format: format}, nil
+
+	case "modified":
+		return parseModified(value, fail)
+
+	case "has":
+		if value == "" {
+			return nil, fail("has: needs what to look for, e.g. has:task")
+		}
+		if strings.ToLower(value) != "task" && strings.ToLower(value) != "tasks" {
+			return nil, fail("unknown has:%s, expected has:task", value)
+		}
+		return &hasTaskNode{}, nil
+
+	case "title":
+		if value == "" {
+			return nil, fail("title: needs some text, e.g. title:plan")
+		}
+		return &titleNode{text: strings.ToLower(value)}, nil
+	}
+	return nil, fail("unknown filter %q", tok.field)
+}
+
+// parseModified parses modified:DATE with an optional >, >=, < or <= before the date
+// Dates are days in local time: modified:>2026-01-01 finds notes changed after that day
+func parseModified(value string, fail func(format string, args ...interface{}) error) (node, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, prefix) {
+			op = prefix
+			break
+		}
+	}
+	day, err := time.ParseInLocation("2006-01-02", strings.TrimPrefix(value, op), time.Local)
+	if err != nil {
+		return nil, fail("modified: needs a date like 2026-01-01, optionally after >, >=, < or <=")
+	}
+	next := day.AddDate(0, 0, 1)
+
+	switch op {
+	case ">":
+		return &modifiedNode{from: next}, nil
+	case ">=":
+		return &modifiedNode{from: day}, nil
+	case "<":
+		return &modifiedNode{to: day}, nil
+	case "<=":
+		return &modifiedNode{to: next}, nil
+	}
+	return &modifiedNode{from: day, to: next}, nil
+}
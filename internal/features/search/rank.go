@@ -0,0 +1,169 @@
+package search
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xshariq/totion/internal/models"
+)
+
+// BM25 parameters and the extra weight of words in titles and headings
+const (
+	bm25K1       = 1.2 // How quickly more occurrences of a word stop adding to the score
+	bm25B        = 0.75
+	titleBoost   = 3.0 // A query word in the note name or title counts this many times
+	headingBoost = 2.0 // A query word in a heading counts this many times
+)
+
+// NoteResult is a note matching a search with its matching lines
+type NoteResult struct {
+	NotePath string
+	NoteName string
+	Score    float64   // Relevance, higher is better
+	Modified time.Time // Breaks ties between equally relevant notes, newest first
+	Matches  []SearchResult
+}
+
+// corpus holds what BM25 needs to know about all the notes searched
+type corpus struct {
+	notes   int            // Number of notes
+	terms   int            // Terms in all notes together
+	docFreq map[string]int // Query term -> notes containing it
+}
+
+// ranker scores notes against the terms of a query
+type ranker struct {
+	terms  []string
+	match  termMatcher
+	corpus corpus
+}
+
+// newRanker creates a ranker for a lowercase query
+func newRanker(query string, m termMatcher) *ranker {
+	return &ranker{
+		terms:  Tokenize(query),
+		match:  m,
+		corpus: corpus{docFreq: make(map[string]int)},
+	}
+}
+
+// weigh counts the query terms in a note, words in the title and headings
+// counting extra and fuzzy matches less, and returns the counts with the
+// number of terms in the note
+func (r *ranker) weigh(path string, meta *models.FrontMatter, body string) (map[string]float64, int) {
+	freqs := make(map[string]float64)
+	if len(r.terms) == 0 {
+		return freqs, 0
+	}
+
+	count := func(text string, weight float64) int {
+		terms := Tokenize(text)
+		for _, term := range terms {
+			for _, queryTerm := range r.terms {
+				if match := r.match.weight(term, queryTerm); match > 0 {
+					freqs[queryTerm] += weight * match
+				}
+			}
+		}
+		return len(terms)
+	}
+
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if meta != nil {
+		title += " " + meta.Title + " " + strings.Join(meta.Aliases, " ")
+	}
+	count(title, titleBoost)
+
+	length := 0
+	marker := headingMarker(path)
+	for _, line := range strings.Split(body, "\n") {
+		length += count(line, 1)
+		if marker != 0 && isHeading(line, marker) {
+			count(line, headingBoost-1) // Already counted once as body text
+		}
+	}
+	return freqs, length
+}
+
+// add counts a note into the corpus
+func (r *ranker) add(freqs map[string]float64, length int) {
+	r.corpus.notes++
+	r.corpus.terms += length
+	for term, freq := range freqs {
+		if freq > 0 {
+			r.corpus.docFreq[term]++
+		}
+	}
+}
+
+// score computes the BM25 score of a note from its query term counts
+func (r *ranker) score(freqs map[string]float64, length int) float64 {
+	if r.corpus.notes == 0 {
+		return 0
+	}
+	avgLength := float64(r.corpus.terms) / float64(r.corpus.notes)
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	score := 0.0
+	for _, term := range r.terms {
+		freq := freqs[term]
+		if freq == 0 {
+			continue
+		}
+		docFreq := float64(r.corpus.docFreq[term])
+		idf := math.Log(1 + (float64(r.corpus.notes)-docFreq+0.5)/(docFreq+0.5))
+		norm := 1 - bm25B + bm25B*float64(length)/avgLength
+		score += idf * freq * (bm25K1 + 1) / (freq + bm25K1*norm)
+	}
+	return score
+}
+
+// sortNotes orders notes by score, then the most recently modified first
+func sortNotes(notes []NoteResult) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Score != notes[j].Score {
+			return notes[i].Score > notes[j].Score
+		}
+		if !notes[i].Modified.Equal(notes[j].Modified) {
+			return notes[i].Modified.After(notes[j].Modified)
+		}
+		return notes[i].NotePath < notes[j].NotePath
+	})
+}
+
+// headingMarker returns the character headings start with in a note's format,
+// or 0 for formats without headings
+func headingMarker(path string) byte {
+	switch models.FormatForPath(path) {
+	case models.FormatMarkdown:
+		return '#'
+	case models.FormatOrg:
+		return '*'
+	case models.FormatAsciiDoc:
+		return '='
+	}
+	return 0
+}
+
+// isHeading checks if a line is a heading: up to six markers and a space
+func isHeading(line string, marker byte) bool {
+	level := 0
+	for level < len(line) && line[level] == marker {
+		level++
+	}
+	return level > 0 && level <= 6 && level < len(line) && line[level] == ' '
+}
+
+// Flatten lists the matches of ranked notes, best note first
+func Flatten(notes []NoteResult) []SearchResult {
+	results := []SearchResult{}
+	for _, note := range notes {
+		results = append(results, note.Matches...)
+	}
+	return results
+}
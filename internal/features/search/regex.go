@@ -0,0 +1,182 @@
+package search
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+)
+
+// RegexLimits bound a regex search, so a pattern matching nearly everything
+// in a large vault still returns quickly. Zero means no limit
+type RegexLimits struct {
+	Timeout    time.Duration // How long to search before returning what was found
+	MaxMatches int           // Matches to find in all notes
+}
+
+// DefaultRegexLimits are the limits of a new search manager
+var DefaultRegexLimits = RegexLimits{Timeout: 5 * time.Second, MaxMatches: 10000}
+
+// LimitError is a regex search that stopped early at one of its limits
+// The notes found until then are returned with it
+type LimitError struct {
+	Reason string
+}
+
+func (e *LimitError) Error() string {
+	return "search stopped early: " + e.Reason
+}
+
+// CompileRegex checks a pattern against the RE2 syntax and compiles it to
+// match ignoring case, unless it starts with (?-i)
+// A pattern that isn't valid, or that matches empty text (and so every line),
+// returns a *QueryError saying where the problem is
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	if _, err := syntax.Parse(pattern, syntax.Perl); err != nil {
+		return nil, regexError(pattern, err)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, regexError(pattern, err)
+	}
+	if re.MatchString("") {
+		return nil, &QueryError{Column: 1, Msg: "it matches empty text, so it would match every line", Regex: true}
+	}
+	return re, nil
+}
+
+// regexError describes why a pattern doesn't compile, pointing at the part in error
+func regexError(pattern string, err error) error {
+	synErr, ok := err.(*syntax.Error)
+	if !ok {
+		return &QueryError{Column: 1, Msg: err.Error(), Regex: true}
+	}
+
+	column := 1
+	if i := strings.Index(pattern, synErr.Expr); i >= 0 && synErr.Expr != "" {
+		column = len([]rune(pattern[:i])) + 1
+	}
+	msg := fmt.Sprintf("%s: %s", synErr.Code, synErr.Expr)
+	switch {
+	case synErr.Code == syntax.ErrInvalidPerlOp && strings.HasPrefix(synErr.Expr, "(?"):
+		msg += " (lookarounds like (?=...) aren't supported)"
+	case synErr.Code == syntax.ErrInvalidEscape && len(synErr.Expr) == 2 && synErr.Expr[1] >= '1' && synErr.Expr[1] <= '9':
+		msg += " (backreferences aren't supported)"
+	}
+	return &QueryError{Column: column, Msg: msg, Regex: true}
+}
+
+// SetRegexLimits changes the limits of regex searches, DefaultRegexLimits by default
+func (sm *SearchManager) SetRegexLimits(limits RegexLimits) {
+	sm.limits = limits
+}
+
+// searchRegex finds the notes with lines matching a regular expression (see
+// CompileRegex), the notes with the most matches first
+// At a limit the notes found so far are returned with a *LimitError
+func (sm *SearchManager) searchRegex(pattern string) ([]NoteResult, error) {
+	re, err := CompileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var deadline time.Time
+	if sm.limits.Timeout > 0 {
+		deadline = time.Now().Add(sm.limits.Timeout)
+	}
+	var limitErr *LimitError
+	found := 0
+
+	notes := []NoteResult{}
+	candidates := func() []string { return sm.index.all(sm.root) }
+	_, err = sm.eachNote(candidates, func(path string, content []byte) error {
+		results := []SearchResult{}
+		count := 0
+		for lineNum, line := range strings.Split(string(content), "\n") {
+			// Checking the clock on every line would slow down large notes
+			if lineNum%256 == 0 && !deadline.IsZero() && time.Now().After(deadline) {
+				limitErr = &LimitError{Reason: fmt.Sprintf("it took longer than %s", sm.limits.Timeout)}
+				break
+			}
+
+			max := -1
+			if sm.limits.MaxMatches > 0 {
+				max = sm.limits.MaxMatches - found
+			}
+			lineMatches := regexMatches(re, line, max)
+			if len(lineMatches) == 0 {
+				continue
+			}
+			found += len(lineMatches)
+			count += len(lineMatches)
+
+			spans, groups := []Span{}, []Span{}
+			for _, lm := range lineMatches {
+				spans = append(spans, Span{lm.Start, lm.End})
+				for _, group := range lm.Groups {
+					if group.Start >= 0 && group.End > group.Start {
+						groups = append(groups, group)
+					}
+				}
+			}
+			cut := cutSnippet(line, spans, 50)
+			results = append(results, SearchResult{
+				NotePath:     path,
+				NoteName:     filepath.Base(path),
+				LineNumber:   lineNum + 1,
+				MatchSnippet: cut.text,
+				FullLine:     line,
+				Highlights:   cut.spans(spans),
+				Captures:     cut.spans(mergeSpans(groups)),
+				LineMatches:  lineMatches,
+			})
+
+			if sm.limits.MaxMatches > 0 && found >= sm.limits.MaxMatches {
+				limitErr = &LimitError{Reason: fmt.Sprintf("it found %d matches", found)}
+				break
+			}
+		}
+
+		if len(results) > 0 {
+			notes = append(notes, NoteResult{
+				NotePath: path,
+				NoteName: filepath.Base(path),
+				Score:    float64(count),
+				Modified: sm.modTime(path),
+				Matches:  results,
+			})
+		}
+		if limitErr != nil {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortNotes(notes)
+	if limitErr != nil {
+		return notes, limitErr
+	}
+	return notes, nil
+}
+
+// regexMatches finds up to max (all if negative) matches of a regular
+// expression in a line, with their capture groups
+func regexMatches(re *regexp.Regexp, line string, max int) []LineMatch {
+	matches := []LineMatch{}
+	for _, loc := range re.FindAllStringSubmatchIndex(line, max) {
+		if loc[1] == loc[0] {
+			continue // Empty match, e.g. of a|b* between the a's
+		}
+		lm := LineMatch{Start: loc[0], End: loc[1]}
+		for i := 2; i+1 < len(loc); i += 2 {
+			lm.Groups = append(lm.Groups, Span{loc[i], loc[i+1]})
+		}
+		matches = append(matches, lm)
+	}
+	return matches
+}
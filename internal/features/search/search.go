@@ -2,11 +2,16 @@ package search
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
 )
 
 // SearchResult represents a search result
@@ -16,117 +21,596 @@ type SearchResult struct {
 	LineNumber   int
 	MatchSnippet string
 	FullLine     string
+	Highlights   []Span      // What matched in MatchSnippet, to highlight it
+	Captures     []Span      // Capture groups of a regex search in MatchSnippet
+	LineMatches  []LineMatch // Where the search matched in FullLine
+}
+
+// Span is the part of a line from byte Start up to End
+type Span struct {
+	Start int
+	End   int
+}
+
+// LineMatch is where a search matched in a line
+type LineMatch struct {
+	Start  int
+	End    int
+	Groups []Span // Capture groups of a regex search, {-1, -1} for groups that didn't take part
 }
 
 // SearchManager handles full-text search
 type SearchManager struct {
-	vaultDir string
+	store  *storage.Storage
+	root   string      // Folder searched, the whole vault by default
+	index  *Index      // Narrows down the notes to read, shared by all managers on the vault
+	mode   Mode        // How queries match notes
+	limits RegexLimits // Bound regex searches
 }
 
-// NewSearchManager creates a new search manager
-func NewSearchManager(vaultDir string) *SearchManager {
+// NewSearchManager creates a new search manager for a vault
+func NewSearchManager(store *storage.Storage) *SearchManager {
+	return NewSearchManagerForDir(store, store.VaultDir())
+}
+
+// NewSearchManagerForDir creates a search manager limited to one folder (e.g. a notebook)
+func NewSearchManagerForDir(store *storage.Storage, dir string) *SearchManager {
 	return &SearchManager{
-		vaultDir: vaultDir,
+		store:  store,
+		root:   dir,
+		index:  IndexFor(store),
+		limits: DefaultRegexLimits,
 	}
 }
 
+// SetMode changes how queries match notes, ModeExact by default
+func (sm *SearchManager) SetMode(mode Mode) {
+	sm.mode = mode
+}
+
+// Mode returns how queries match notes
+func (sm *SearchManager) Mode() Mode {
+	return sm.mode
+}
+
+// eachNote calls fn with the path and content of the notes to search
+// With the index only the candidates it returns are read, otherwise every note
+// under the root is; fn returns filepath.SkipAll to stop early
+// It reports whether the index was used
+func (sm *SearchManager) eachNote(candidates func() []string, fn func(path string, content []byte) error) (bool, error) {
+	if sm.index.usable() {
+		for _, path := range candidates() {
+			content, err := sm.store.ReadFile(path)
+			if err != nil {
+				continue // Deleted since it was indexed
+			}
+			if err := fn(path, content); err != nil {
+				if err == filepath.SkipAll {
+					return true, nil
+				}
+				return true, err
+			}
+		}
+		return true, nil
+	}
+
+	err := sm.store.Walk(sm.root, func(path string, info backend.FileInfo) error {
+		// Only search notes
+		if info.IsDir || !storage.IsNoteFile(path) {
+			return nil
+		}
+
+		// Read file content
+		content, err := sm.store.ReadFile(path)
+		if err != nil {
+			return nil // Skip if can't read
+		}
+		return fn(path, content)
+	})
+	if err == filepath.SkipAll {
+		return false, nil
+	}
+	return false, err
+}
+
 // Search performs full-text search across all notes
 // Supports regular text search and tag search (prefix with #)
+// Matches are grouped by note, the most relevant note first
 func (sm *SearchManager) Search(query string) ([]SearchResult, error) {
-	if query == "" {
-		return []SearchResult{}, nil
+	notes, err := sm.SearchNotes(query)
+	if err != nil && notes == nil {
+		return nil, err
 	}
+	return Flatten(notes), err // With a *LimitError, what was found before it
+}
 
-	// Check if this is a tag search (starts with #)
-	isTagSearch := strings.HasPrefix(query, "#")
-	if isTagSearch {
-		// Remove # prefix and search for tag
-		tagName := strings.TrimPrefix(query, "#")
-		return sm.SearchByTag(tagName)
+// SearchNotes searches with a query (see Query for the syntax) and returns the
+// matching notes ranked by relevance (BM25), words in note titles and headings
+// counting extra. Equally relevant notes are listed most recently modified first
+// A malformed query returns a *QueryError
+// In ModeFuzzy words also match with typos, and note names are searched too;
+// in ModeRegex the query is a regular expression instead (see CompileRegex),
+// and a search stopped at its RegexLimits returns what it found with a *LimitError
+func (sm *SearchManager) SearchNotes(query string) ([]NoteResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return []NoteResult{}, nil
+	}
+	if sm.mode == ModeRegex {
+		return sm.searchRegex(query)
+	}
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
 	}
 
-	results := []SearchResult{}
-	query = strings.ToLower(query)
+	m := termMatcher{stem: sm.index.Options().Stem, fuzzy: sm.mode == ModeFuzzy}
+	matchLine := newLineMatcher(q, m)
+	r := newRanker(strings.Join(append(append([]string{}, q.terms...), q.tags...), " "), m)
+	vaultDir := sm.store.VaultDir()
 
-	// Walk through all files in vault
-	err := filepath.Walk(sm.vaultDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
+	type match struct {
+		note   NoteResult
+		freqs  map[string]float64
+		length int
+	}
+	matches := []match{}
+
+	candidates := func() []string { return sm.index.matching(sm.root, q, m) }
+	indexed, err := sm.eachNote(candidates, func(path string, content []byte) error {
+		meta, body, offset := splitFrontMatter(string(content))
+		freqs, length := r.weigh(path, meta, body)
+		r.add(freqs, length)
+
+		rel, _ := filepath.Rel(vaultDir, path)
+		var modified *time.Time
+		doc := &document{
+			path:    path,
+			rel:     filepath.ToSlash(rel),
+			content: strings.ToLower(string(content)),
+			meta:    meta,
+			body:    body,
+			offset:  offset,
+			match:   m,
+			modified: func() time.Time {
+				if modified == nil {
+					t := sm.modTime(path)
+					modified = &t
+				}
+				return *modified
+			},
 		}
-
-		// Only search .md and .txt files
-		if info.IsDir() || (filepath.Ext(path) != ".md" && filepath.Ext(path) != ".txt") {
+		if !q.root.match(doc) {
 			return nil
 		}
 
-		// Read file content
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil // Skip if can't read
+		// The note name when it matched fuzzily, then front matter title, aliases and tags
+		results := []SearchResult{}
+		if m.fuzzy {
+			results = append(results, searchName(path, q)...)
+		}
+		if meta != nil {
+			results = append(results, sm.searchFrontMatter(path, meta, q)...)
 		}
 
-		// Search line by line
-		lines := strings.Split(string(content), "\n")
+		// Then the lines with what was searched for
+		taskLines := make(map[int]bool)
+		if q.tasks {
+			for _, task := range doc.noteTasks() {
+				taskLines[task.Line] = true
+			}
+		}
+		lines := strings.Split(body, "\n")
 		for lineNum, line := range lines {
-			if strings.Contains(strings.ToLower(line), query) {
-				snippet := sm.createSnippet(line, query, 50)
-				results = append(results, SearchResult{
-					NotePath:     path,
-					NoteName:     filepath.Base(path),
-					LineNumber:   lineNum + 1,
-					MatchSnippet: snippet,
-					FullLine:     line,
-				})
-
-				// Limit results per file to avoid overwhelming
-				if len(results) > 100 {
-					return filepath.SkipAll
+			spans, ok := matchLine(line)
+			if !ok && !taskLines[lineNum] {
+				continue
+			}
+			snippet, highlights := sm.createSnippet(line, spans, 50)
+			results = append(results, SearchResult{
+				NotePath:     path,
+				NoteName:     filepath.Base(path),
+				LineNumber:   offset + lineNum + 1,
+				MatchSnippet: snippet,
+				FullLine:     line,
+				Highlights:   highlights,
+				LineMatches:  lineMatches(spans),
+			})
+		}
+
+		// Only filters matched (e.g. format:org), show the note's first line
+		if len(results) == 0 {
+			results = append(results, firstLine(path, lines, offset))
+		}
+
+		note := NoteResult{NotePath: path, NoteName: filepath.Base(path), Modified: doc.modified(), Matches: results}
+		matches = append(matches, match{note: note, freqs: freqs, length: length})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only candidates were read, the index knows about all the notes
+	if indexed {
+		r.corpus = sm.index.corpus(sm.root, r)
+	}
+
+	notes := make([]NoteResult, 0, len(matches))
+	for _, m := range matches {
+		m.note.Score = r.score(m.freqs, m.length)
+		notes = append(notes, m.note)
+	}
+	sortNotes(notes)
+	return notes, nil
+}
+
+// firstLine returns the first line with text in a note as a search result
+func firstLine(path string, lines []string, offset int) SearchResult {
+	for lineNum, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return SearchResult{
+				NotePath:     path,
+				NoteName:     filepath.Base(path),
+				LineNumber:   offset + lineNum + 1,
+				MatchSnippet: strings.TrimSpace(line),
+				FullLine:     line,
+			}
+		}
+	}
+	return SearchResult{NotePath: path, NoteName: filepath.Base(path), LineNumber: offset + 1}
+}
+
+// modTime returns when a note was last modified, zero if it can't be read
+func (sm *SearchManager) modTime(path string) time.Time {
+	info, err := sm.store.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime
+}
+
+// newLineMatcher returns a function matching lines against the words, phrases
+// and tags of a query, which returns where they are in the line, to highlight them
+// With stemming on, a line also matches if it has every word of a term in
+// some form ("meeting notes" matches "noted in the meetings"); in fuzzy mode
+// a line matches if one of its words is like a word of the query
+func newLineMatcher(q *Query, m termMatcher) func(line string) ([]Span, bool) {
+	texts := append([]string{}, q.terms...)
+	for _, tag := range q.tags {
+		texts = append(texts, "#"+tag)
+	}
+
+	var stems [][]string
+	if m.stem {
+		for _, term := range q.terms {
+			termStems := []string{}
+			for _, word := range Tokenize(term) {
+				termStems = append(termStems, Stem(word))
+			}
+			stems = append(stems, termStems)
+		}
+	}
+
+	return func(line string) ([]Span, bool) {
+		lower := strings.ToLower(line)
+		spans := []Span{}
+		for _, text := range texts {
+			spans = append(spans, findSpans(lower, text)...)
+		}
+
+		if len(stems) > 0 || m.fuzzy {
+			words := termSpans(lower)
+			lineStems := make(map[string]bool)
+			for _, word := range words {
+				lineStems[Stem(lower[word.Start:word.End])] = true
+			}
+			for _, word := range words {
+				term := lower[word.Start:word.End]
+				if stemMatches(stems, lineStems, Stem(term)) || (m.fuzzy && fuzzyMatches(q.words, term, m)) {
+					spans = append(spans, word)
 				}
 			}
 		}
 
+		if len(spans) == 0 {
+			return nil, false
+		}
+		if len(lower) != len(line) {
+			return nil, true // Lowercase positions don't fit the line, nothing to highlight
+		}
+		return mergeSpans(spans), true
+	}
+}
+
+// stemMatches checks if a stem belongs to a term whose stems are all in a line
+func stemMatches(stems [][]string, lineStems map[string]bool, stem string) bool {
+	for _, termStems := range stems {
+		if !containsString(termStems, stem) {
+			continue
+		}
+		all := true
+		for _, s := range termStems {
+			if !lineStems[s] {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// fuzzyMatches checks if a word of a note is like any of the query words
+func fuzzyMatches(words []string, term string, m termMatcher) bool {
+	for _, word := range words {
+		if m.matches(term, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// findSpans returns where a text appears in a lowercase line
+func findSpans(lower, text string) []Span {
+	spans := []Span{}
+	if text == "" {
+		return spans
+	}
+	for from := 0; ; {
+		i := strings.Index(lower[from:], text)
+		if i < 0 {
+			return spans
+		}
+		spans = append(spans, Span{from + i, from + i + len(text)})
+		from += i + len(text)
+	}
+}
+
+// lineMatches turns the spans that matched in a line into line matches
+func lineMatches(spans []Span) []LineMatch {
+	matches := []LineMatch{}
+	for _, span := range spans {
+		matches = append(matches, LineMatch{Start: span.Start, End: span.End})
+	}
+	return matches
+}
+
+// lineSpans returns where a text is in a line, ignoring case
+func lineSpans(line, text string) []Span {
+	lower := strings.ToLower(line)
+	if len(lower) != len(line) {
+		return nil // Lowercase positions don't fit the line
+	}
+	return findSpans(lower, strings.ToLower(text))
+}
+
+// mergeSpans sorts spans and joins those that overlap or touch
+func mergeSpans(spans []Span) []Span {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	merged := []Span{}
+	for _, span := range spans {
+		if n := len(merged); n > 0 && span.Start <= merged[n-1].End {
+			if span.End > merged[n-1].End {
+				merged[n-1].End = span.End
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// searchName returns the note name as a result when it matches a word of the
+// query fuzzily
+func searchName(path string, q *Query) []SearchResult {
+	base := filepath.Base(path)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	for _, term := range q.plain {
+		spans, ok := fuzzyName(name, term)
+		if !ok {
+			continue
+		}
+		const label = "name: "
+		for i := range spans {
+			spans[i].Start += len(label)
+			spans[i].End += len(label)
+		}
+		return []SearchResult{{
+			NotePath:     path,
+			NoteName:     base,
+			LineNumber:   0,
+			MatchSnippet: label + base,
+			FullLine:     label + base,
+			Highlights:   spans,
+		}}
+	}
+	return nil
+}
+
+// splitFrontMatter parses front matter and returns the body with its line offset
+func splitFrontMatter(content string) (*models.FrontMatter, string, int) {
+	meta, body, err := models.ParseFrontMatter(content)
+	if err != nil || meta == nil {
+		return nil, content, 0
+	}
+	offset := strings.Count(content[:len(content)-len(body)], "\n")
+	return meta, body, offset
+}
+
+// searchFrontMatter matches the words of a query against front matter title
+// and aliases, and its tags against front matter tags
+func (sm *SearchManager) searchFrontMatter(path string, meta *models.FrontMatter, q *Query) []SearchResult {
+	results := []SearchResult{}
+
+	type field struct {
+		label string
+		value string
+	}
+
+	fields := []field{}
+	if meta.Title != "" {
+		fields = append(fields, field{"title", meta.Title})
+	}
+	for _, alias := range meta.Aliases {
+		fields = append(fields, field{"alias", alias})
+	}
+
+	add := func(line string, highlight []string) {
+		spans := []Span{}
+		for _, text := range highlight {
+			spans = append(spans, lineSpans(line, text)...)
+		}
+		results = append(results, SearchResult{
+			NotePath:     path,
+			NoteName:     filepath.Base(path),
+			LineNumber:   0,
+			MatchSnippet: line,
+			FullLine:     line,
+			Highlights:   mergeSpans(spans),
+		})
+	}
+
+	for _, f := range fields {
+		for _, term := range q.terms {
+			if strings.Contains(strings.ToLower(f.value), term) {
+				add(f.label+": "+f.value, q.terms)
+				break
+			}
+		}
+	}
+	for _, tag := range meta.Tags {
+		if containsString(q.tags, strings.ToLower(tag)) {
+			add("tags: "+strings.Join(meta.Tags, ", "), q.tags)
+			break
+		}
+	}
+
+	return results
+}
+
+// SearchByProperty finds notes whose front matter property matches a value
+// An empty value matches any note that defines the property
+func (sm *SearchManager) SearchByProperty(key, value string) ([]SearchResult, error) {
+	results := []SearchResult{}
+	value = strings.ToLower(value)
+
+	err := sm.store.Walk(sm.root, func(path string, info backend.FileInfo) error {
+		if info.IsDir || !storage.IsNoteFile(path) {
+			return nil
+		}
+
+		content, err := sm.store.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		meta, _, _ := splitFrontMatter(string(content))
+		if meta == nil {
+			return nil
+		}
+
+		propValue, ok := meta.Get(key)
+		if !ok {
+			return nil
+		}
+
+		display := fmt.Sprintf("%v", propValue)
+		if value != "" && !strings.Contains(strings.ToLower(display), value) {
+			return nil
+		}
+
+		results = append(results, SearchResult{
+			NotePath:     path,
+			NoteName:     filepath.Base(path),
+			LineNumber:   0,
+			MatchSnippet: fmt.Sprintf("%s: %s", key, display),
+			FullLine:     fmt.Sprintf("%s: %s", key, display),
+		})
 		return nil
 	})
 
 	return results, err
 }
 
-// createSnippet creates a context snippet around the match
-func (sm *SearchManager) createSnippet(line, query string, maxLen int) string {
-	lowerLine := strings.ToLower(line)
-	lowerQuery := strings.ToLower(query)
+// createSnippet creates a context snippet around the first match in a line
+// and returns it with where the matches are in it, to highlight them
+func (sm *SearchManager) createSnippet(line string, spans []Span, maxLen int) (string, []Span) {
+	cut := cutSnippet(line, spans, maxLen)
+	return cut.text, cut.spans(spans)
+}
+
+// snippet is the part of a line shown for a match
+type snippet struct {
+	text       string
+	start, end int // Part of the line in text
+	shift      int // Added to a position in the line to get its position in text
+}
 
-	idx := strings.Index(lowerLine, lowerQuery)
-	if idx == -1 {
+// cutSnippet cuts a snippet around the first of the spans that matched in a
+// line, or from the line's start without any
+func cutSnippet(line string, spans []Span, maxLen int) snippet {
+	if len(spans) == 0 {
 		// Fallback: return beginning of line
 		if len(line) > maxLen {
-			return line[:maxLen] + "..."
+			end := runeStart(line, maxLen)
+			return snippet{text: line[:end] + "...", end: end}
 		}
-		return line
+		return snippet{text: line, end: len(line)}
 	}
 
 	// Calculate snippet range
-	start := idx - 20
+	first := spans[0]
+	start := first.Start - 20
 	if start < 0 {
 		start = 0
 	}
+	start = runeStart(line, start)
 
-	end := idx + len(query) + 30
+	end := first.End + 30
 	if end > len(line) {
 		end = len(line)
 	}
+	end = runeStart(line, end)
 
-	snippet := line[start:end]
+	cut := snippet{text: line[start:end], start: start, end: end, shift: -start}
 
 	// Add ellipsis if truncated
 	if start > 0 {
-		snippet = "..." + snippet
+		cut.text = "..." + cut.text
+		cut.shift += len("...")
 	}
 	if end < len(line) {
-		snippet = snippet + "..."
+		cut.text += "..."
+	}
+	return cut
+}
+
+// spans maps spans of the line to the snippet, dropping those outside it
+func (s snippet) spans(spans []Span) []Span {
+	mapped := []Span{}
+	for _, span := range spans {
+		if span.End <= s.start || span.Start >= s.end {
+			continue
+		}
+		if span.Start < s.start {
+			span.Start = s.start
+		}
+		if span.End > s.end {
+			span.End = s.end
+		}
+		mapped = append(mapped, Span{span.Start + s.shift, span.End + s.shift})
 	}
+	return mapped
+}
 
-	return snippet
+// runeStart moves a position in a string back to the start of a character
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
 }
 
 // SearchInNote searches within a specific note
@@ -134,7 +618,7 @@ func (sm *SearchManager) SearchInNote(notePath, query string) ([]SearchResult, e
 	results := []SearchResult{}
 	query = strings.ToLower(query)
 
-	content, err := os.ReadFile(notePath)
+	content, err := sm.store.ReadFile(notePath)
 	if err != nil {
 		return results, err
 	}
@@ -142,13 +626,14 @@ func (sm *SearchManager) SearchInNote(notePath, query string) ([]SearchResult, e
 	lines := strings.Split(string(content), "\n")
 	for lineNum, line := range lines {
 		if strings.Contains(strings.ToLower(line), query) {
-			snippet := sm.createSnippet(line, query, 80)
+			snippet, highlights := sm.createSnippet(line, lineSpans(line, query), 80)
 			results = append(results, SearchResult{
 				NotePath:     notePath,
 				NoteName:     filepath.Base(notePath),
 				LineNumber:   lineNum + 1,
 				MatchSnippet: snippet,
 				FullLine:     line,
+				Highlights:   highlights,
 			})
 		}
 	}
@@ -202,25 +687,10 @@ func (sm *SearchManager) SearchByTag(tagName string) ([]SearchResult, error) {
 	tagName = strings.ToLower(tagName)
 	searchPattern := "#" + tagName
 
-	// Walk through all files in vault
-	err := filepath.Walk(sm.vaultDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-
-		// Only search .md and .txt files
-		if info.IsDir() || (filepath.Ext(path) != ".md" && filepath.Ext(path) != ".txt") {
-			return nil
-		}
-
-		// Read file content
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-
+	candidates := func() []string { return sm.index.withTags(sm.root, []string{tagName}) }
+	_, err := sm.eachNote(candidates, func(path string, content []byte) error {
 		// Extract all tags from the note
-		noteTags := tags.ExtractTags(string(content))
+		noteTags := tags.ExtractNoteTags(path, string(content))
 
 		// Check if the tag exists in this note
 		hasTag := false
@@ -232,17 +702,36 @@ func (sm *SearchManager) SearchByTag(tagName string) ([]SearchResult, error) {
 		}
 
 		if hasTag {
+			meta, body, offset := splitFrontMatter(string(content))
+
+			// Tag declared in front matter
+			if meta != nil {
+				for _, tag := range meta.Tags {
+					if tag == tagName {
+						results = append(results, SearchResult{
+							NotePath:     path,
+							NoteName:     filepath.Base(path),
+							LineNumber:   0,
+							MatchSnippet: "tags: " + strings.Join(meta.Tags, ", "),
+							FullLine:     "tags: " + strings.Join(meta.Tags, ", "),
+						})
+						break
+					}
+				}
+			}
+
 			// Find all lines containing the tag
-			lines := strings.Split(string(content), "\n")
+			lines := strings.Split(body, "\n")
 			for lineNum, line := range lines {
 				if strings.Contains(strings.ToLower(line), searchPattern) {
-					snippet := sm.createSnippet(line, searchPattern, 60)
+					snippet, highlights := sm.createSnippet(line, lineSpans(line, searchPattern), 60)
 					results = append(results, SearchResult{
 						NotePath:     path,
 						NoteName:     filepath.Base(path),
-						LineNumber:   lineNum + 1,
+						LineNumber:   offset + lineNum + 1,
 						MatchSnippet: snippet,
 						FullLine:     line,
+						Highlights:   highlights,
 					})
 				}
 			}
@@ -267,26 +756,40 @@ func (sm *SearchManager) SearchByMultipleTags(tagNames []string) ([]SearchResult
 	}
 
 	results := []SearchResult{}
+	tagsDisplay := strings.Join(normalizedTags, ", #")
+	addResult := func(path string) {
+		results = append(results, SearchResult{
+			NotePath:     path,
+			NoteName:     filepath.Base(path),
+			LineNumber:   0,
+			MatchSnippet: fmt.Sprintf("Contains tags: #%s", tagsDisplay),
+			FullLine:     fmt.Sprintf("Note contains all specified tags: #%s", tagsDisplay),
+		})
+	}
 
-	// Walk through all files in vault
-	err := filepath.Walk(sm.vaultDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+	// The index knows the tags of every note, no need to read them
+	if sm.index.usable() {
+		for _, path := range sm.index.withTags(sm.root, normalizedTags) {
+			addResult(path)
 		}
+		return results, nil
+	}
 
-		// Only search .md and .txt files
-		if info.IsDir() || (filepath.Ext(path) != ".md" && filepath.Ext(path) != ".txt") {
+	// Walk through all files in vault
+	err := sm.store.Walk(sm.root, func(path string, info backend.FileInfo) error {
+		// Only search notes
+		if info.IsDir || !storage.IsNoteFile(path) {
 			return nil
 		}
 
 		// Read file content
-		content, err := os.ReadFile(path)
+		content, err := sm.store.ReadFile(path)
 		if err != nil {
 			return nil
 		}
 
 		// Extract all tags from the note
-		noteTags := tags.ExtractTags(string(content))
+		noteTags := tags.ExtractNoteTags(path, string(content))
 
 		// Check if all required tags exist in this note
 		hasAllTags := true
@@ -306,15 +809,7 @@ func (sm *SearchManager) SearchByMultipleTags(tagNames []string) ([]SearchResult
 
 		if hasAllTags {
 			// Add a result showing the note has all tags
-			tagsDisplay := strings.Join(normalizedTags, ", #")
-			result := SearchResult{
-				NotePath:     path,
-				NoteName:     filepath.Base(path),
-				LineNumber:   0,
-				MatchSnippet: fmt.Sprintf("Contains tags: #%s", tagsDisplay),
-				FullLine:     fmt.Sprintf("Note contains all specified tags: #%s", tagsDisplay),
-			}
-			results = append(results, result)
+			addResult(path)
 		}
 
 		return nil
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenize splits text into lowercase terms: runs of letters and digits in any
+// script. Scripts written without spaces between words (Chinese, Japanese, Thai)
+// give one term per character, so any part of a word can be searched for
+func Tokenize(text string) []string {
+	text = strings.ToLower(text)
+	terms := []string{}
+	for _, span := range termSpans(text) {
+		terms = append(terms, text[span.Start:span.End])
+	}
+	return terms
+}
+
+// termSpans returns where the terms of a text are, as Tokenize splits it
+func termSpans(text string) []Span {
+	spans := []Span{}
+	start := -1
+	for i, r := range text {
+		switch {
+		case isSingleRuneTerm(r):
+			if start >= 0 {
+				spans = append(spans, Span{start, i})
+				start = -1
+			}
+			spans = append(spans, Span{i, i + utf8.RuneLen(r)})
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			if start >= 0 {
+				spans = append(spans, Span{start, i})
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, Span{start, len(text)})
+	}
+	return spans
+}
+
+// isSingleRuneTerm checks if a character is a term on its own
+func isSingleRuneTerm(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai)
+}
+
+// Stem reduces an English word to a common form by removing inflections,
+// so "notes", "noting" and "noted" all become "note"
+// It is a light stemmer: words it doesn't recognize are returned unchanged
+func Stem(term string) string {
+	if len(term) <= 3 || !isASCIIWord(term) {
+		return term
+	}
+
+	switch {
+	case strings.HasSuffix(term, "sses"):
+		term = term[:len(term)-2]
+	case strings.HasSuffix(term, "ies") && len(term) > 4:
+		term = term[:len(term)-3] + "y"
+	case strings.HasSuffix(term, "ss"), strings.HasSuffix(term, "us"), strings.HasSuffix(term, "is"):
+		// "class", "status", "analysis" aren't plurals
+	case strings.HasSuffix(term, "s"):
+		term = term[:len(term)-1]
+	}
+
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed", "ly"} {
+		stem := strings.TrimSuffix(term, suffix)
+		if stem == term || len(stem) < 3 || !hasVowel(stem) {
+			continue
+		}
+		term = stem
+		switch {
+		case len(term) > 3 && term[len(term)-1] == term[len(term)-2] && !strings.ContainsRune("lsz", rune(term[len(term)-1])):
+			term = term[:len(term)-1] // "planned" -> "plan"
+		case strings.HasSuffix(term, "at"), strings.HasSuffix(term, "bl"), strings.HasSuffix(term, "iz"), strings.HasSuffix(term, "ot"):
+			term += "e" // "created" -> "create", "noted" -> "note"
+		}
+		break
+	}
+
+	return term
+}
+
+// isASCIIWord checks if a term is made of ASCII letters only
+func isASCIIWord(term string) bool {
+	for i := 0; i < len(term); i++ {
+		if term[i] < 'a' || term[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// hasVowel checks if a word part contains a vowel, so "sing" isn't stemmed to "s"
+func hasVowel(s string) bool {
+	return strings.ContainsAny(s, "aeiouy")
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 	"unicode"
+
+	"github.com/0xshariq/totion/internal/storage/lock"
 )
 
 // Statistics holds note statistics
@@ -89,7 +91,7 @@ func (sm *StatsManager) loadHistory() error {
 	return json.Unmarshal(data, &sm.history)
 }
 
-// saveHistory saves statistics history to disk
+// saveHistory saves statistics history to disk; its lock must be held
 func (sm *StatsManager) saveHistory() error {
 	if sm.configPath == "" {
 		return nil
@@ -140,8 +142,18 @@ func (sm *StatsManager) RecordStats(date time.Time, wordCount, noteCount int) {
 		NoteCount: noteCount,
 	}
 
-	sm.history[dateStr] = append(sm.history[dateStr], entry)
-	sm.saveHistory()
+	// Another totion on the same vault may be recording its stats too: reload
+	// the history and save it while holding the lock so neither update is lost
+	if sm.configPath == "" {
+		sm.history[dateStr] = append(sm.history[dateStr], entry)
+		return
+	}
+	lock.Do(sm.configPath, lock.DefaultTimeout, func() error {
+		sm.history = make(map[string][]StatEntry)
+		sm.loadHistory() // A corrupt history is replaced
+		sm.history[dateStr] = append(sm.history[dateStr], entry)
+		return sm.saveHistory()
+	})
 }
 
 // GetStreak calculates the current writing streak
@@ -2,24 +2,29 @@ package sync
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
+	"github.com/0xshariq/totion/internal/storage/lock"
 )
 
 // SyncManager handles cloud sync operations
+// The vault is read and written through its storage backend; the sync folder
+// and backups are plain folders on disk
 type SyncManager struct {
-	vaultDir string
-	syncDir  string
-	enabled  bool
+	store   *storage.Storage
+	syncDir string
+	enabled bool
 }
 
 // NewSyncManager creates a new sync manager
-func NewSyncManager(vaultDir, syncDir string) *SyncManager {
+func NewSyncManager(store *storage.Storage, syncDir string) *SyncManager {
 	return &SyncManager{
-		vaultDir: vaultDir,
-		syncDir:  syncDir,
-		enabled:  false,
+		store:   store,
+		syncDir: syncDir,
+		enabled: false,
 	}
 }
 
@@ -48,28 +53,7 @@ func (sm *SyncManager) SyncToCloud() error {
 		return fmt.Errorf("error creating sync directory: %w", err)
 	}
 
-	return filepath.Walk(sm.vaultDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() {
-			return nil
-		}
-
-		relPath, err := filepath.Rel(sm.vaultDir, path)
-		if err != nil {
-			return err
-		}
-
-		destPath := filepath.Join(sm.syncDir, relPath)
-
-		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
-			return err
-		}
-
-		return copyFile(path, destPath)
-	})
+	return sm.copyVaultTo(sm.syncDir)
 }
 
 // SyncFromCloud syncs cloud storage to local notes
@@ -78,59 +62,53 @@ func (sm *SyncManager) SyncFromCloud() error {
 		return fmt.Errorf("sync is not enabled")
 	}
 
-	return filepath.Walk(sm.syncDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return sm.copyIntoVault(sm.syncDir)
+}
 
-		if info.IsDir() {
-			return nil
-		}
+// BackupVault creates a backup of the entire vault
+// Ignored folders such as .git and .trash are not backed up
+func (sm *SyncManager) BackupVault(backupPath string) error {
+	return sm.copyVaultTo(backupPath)
+}
 
-		relPath, err := filepath.Rel(sm.syncDir, path)
-		if err != nil {
-			return err
-		}
+// RestoreVault restores vault from backup
+func (sm *SyncManager) RestoreVault(backupPath string) error {
+	return sm.copyIntoVault(backupPath)
+}
 
-		destPath := filepath.Join(sm.vaultDir, relPath)
+// copyVaultTo copies every file in the vault into a folder on disk
+func (sm *SyncManager) copyVaultTo(dir string) error {
+	vaultDir := sm.store.VaultDir()
 
-		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
-			return err
+	return sm.store.Walk(vaultDir, func(path string, info backend.FileInfo) error {
+		// Locks belong to running processes, a backup must not bring them back
+		if info.IsDir || lock.IsLockFile(info.Name()) {
+			return nil
 		}
 
-		return copyFile(path, destPath)
-	})
-}
-
-// BackupVault creates a backup of the entire vault
-func (sm *SyncManager) BackupVault(backupPath string) error {
-	return filepath.Walk(sm.vaultDir, func(path string, info os.FileInfo, err error) error {
+		relPath, err := filepath.Rel(vaultDir, path)
 		if err != nil {
 			return err
 		}
 
-		if info.IsDir() {
-			return nil
-		}
-
-		relPath, err := filepath.Rel(sm.vaultDir, path)
+		data, err := sm.store.ReadFile(path)
 		if err != nil {
 			return err
 		}
 
-		destPath := filepath.Join(backupPath, relPath)
+		destPath := filepath.Join(dir, relPath)
 
 		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
 			return err
 		}
 
-		return copyFile(path, destPath)
+		return os.WriteFile(destPath, data, 0644)
 	})
 }
 
-// RestoreVault restores vault from backup
-func (sm *SyncManager) RestoreVault(backupPath string) error {
-	return filepath.Walk(backupPath, func(path string, info os.FileInfo, err error) error {
+// copyIntoVault copies every file in a folder on disk into the vault
+func (sm *SyncManager) copyIntoVault(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -139,34 +117,16 @@ func (sm *SyncManager) RestoreVault(backupPath string) error {
 			return nil
 		}
 
-		relPath, err := filepath.Rel(backupPath, path)
+		relPath, err := filepath.Rel(dir, path)
 		if err != nil {
 			return err
 		}
 
-		destPath := filepath.Join(sm.vaultDir, relPath)
-
-		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
+		data, err := os.ReadFile(path)
+		if err != nil {
 			return err
 		}
 
-		return copyFile(path, destPath)
+		return sm.store.WriteFile(filepath.Join(sm.store.VaultDir(), relPath), data)
 	})
 }
-
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer sourceFile.Close()
-
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, sourceFile)
-	return err
-}
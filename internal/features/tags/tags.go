@@ -3,31 +3,35 @@ package tags
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
 )
 
 // TagInfo represents a tag with its associated notes
 type TagInfo struct {
 	Tag   string   `json:"tag"`
-	Notes []string `json:"notes"` // List of note paths
+	Notes []string `json:"notes"` // List of note paths (stored on disk as note IDs)
 	Count int      `json:"count"`
 }
 
 // TagManager handles tag extraction and indexing
 type TagManager struct {
-	vaultDir  string
+	store     *storage.Storage
 	indexPath string
 	tags      map[string]*TagInfo // tag -> TagInfo
 }
 
-// NewTagManager creates a new tag manager
-func NewTagManager(vaultDir, configDir string) *TagManager {
+// NewTagManager creates a new tag manager for a vault
+// The index is kept in configDir, usually the vault itself
+func NewTagManager(store *storage.Storage, configDir string) *TagManager {
 	tm := &TagManager{
-		vaultDir:  vaultDir,
+		store:     store,
 		indexPath: filepath.Join(configDir, ".tags.json"),
 		tags:      make(map[string]*TagInfo),
 	}
@@ -35,15 +39,28 @@ func NewTagManager(vaultDir, configDir string) *TagManager {
 	return tm
 }
 
-// ExtractTags extracts all #tags from content
+// ExtractTags extracts all #tags from content, including front matter tags
 func ExtractTags(content string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+
+	// Tags declared in YAML front matter come first
+	meta, body, err := models.ParseFrontMatter(content)
+	if err == nil && meta != nil {
+		for _, tag := range meta.Tags {
+			tag = strings.ToLower(tag)
+			if tag != "" && !seen[tag] {
+				tags = append(tags, tag)
+				seen[tag] = true
+			}
+		}
+		content = body
+	}
+
 	// Match #word (but not ##heading or #123)
 	tagPattern := regexp.MustCompile(`(?:^|[^\w#])#([a-zA-Z][a-zA-Z0-9_-]*)\b`)
 	matches := tagPattern.FindAllStringSubmatch(content, -1)
 	
-	tags := []string{}
-	seen := make(map[string]bool)
-	
 	for _, match := range matches {
 		if len(match) > 1 {
 			tag := strings.ToLower(match[1])
@@ -57,37 +74,84 @@ func ExtractTags(content string) []string {
 	return tags
 }
 
+// orgHeadlineTags matches the tag list at the end of an Org headline, e.g. "* Plan   :work:q3:"
+var orgHeadlineTags = regexp.MustCompile(`(?m)^\*+\s.*?\s(:(?:[\w@#%]+:)+)[ \t]*$`)
+
+// orgFileTags matches "#+FILETAGS: :work:q3:" (or space-separated tags)
+var orgFileTags = regexp.MustCompile(`(?mi)^#\+FILETAGS:[ \t]*(.+)$`)
+
+// adocHighlight matches AsciiDoc #highlighted# text, which isn't a tag
+var adocHighlight = regexp.MustCompile(`#[^#\s](?:[^#\n]*[^#\s])?#`)
+
+// ExtractTagsForFormat extracts tags using the tag syntax of a note's format
+// Org notes also have headline tags (:tag:) and #+FILETAGS; #tags work in every format
+func ExtractTagsForFormat(content string, format models.FileFormat) []string {
+	switch format {
+	case models.FormatOrg:
+		tags := []string{}
+		seen := make(map[string]bool)
+		add := func(tag string) {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag != "" && !seen[tag] {
+				tags = append(tags, tag)
+				seen[tag] = true
+			}
+		}
+
+		for _, match := range orgFileTags.FindAllStringSubmatch(content, -1) {
+			for _, tag := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ':' || r == ' ' || r == '\t' }) {
+				add(tag)
+			}
+		}
+		for _, match := range orgHeadlineTags.FindAllStringSubmatch(content, -1) {
+			for _, tag := range strings.Split(match[1], ":") {
+				add(tag)
+			}
+		}
+		for _, tag := range ExtractTags(content) {
+			add(tag)
+		}
+		return tags
+
+	case models.FormatAsciiDoc:
+		return ExtractTags(adocHighlight.ReplaceAllString(content, ""))
+	}
+
+	return ExtractTags(content)
+}
+
+// ExtractNoteTags extracts the tags of a note file, picking the syntax from its extension
+func ExtractNoteTags(notePath, content string) []string {
+	return ExtractTagsForFormat(content, models.FormatForPath(notePath))
+}
+
 // RebuildIndex rebuilds the entire tag index by scanning all notes
 func (tm *TagManager) RebuildIndex() error {
-	tm.tags = make(map[string]*TagInfo)
+	index := make(map[string]*TagInfo)
 	
-	err := filepath.Walk(tm.vaultDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		
-		// Only index .md and .txt files
-		if info.IsDir() || (filepath.Ext(path) != ".md" && filepath.Ext(path) != ".txt") {
+	err := tm.store.Walk(tm.store.VaultDir(), func(path string, info backend.FileInfo) error {
+		// Only index notes
+		if info.IsDir || !storage.IsNoteFile(path) {
 			return nil
 		}
 		
 		// Read and extract tags
-		content, err := os.ReadFile(path)
+		content, err := tm.store.ReadFile(path)
 		if err != nil {
 			return nil
 		}
 		
-		tags := ExtractTags(string(content))
+		tags := ExtractNoteTags(path, string(content))
 		for _, tag := range tags {
-			if _, exists := tm.tags[tag]; !exists {
-				tm.tags[tag] = &TagInfo{
+			if _, exists := index[tag]; !exists {
+				index[tag] = &TagInfo{
 					Tag:   tag,
 					Notes: []string{},
 					Count: 0,
 				}
 			}
-			tm.tags[tag].Notes = append(tm.tags[tag].Notes, path)
-			tm.tags[tag].Count++
+			index[tag].Notes = append(index[tag].Notes, path)
+			index[tag].Count++
 		}
 		
 		return nil
@@ -97,7 +161,10 @@ func (tm *TagManager) RebuildIndex() error {
 		return err
 	}
 	
-	return tm.saveIndex()
+	return tm.update(func() bool {
+		tm.tags = index
+		return true
+	})
 }
 
 // GetNotesByTag returns all notes containing a specific tag
@@ -126,48 +193,91 @@ func (tm *TagManager) GetAllTags() []*TagInfo {
 
 // GetTagsForNote returns all tags in a specific note
 func (tm *TagManager) GetTagsForNote(notePath string) ([]string, error) {
-	content, err := os.ReadFile(notePath)
+	content, err := tm.store.ReadFile(notePath)
 	if err != nil {
 		return nil, err
 	}
 	
-	return ExtractTags(string(content)), nil
+	return ExtractNoteTags(notePath, string(content)), nil
 }
 
 // IndexNote updates the index for a specific note
 func (tm *TagManager) IndexNote(notePath string) error {
-	// Remove this note from all existing tags
-	for _, info := range tm.tags {
-		newNotes := []string{}
-		for _, note := range info.Notes {
-			if note != notePath {
-				newNotes = append(newNotes, note)
-			}
-		}
-		info.Notes = newNotes
-		info.Count = len(newNotes)
-	}
-	
-	// Re-index the note
-	content, err := os.ReadFile(notePath)
+	content, err := tm.store.ReadFile(notePath)
 	if err != nil {
 		return err
 	}
+	tags := ExtractNoteTags(notePath, string(content))
 	
-	tags := ExtractTags(string(content))
-	for _, tag := range tags {
-		if _, exists := tm.tags[tag]; !exists {
-			tm.tags[tag] = &TagInfo{
-				Tag:   tag,
-				Notes: []string{},
-				Count: 0,
+	return tm.update(func() bool {
+		// Remove this note from all existing tags
+		for _, info := range tm.tags {
+			newNotes := []string{}
+			for _, note := range info.Notes {
+				if note != notePath {
+					newNotes = append(newNotes, note)
+				}
 			}
+			info.Notes = newNotes
+			info.Count = len(newNotes)
 		}
-		tm.tags[tag].Notes = append(tm.tags[tag].Notes, notePath)
-		tm.tags[tag].Count++
-	}
-	
-	return tm.saveIndex()
+		
+		// Re-index the note
+		for _, tag := range tags {
+			if _, exists := tm.tags[tag]; !exists {
+				tm.tags[tag] = &TagInfo{
+					Tag:   tag,
+					Notes: []string{},
+					Count: 0,
+				}
+			}
+			tm.tags[tag].Notes = append(tm.tags[tag].Notes, notePath)
+			tm.tags[tag].Count++
+		}
+		return true
+	})
+}
+
+// RenameNote moves index entries from oldPath to newPath without re-reading the note
+// Returns the number of tags that referenced the note
+func (tm *TagManager) RenameNote(oldPath, newPath string) (int, error) {
+	updated := 0
+	err := tm.update(func() bool {
+		for _, info := range tm.tags {
+			for i, note := range info.Notes {
+				if note == oldPath {
+					info.Notes[i] = newPath
+					updated++
+				}
+			}
+		}
+		return updated > 0
+	})
+	return updated, err
+}
+
+// RemoveNote drops a deleted note from the index
+// Removing a notebook path drops every note inside it
+// Returns the number of tags that referenced the removed notes
+func (tm *TagManager) RemoveNote(path string) (int, error) {
+	prefix := path + string(filepath.Separator)
+	removed := 0
+	err := tm.update(func() bool {
+		for _, info := range tm.tags {
+			kept := info.Notes[:0]
+			for _, note := range info.Notes {
+				if note == path || strings.HasPrefix(note, prefix) {
+					removed++
+					continue
+				}
+				kept = append(kept, note)
+			}
+			info.Notes = kept
+			info.Count = len(kept)
+		}
+		return removed > 0
+	})
+	return removed, err
 }
 
 // FormatTagCloud formats tags for display
@@ -222,7 +332,7 @@ func FormatTagsForNote(tags []string) string {
 
 // loadIndex loads the tag index from disk
 func (tm *TagManager) loadIndex() error {
-	data, err := os.ReadFile(tm.indexPath)
+	data, err := tm.store.ReadFile(tm.indexPath)
 	if err != nil {
 		// File doesn't exist yet, that's okay
 		return nil
@@ -233,21 +343,42 @@ func (tm *TagManager) loadIndex() error {
 		return err
 	}
 	
-	// Convert to map
+	// Convert to map, resolving note IDs to where the notes are now
 	for _, info := range tagList {
+		for i, ref := range info.Notes {
+			info.Notes[i] = tm.notePath(ref)
+		}
 		tm.tags[info.Tag] = info
 	}
 	
 	return nil
 }
 
-// saveIndex saves the tag index to disk
+// update reloads the index, applies a change and saves it, all while holding
+// the index's lock, so changes another totion on the vault made meanwhile
+// aren't lost; fn reports whether it changed anything
+func (tm *TagManager) update(fn func() bool) error {
+	return tm.store.WithLock(tm.indexPath, func() error {
+		tm.tags = make(map[string]*TagInfo)
+		tm.loadIndex() // A corrupt index is replaced
+		if !fn() {
+			return nil
+		}
+		return tm.saveIndex()
+	})
+}
+
+// saveIndex saves the tag index to disk; the index's lock must be held
 func (tm *TagManager) saveIndex() error {
 	// Convert map to list
 	tagList := make([]*TagInfo, 0, len(tm.tags))
 	for _, info := range tm.tags {
 		if info.Count > 0 { // Only save tags with notes
-			tagList = append(tagList, info)
+			refs := make([]string, len(info.Notes))
+			for i, path := range info.Notes {
+				refs[i] = tm.noteRef(path)
+			}
+			tagList = append(tagList, &TagInfo{Tag: info.Tag, Notes: refs, Count: info.Count})
 		}
 	}
 	
@@ -256,7 +387,28 @@ func (tm *TagManager) saveIndex() error {
 		return err
 	}
 	
-	return os.WriteFile(tm.indexPath, data, 0644)
+	return tm.store.WriteFile(tm.indexPath, data)
+}
+
+// noteRef returns how a note is stored in the index: its ID, so the index
+// survives moves, or its path if it has no ID yet
+func (tm *TagManager) noteRef(path string) string {
+	if id := tm.store.IDs().ID(path); id != "" {
+		return id
+	}
+	return path
+}
+
+// notePath returns the current path of a note stored in the index
+// Paths from older indexes are kept as they are
+func (tm *TagManager) notePath(ref string) string {
+	if filepath.IsAbs(ref) {
+		return ref
+	}
+	if path, ok := tm.store.IDs().Path(ref); ok {
+		return path
+	}
+	return ref
 }
 
 // GetTagCount returns the total number of unique tags
@@ -3,6 +3,8 @@ package tasks
 import (
 	"regexp"
 	"strings"
+
+	"github.com/0xshariq/totion/internal/models"
 )
 
 // Task represents a single task/checkbox
@@ -44,6 +46,38 @@ func (tm *TaskManager) ParseTasks(content string) []Task {
 	return tasks
 }
 
+// orgTodoRegex matches Org headlines with a TODO keyword: * TODO text or ** DONE text :tags:
+var orgTodoRegex = regexp.MustCompile(`^(\*+)\s+(TODO|DONE)\s+(.*?)(?:\s+:(?:[\w@#%]+:)+)?\s*$`)
+
+// ParseTasksForFormat extracts tasks using the task syntax of a note format
+// Org notes have TODO/DONE headlines besides checkboxes; other formats use checkboxes
+func (tm *TaskManager) ParseTasksForFormat(content string, format models.FileFormat) []Task {
+	if format != models.FormatOrg {
+		return tm.ParseTasks(content)
+	}
+
+	tasks := []Task{}
+	checkboxes := tm.ParseTasks(content)
+	next := 0
+
+	for i, line := range strings.Split(content, "\n") {
+		// Keep tasks in line order
+		for next < len(checkboxes) && checkboxes[next].Line < i {
+			tasks = append(tasks, checkboxes[next])
+			next++
+		}
+
+		if matches := orgTodoRegex.FindStringSubmatch(line); matches != nil {
+			tasks = append(tasks, Task{
+				Text:      matches[3],
+				Completed: matches[2] == "DONE",
+				Line:      i,
+			})
+		}
+	}
+	return append(tasks, checkboxes[next:]...)
+}
+
 // ToggleTask toggles a task's completion status
 func (tm *TaskManager) ToggleTask(content string, lineNum int) string {
 	lines := strings.Split(content, "\n")
@@ -54,6 +88,16 @@ func (tm *TaskManager) ToggleTask(content string, lineNum int) string {
 
 	line := lines[lineNum]
 
+	// Toggle Org TODO to DONE and back
+	if matches := orgTodoRegex.FindStringSubmatch(line); matches != nil {
+		keyword, replacement := "TODO", "DONE"
+		if matches[2] == "DONE" {
+			keyword, replacement = "DONE", "TODO"
+		}
+		lines[lineNum] = strings.Replace(line, keyword, replacement, 1)
+		return strings.Join(lines, "\n")
+	}
+
 	// Toggle [ ] to [x] or [x] to [ ]
 	if strings.Contains(line, "[ ]") {
 		lines[lineNum] = strings.Replace(line, "[ ]", "[x]", 1)
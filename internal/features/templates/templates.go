@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/0xshariq/totion/internal/models"
 )
 
 // Template represents a note template
@@ -13,6 +15,32 @@ type Template struct {
 	Name    string
 	Content string
 	Icon    string
+	Tags    []string // Default front matter tags for notes created from this template
+}
+
+// Render returns the template content with front matter for a new note
+// Front matter already present in the template is kept and completed
+func (t Template) Render(title string) string {
+	meta, body, err := models.ParseFrontMatter(t.Content)
+	if err != nil || meta == nil {
+		meta = models.NewFrontMatter(title)
+		body = t.Content
+	} else {
+		now := time.Now()
+		if meta.Title == "" {
+			meta.Title = title
+		}
+		if meta.Created.IsZero() {
+			meta.Created = now
+		}
+		meta.Updated = now
+	}
+
+	if len(meta.Tags) == 0 {
+		meta.Tags = append([]string{}, t.Tags...)
+	}
+
+	return meta.Render() + body
 }
 
 // TemplateManager handles note templates
@@ -22,9 +50,8 @@ type TemplateManager struct {
 }
 
 // NewTemplateManager creates a new template manager
-func NewTemplateManager() *TemplateManager {
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".totion", ".custom_templates.json")
+func NewTemplateManager(configDir string) *TemplateManager {
+	configPath := filepath.Join(configDir, ".custom_templates.json")
 
 	tm := &TemplateManager{
 		configPath: configPath,
@@ -39,6 +66,7 @@ func (tm *TemplateManager) initializeDefaultTemplates() {
 	tm.templates = []Template{
 		{
 			Name: "Meeting Notes",
+			Tags: []string{"meeting"},
 			Icon: "📋",
 			Content: fmt.Sprintf(`# Meeting Notes - %s
 
@@ -63,6 +91,7 @@ Date: %s
 		},
 		{
 			Name: "Todo List",
+			Tags: []string{"todo"},
 			Icon: "✅",
 			Content: `# Todo List
 
@@ -80,6 +109,7 @@ Created: ` + time.Now().Format("2006-01-02 15:04"),
 		},
 		{
 			Name: "Journal Entry",
+			Tags: []string{"journal"},
 			Icon: "📔",
 			Content: fmt.Sprintf(`# Journal Entry - %s
 
@@ -103,6 +133,7 @@ Created: ` + time.Now().Format("2006-01-02 15:04"),
 		},
 		{
 			Name: "Project Plan",
+			Tags: []string{"project"},
 			Icon: "🚀",
 			Content: `# Project Plan
 
@@ -129,11 +160,13 @@ Created: ` + time.Now().Format("2006-01-02"),
 		},
 		{
 			Name:    "Code Snippet",
+			Tags:    []string{"snippet"},
 			Icon:    "💻",
 			Content: "# Code Snippet\n\n## Description\n\n\n## Language\n\n\n## Code\n```\n\n```\n\n## Notes\n\n",
 		},
 		{
 			Name: "Book Notes",
+			Tags: []string{"book"},
 			Icon: "📚",
 			Content: `# Book Notes
 
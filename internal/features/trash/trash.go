@@ -0,0 +1,310 @@
+package trash
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xshariq/totion/internal/storage/backend"
+	"github.com/0xshariq/totion/internal/storage/lock"
+)
+
+// DirName is the trash directory inside the vault
+const DirName = ".trash"
+
+// TrashedItem is a note or notebook moved to the trash
+type TrashedItem struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"original_path"`
+	RelPath      string    `json:"rel_path"` // Original path relative to the vault
+	IsDir        bool      `json:"is_dir"`
+	DeletedAt    time.Time `json:"deleted_at"`
+}
+
+// TrashManager moves deleted notes and notebooks into <vault>/.trash
+// Each item is stored as .trash/<id>/<name>, described by .trash/index.json
+type TrashManager struct {
+	vaultDir  string
+	backend   backend.Backend
+	indexPath string // Vault-relative
+}
+
+// NewTrashManager creates a new trash manager for a vault on the filesystem
+func NewTrashManager(vaultDir string) *TrashManager {
+	return NewTrashManagerWithBackend(vaultDir, backend.NewFS(vaultDir))
+}
+
+// NewTrashManagerWithBackend creates a trash manager for a vault kept in a storage backend
+func NewTrashManagerWithBackend(vaultDir string, b backend.Backend) *TrashManager {
+	return &TrashManager{
+		vaultDir:  vaultDir,
+		backend:   b,
+		indexPath: DirName + "/index.json",
+	}
+}
+
+// load reads the trash index
+func (tm *TrashManager) load() ([]TrashedItem, error) {
+	items := []TrashedItem{}
+
+	data, err := tm.backend.Read(tm.indexPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return items, nil
+		}
+		return nil, fmt.Errorf("error reading trash index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("error parsing trash index: %w", err)
+	}
+	return items, nil
+}
+
+// save writes the trash index
+func (tm *TrashManager) save(items []TrashedItem) error {
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := tm.backend.Write(tm.indexPath, data); err != nil {
+		return fmt.Errorf("error writing trash index: %w", err)
+	}
+	return nil
+}
+
+// update runs a read-modify-write of the index while holding its lock, so two
+// totion processes, or goroutines such as API requests, don't lose each other's items
+func (tm *TrashManager) update(fn func(items []TrashedItem) ([]TrashedItem, error)) error {
+	apply := func() error {
+		items, err := tm.load()
+		if err != nil {
+			return err
+		}
+		items, err = fn(items)
+		if items == nil {
+			return err
+		}
+		if saveErr := tm.save(items); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+
+	if fsb, ok := tm.backend.(*backend.FS); ok {
+		// The lock file sits next to the index
+		if err := fsb.Mkdir(DirName); err != nil {
+			return fmt.Errorf("error creating trash directory: %w", err)
+		}
+		return lock.Do(fsb.Abs(tm.indexPath), lock.DefaultTimeout, apply)
+	}
+	return lock.DoLocal(filepath.Join(tm.vaultDir, filepath.FromSlash(tm.indexPath)), lock.DefaultTimeout, apply)
+}
+
+// Trash moves a note or notebook into the trash
+func (tm *TrashManager) Trash(itemPath string) (TrashedItem, error) {
+	absPath, err := filepath.Abs(itemPath)
+	if err != nil {
+		return TrashedItem{}, fmt.Errorf("error moving to trash: %w", err)
+	}
+	rel, err := filepath.Rel(tm.vaultDir, absPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return TrashedItem{}, fmt.Errorf("error moving to trash: %s is not inside the vault", filepath.Base(itemPath))
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == DirName || strings.HasPrefix(rel, DirName+"/") {
+		return TrashedItem{}, fmt.Errorf("%s is already in the trash", filepath.Base(itemPath))
+	}
+
+	info, err := tm.backend.Stat(rel)
+	if err != nil {
+		return TrashedItem{}, fmt.Errorf("error moving to trash: %w", err)
+	}
+
+	now := time.Now()
+	item := TrashedItem{
+		ID:           tm.newID(now),
+		Name:         info.Name(),
+		OriginalPath: absPath,
+		RelPath:      rel,
+		IsDir:        info.IsDir,
+		DeletedAt:    now,
+	}
+
+	moved := false
+	err = tm.update(func(items []TrashedItem) ([]TrashedItem, error) {
+		itemDir := path.Join(DirName, item.ID)
+		if err := tm.backend.Mkdir(itemDir); err != nil {
+			return nil, fmt.Errorf("error creating trash directory: %w", err)
+		}
+
+		if err := tm.backend.Move(rel, path.Join(itemDir, item.Name)); err != nil {
+			tm.backend.Delete(itemDir)
+			return nil, fmt.Errorf("error moving to trash: %w", err)
+		}
+		moved = true
+
+		return append(items, item), nil
+	})
+	if !moved {
+		return TrashedItem{}, err
+	}
+	return item, err
+}
+
+// List returns trashed items, most recently deleted first
+func (tm *TrashManager) List() ([]TrashedItem, error) {
+	items, err := tm.load()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// Restore moves an item back to its original location
+// If that path is taken, the item is restored next to it with a " (restored)" suffix
+func (tm *TrashManager) Restore(id string) (string, error) {
+	restored := ""
+	err := tm.update(func(items []TrashedItem) ([]TrashedItem, error) {
+		index := findItem(items, id)
+		if index < 0 {
+			return nil, fmt.Errorf("item not found in trash: %s", id)
+		}
+		item := items[index]
+
+		target := item.RelPath
+		if _, err := tm.backend.Stat(target); err == nil {
+			target = tm.restoredPath(target, item.IsDir)
+		}
+
+		if err := tm.backend.Move(path.Join(DirName, item.ID, item.Name), target); err != nil {
+			return nil, fmt.Errorf("error restoring from trash: %w", err)
+		}
+		tm.backend.Delete(path.Join(DirName, item.ID))
+
+		restored = filepath.Join(tm.vaultDir, filepath.FromSlash(target))
+		return append(items[:index], items[index+1:]...), nil
+	})
+	return restored, err
+}
+
+// Purge permanently deletes an item from the trash
+func (tm *TrashManager) Purge(id string) error {
+	return tm.update(func(items []TrashedItem) ([]TrashedItem, error) {
+		index := findItem(items, id)
+		if index < 0 {
+			return nil, fmt.Errorf("item not found in trash: %s", id)
+		}
+
+		if err := tm.removeItem(items[index].ID); err != nil {
+			return nil, fmt.Errorf("error purging from trash: %w", err)
+		}
+
+		return append(items[:index], items[index+1:]...), nil
+	})
+}
+
+// Empty permanently deletes everything in the trash
+func (tm *TrashManager) Empty() (int, error) {
+	count := 0
+	err := tm.update(func(items []TrashedItem) ([]TrashedItem, error) {
+		for _, item := range items {
+			if err := tm.removeItem(item.ID); err != nil {
+				return nil, fmt.Errorf("error emptying trash: %w", err)
+			}
+		}
+
+		count = len(items)
+		return []TrashedItem{}, nil
+	})
+	return count, err
+}
+
+// PurgeExpired permanently deletes items older than the retention period
+// A retention of zero or less keeps items forever
+func (tm *TrashManager) PurgeExpired(retention time.Duration) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-retention)
+	purged := 0
+	err := tm.update(func(items []TrashedItem) ([]TrashedItem, error) {
+		kept := make([]TrashedItem, 0, len(items))
+		for _, item := range items {
+			if item.DeletedAt.Before(cutoff) {
+				if err := tm.removeItem(item.ID); err == nil {
+					purged++
+					continue
+				}
+			}
+			kept = append(kept, item)
+		}
+
+		if purged == 0 {
+			return nil, nil
+		}
+		return kept, nil
+	})
+	return purged, err
+}
+
+// newID returns a unique, time-ordered item ID
+// The random suffix keeps items trashed in the same second, by this or another
+// totion, apart
+func (tm *TrashManager) newID(now time.Time) string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// removeItem deletes an item's folder from the trash; a missing folder is not an error
+func (tm *TrashManager) removeItem(id string) error {
+	if err := tm.backend.Delete(path.Join(DirName, id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// findItem returns the index of the item with the given ID, or -1
+func findItem(items []TrashedItem, id string) int {
+	for i, item := range items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// restoredPath returns a free path next to name (e.g. "note (restored).md")
+func (tm *TrashManager) restoredPath(name string, isDir bool) string {
+	ext := ""
+	if !isDir {
+		ext = path.Ext(name)
+	}
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := base + " (restored)" + ext
+	for i := 2; ; i++ {
+		if _, err := tm.backend.Stat(candidate); errors.Is(err, fs.ErrNotExist) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (restored %d)%s", base, i, ext)
+	}
+}
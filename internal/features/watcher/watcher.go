@@ -0,0 +1,116 @@
+package watcher
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+// Op is the kind of change seen on a path
+type Op = backend.Op
+
+const (
+	Created  = backend.Created
+	Modified = backend.Modified
+	Removed  = backend.Removed // Deleted, or renamed away (the new name arrives as Created)
+)
+
+// Event is a debounced change to a note or notebook
+type Event struct {
+	Path  string
+	Op    Op
+	IsDir bool
+}
+
+// Watcher reports changes made to notes in a vault, including notebooks
+// Changes are collected by the storage backend until the vault has been quiet
+// for the debounce period and then delivered as one batch, with at most one
+// event per path. Hidden files (swap files, temp files, .trash) are left out.
+type Watcher struct {
+	store   *storage.Storage
+	changes backend.Watcher
+	events  chan []Event
+	done    chan struct{}
+	once    sync.Once
+}
+
+// New starts watching the vault behind a storage
+// A non-positive debounce falls back to 300ms
+func New(store *storage.Storage, debounce time.Duration) (*Watcher, error) {
+	changes, err := store.Backend().Watch(debounce)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		store:   store,
+		changes: changes,
+		events:  make(chan []Event, 1),
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Events returns the channel of debounced change batches
+// It is closed when the watcher is closed
+func (w *Watcher) Events() <-chan []Event {
+	return w.events
+}
+
+// Close stops watching and closes the events channel
+func (w *Watcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return w.changes.Close()
+}
+
+// run turns backend changes into note events until the backend watcher is closed
+func (w *Watcher) run() {
+	defer close(w.events)
+
+	for changes := range w.changes.Changes() {
+		events := make([]Event, 0, len(changes))
+		for _, change := range changes {
+			if event, ok := w.event(change); ok {
+				events = append(events, event)
+			}
+		}
+
+		if len(events) == 0 {
+			continue
+		}
+		select {
+		case w.events <- events:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// event converts a backend change, reporting false for paths that aren't notes or notebooks
+func (w *Watcher) event(change backend.Change) (Event, bool) {
+	// Temp files from atomic saves, swap files, state files and .trash are all hidden
+	for _, part := range strings.Split(change.Path, "/") {
+		if storage.IsHidden(part) || storage.IsIgnoredDir(part) {
+			return Event{}, false
+		}
+	}
+
+	event := Event{
+		Path:  filepath.Join(w.store.VaultDir(), filepath.FromSlash(change.Path)),
+		Op:    change.Op,
+		IsDir: change.IsDir,
+	}
+
+	if change.Op == Removed {
+		// The path is gone, so it is a notebook if it has no note extension
+		event.IsDir = !storage.IsNoteFile(change.Path)
+		return event, true
+	}
+	return event, change.IsDir || storage.IsNoteFile(change.Path)
+}
@@ -16,10 +16,14 @@ type BridgeServer struct {
 	port    int
 }
 
-// NewBridgeServer creates a new bridge server manager
-func NewBridgeServer() *BridgeServer {
+// NewBridgeServer creates a new bridge server manager on the given port
+func NewBridgeServer(port int) *BridgeServer {
+	if port <= 0 {
+		port = 3737
+	}
+
 	return &BridgeServer{
-		port:    3737,
+		port:    port,
 		running: false,
 	}
 }
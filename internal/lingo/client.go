@@ -28,9 +28,11 @@ type Response struct {
 
 // NewClient creates a new Lingo.dev client that connects to the bridge server
 // The bridge server uses the official Lingo.dev JavaScript SDK with Redis caching
-func NewClient(apiKey string) *Client {
-	// Bridge server URL - runs locally on port 3737
-	bridgeURL := "http://localhost:3737"
+func NewClient(apiKey, bridgeURL string) *Client {
+	// Bridge server URL - runs locally (default port 3737)
+	if bridgeURL == "" {
+		bridgeURL = "http://localhost:3737"
+	}
 
 	if apiKey == "" {
 		// Return a disabled client if no API key
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FormatInfo describes a note format known to Totion
+type FormatInfo struct {
+	Format     FileFormat
+	Name       string   // Shown in the format selector, e.g. "Markdown"
+	Icon       string   // Shown in lists and the editor
+	Extensions []string // File extensions with the dot; the first one is used for new notes
+}
+
+// Extension returns the extension used for new notes
+func (fi FormatInfo) Extension() string {
+	if len(fi.Extensions) == 0 {
+		return "." + string(fi.Format)
+	}
+	return fi.Extensions[0]
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = []FormatInfo{
+		{Format: FormatMarkdown, Name: "Markdown", Icon: "📝", Extensions: []string{".md"}},
+		{Format: FormatText, Name: "Plain Text", Icon: "📄", Extensions: []string{".txt"}},
+		{Format: FormatOrg, Name: "Org-mode", Icon: "🦄", Extensions: []string{".org"}},
+		{Format: FormatAsciiDoc, Name: "AsciiDoc", Icon: "📘", Extensions: []string{".adoc", ".asciidoc"}},
+	}
+)
+
+// RegisterFormat adds a note format, or replaces the one with the same name
+// Notes with its extensions are then listed, searched and indexed like the built-in formats
+func RegisterFormat(info FormatInfo) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	for i, existing := range formats {
+		if existing.Format == info.Format {
+			formats[i] = info
+			return
+		}
+	}
+	formats = append(formats, info)
+}
+
+// Formats returns the registered note formats in selector order
+func Formats() []FormatInfo {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return append([]FormatInfo{}, formats...)
+}
+
+// LookupFormat returns the registered format with the given name (e.g. "org")
+func LookupFormat(format FileFormat) (FormatInfo, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	for _, info := range formats {
+		if info.Format == format {
+			return info, true
+		}
+	}
+	return FormatInfo{}, false
+}
+
+// FormatForExt returns the format of a file extension (e.g. ".org"), case-insensitively
+func FormatForExt(ext string) (FileFormat, bool) {
+	ext = strings.ToLower(ext)
+
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	for _, info := range formats {
+		for _, e := range info.Extensions {
+			if e == ext {
+				return info.Format, true
+			}
+		}
+	}
+	return "", false
+}
+
+// FormatForPath returns the format of a note file, falling back to Markdown
+func FormatForPath(path string) FileFormat {
+	if format, ok := FormatForExt(filepath.Ext(path)); ok {
+		return format
+	}
+	return FormatMarkdown
+}
+
+// IsNoteExt checks if a file extension belongs to a registered format
+func IsNoteExt(ext string) bool {
+	_, ok := FormatForExt(ext)
+	return ok
+}
+
+// NoteExtensions returns every registered note extension
+func NoteExtensions() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	exts := []string{}
+	for _, info := range formats {
+		exts = append(exts, info.Extensions...)
+	}
+	return exts
+}
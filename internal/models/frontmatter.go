@@ -0,0 +1,315 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatterDelimiter marks the start and end of a YAML front matter block
+const FrontMatterDelimiter = "---"
+
+// frontMatterTimeLayouts are the date formats accepted for created/updated
+var frontMatterTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// FrontMatter holds the YAML metadata block at the top of a note
+type FrontMatter struct {
+	Title      string
+	Aliases    []string
+	Tags       []string
+	Created    time.Time
+	Updated    time.Time
+	Properties map[string]interface{} // Any custom keys not covered above
+}
+
+// NewFrontMatter creates front matter with a title and creation time
+func NewFrontMatter(title string) *FrontMatter {
+	now := time.Now()
+	return &FrontMatter{
+		Title:      title,
+		Created:    now,
+		Updated:    now,
+		Properties: make(map[string]interface{}),
+	}
+}
+
+// HasFrontMatter checks if content starts with a front matter block
+func HasFrontMatter(content string) bool {
+	_, _, ok := splitFrontMatter(content)
+	return ok
+}
+
+// ParseFrontMatter splits content into its front matter and body
+// Returns nil front matter (and the full content as body) when there is none
+func ParseFrontMatter(content string) (*FrontMatter, string, error) {
+	raw, body, ok := splitFrontMatter(content)
+	if !ok {
+		return nil, content, nil
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, content, fmt.Errorf("error parsing front matter: %w", err)
+	}
+
+	fm := &FrontMatter{Properties: make(map[string]interface{})}
+	for key, value := range values {
+		switch strings.ToLower(key) {
+		case "title":
+			fm.Title = toString(value)
+		case "aliases":
+			fm.Aliases = toStringList(value)
+		case "tags":
+			for _, tag := range toStringList(value) {
+				fm.Tags = append(fm.Tags, strings.ToLower(strings.TrimPrefix(tag, "#")))
+			}
+		case "created":
+			fm.Created = toTime(value)
+		case "updated":
+			fm.Updated = toTime(value)
+		default:
+			fm.Properties[key] = value
+		}
+	}
+
+	return fm, body, nil
+}
+
+// StripFrontMatter returns the content without its front matter block
+func StripFrontMatter(content string) string {
+	_, body, ok := splitFrontMatter(content)
+	if !ok {
+		return content
+	}
+	return body
+}
+
+// Render serializes the front matter as a delimited YAML block
+func (fm *FrontMatter) Render() string {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	if fm.Title != "" {
+		appendYAMLPair(root, "title", fm.Title)
+	}
+	if len(fm.Aliases) > 0 {
+		appendYAMLPair(root, "aliases", fm.Aliases)
+	}
+	if len(fm.Tags) > 0 {
+		appendYAMLPair(root, "tags", fm.Tags)
+	}
+	if !fm.Created.IsZero() {
+		appendYAMLPair(root, "created", fm.Created.Format(time.RFC3339))
+	}
+	if !fm.Updated.IsZero() {
+		appendYAMLPair(root, "updated", fm.Updated.Format(time.RFC3339))
+	}
+
+	// Custom properties in a stable order
+	keys := make([]string, 0, len(fm.Properties))
+	for key := range fm.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		appendYAMLPair(root, key, fm.Properties[key])
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(FrontMatterDelimiter + "\n")
+	if len(root.Content) > 0 {
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		_ = encoder.Encode(root)
+		_ = encoder.Close()
+	}
+	buf.WriteString(FrontMatterDelimiter + "\n")
+
+	return buf.String()
+}
+
+// Apply replaces the front matter of content with this front matter
+func (fm *FrontMatter) Apply(content string) string {
+	return fm.Render() + StripFrontMatter(content)
+}
+
+// Get returns a typed or custom property by key
+func (fm *FrontMatter) Get(key string) (interface{}, bool) {
+	switch strings.ToLower(key) {
+	case "title":
+		return fm.Title, fm.Title != ""
+	case "aliases":
+		return fm.Aliases, len(fm.Aliases) > 0
+	case "tags":
+		return fm.Tags, len(fm.Tags) > 0
+	case "created":
+		return fm.Created, !fm.Created.IsZero()
+	case "updated":
+		return fm.Updated, !fm.Updated.IsZero()
+	}
+	value, ok := fm.Properties[key]
+	return value, ok
+}
+
+// Set sets a custom property (typed keys are routed to their fields)
+func (fm *FrontMatter) Set(key string, value interface{}) {
+	switch strings.ToLower(key) {
+	case "title":
+		fm.Title = toString(value)
+	case "aliases":
+		fm.Aliases = toStringList(value)
+	case "tags":
+		fm.Tags = toStringList(value)
+	case "created":
+		fm.Created = toTime(value)
+	case "updated":
+		fm.Updated = toTime(value)
+	default:
+		if fm.Properties == nil {
+			fm.Properties = make(map[string]interface{})
+		}
+		fm.Properties[key] = value
+	}
+}
+
+// SetFrontMatterValue sets a single scalar key in the content's front matter
+// The rest of the block (key order, comments) is preserved as written
+// Content without front matter is returned unchanged
+func SetFrontMatterValue(content, key, value string) string {
+	raw, body, ok := splitFrontMatter(content)
+	if !ok {
+		return content
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &doc); err != nil {
+		return content
+	}
+
+	var mapping *yaml.Node
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		mapping = doc.Content[0]
+	} else if doc.Kind == 0 {
+		// Empty front matter block
+		mapping = &yaml.Node{Kind: yaml.MappingNode}
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mapping}}
+	} else {
+		return content
+	}
+
+	found := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			mapping.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			found = true
+			break
+		}
+	}
+	if !found {
+		appendYAMLPair(mapping, key, value)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(FrontMatterDelimiter + "\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return content
+	}
+	_ = encoder.Close()
+	buf.WriteString(FrontMatterDelimiter + "\n")
+
+	return buf.String() + body
+}
+
+// splitFrontMatter separates the raw YAML block from the body
+func splitFrontMatter(content string) (string, string, bool) {
+	normalized := strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(normalized, FrontMatterDelimiter+"\n") && !strings.HasPrefix(normalized, FrontMatterDelimiter+"\r\n") {
+		return "", content, false
+	}
+
+	lines := strings.SplitAfter(normalized, "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r\n") == FrontMatterDelimiter {
+			raw := strings.Join(lines[1:i], "")
+			body := strings.Join(lines[i+1:], "")
+			return raw, body, true
+		}
+	}
+
+	return "", content, false
+}
+
+// appendYAMLPair appends a key/value pair to a mapping node
+func appendYAMLPair(mapping *yaml.Node, key string, value interface{}) {
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		valueNode = &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(value)}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		valueNode,
+	)
+}
+
+// toString converts a YAML value to a string
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toStringList converts a YAML scalar, list or comma-separated string to a list
+func toStringList(value interface{}) []string {
+	list := []string{}
+	switch v := value.(type) {
+	case nil:
+	case []string:
+		list = append(list, v...)
+	case []interface{}:
+		for _, item := range v {
+			if s := strings.TrimSpace(toString(item)); s != "" {
+				list = append(list, s)
+			}
+		}
+	default:
+		for _, item := range strings.Split(toString(v), ",") {
+			if s := strings.TrimSpace(item); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// toTime converts a YAML value to a time, returning zero time if unparsable
+func toTime(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range frontMatterTimeLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(v), time.Local); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -10,16 +10,23 @@ type FileFormat string
 const (
 	FormatMarkdown FileFormat = "md"
 	FormatText     FileFormat = "txt"
+	FormatOrg      FileFormat = "org"
+	FormatAsciiDoc FileFormat = "adoc"
 )
 
 // Note represents a single note file
 type Note struct {
-	Name    string
-	Path    string
-	Format  FileFormat
-	Content string
-	ModTime time.Time
-	Size    int64
+	Name     string
+	Path     string
+	Format   FileFormat
+	Content  string
+	ModTime  time.Time
+	Size     int64
+	Notebook string       // Notebook path relative to the vault ("" for the vault root)
+	RelID    string       // Vault-relative path with forward slashes, unique per note
+	ID       string       // Persistent ID that survives renames and moves ("" until assigned)
+	Depth    int          // Number of notebook levels above the note (0 for the vault root)
+	Meta     *FrontMatter // Parsed YAML front matter, nil if the note has none
 }
 
 // Title returns the display title for the note
@@ -27,34 +34,66 @@ func (n Note) Title() string {
 	return n.Name
 }
 
+// DisplayTitle returns the front matter title if set, otherwise the file name
+func (n Note) DisplayTitle() string {
+	if n.Meta != nil && n.Meta.Title != "" {
+		return n.Meta.Title
+	}
+	return n.Name
+}
+
+// Tags returns the front matter tags of the note
+func (n Note) Tags() []string {
+	if n.Meta == nil {
+		return nil
+	}
+	return n.Meta.Tags
+}
+
 // Description returns the display description for the note
 func (n Note) Description() string {
-	formatIcon := "📝"
-	if n.Format == FormatText {
-		formatIcon = "📄"
+	formatIcon := n.Format.GetIcon()
+	if n.Notebook != "" {
+		return formatIcon + " 📁 " + n.Notebook + " • " + n.ModTime.Format("2006-01-02 15:04")
 	}
 	return formatIcon + " " + n.ModTime.Format("2006-01-02 15:04")
 }
 
 // FilterValue returns the value used for filtering
+// Includes the notebook path so notes can be filtered by folder
 func (n Note) FilterValue() string {
+	if n.RelID != "" {
+		return n.RelID
+	}
 	return n.Name
 }
 
+// DisplayName returns the note name prefixed with its notebook path
+func (n Note) DisplayName() string {
+	if n.Notebook == "" {
+		return n.Name
+	}
+	return n.Notebook + "/" + n.Name
+}
+
 // GetExtension returns the file extension for the format
 func (f FileFormat) GetExtension() string {
+	if info, ok := LookupFormat(f); ok {
+		return info.Extension()
+	}
 	return "." + string(f)
 }
 
 // GetIcon returns the icon for the format
 func (f FileFormat) GetIcon() string {
-	if f == FormatMarkdown {
-		return "📝"
+	if info, ok := LookupFormat(f); ok && info.Icon != "" {
+		return info.Icon
 	}
 	return "📄"
 }
 
-// IsValidFormat checks if the format is valid
+// IsValidFormat checks if the format is registered
 func IsValidFormat(format string) bool {
-	return format == string(FormatMarkdown) || format == string(FormatText)
+	_, ok := LookupFormat(FileFormat(format))
+	return ok
 }
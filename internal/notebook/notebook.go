@@ -2,15 +2,20 @@ package notebook
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/0xshariq/totion/internal/features/attachments"
 	"github.com/0xshariq/totion/internal/features/export"
 	"github.com/0xshariq/totion/internal/features/search"
 	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
 )
 
 // Notebook represents a folder/notebook containing notes
@@ -40,13 +45,16 @@ type NotebookMetadata struct {
 
 // NotebookManager handles notebook/folder operations
 type NotebookManager struct {
+	store    *storage.Storage
 	vaultDir string
 	root     *Notebook
 }
 
-// NewNotebookManager creates a new notebook manager
-func NewNotebookManager(vaultDir string) *NotebookManager {
+// NewNotebookManager creates a new notebook manager for a vault
+func NewNotebookManager(store *storage.Storage) *NotebookManager {
+	vaultDir := store.VaultDir()
 	return &NotebookManager{
+		store:    store,
 		vaultDir: vaultDir,
 		root: &Notebook{
 			Name: "Root",
@@ -62,12 +70,12 @@ func (nm *NotebookManager) CreateNotebook(name string) error {
 	notebookPath := filepath.Join(nm.vaultDir, name)
 
 	// Check if notebook already exists
-	if _, err := os.Stat(notebookPath); err == nil {
+	if _, err := nm.store.Stat(notebookPath); err == nil {
 		return fmt.Errorf("notebook '%s' already exists", name)
 	}
 
 	// Create directory
-	if err := os.MkdirAll(notebookPath, 0755); err != nil {
+	if err := nm.store.Mkdir(notebookPath); err != nil {
 		return fmt.Errorf("error creating notebook: %w", err)
 	}
 
@@ -91,11 +99,11 @@ func (nm *NotebookManager) CreateNotebook(name string) error {
 func (nm *NotebookManager) CreateNotebookWithDescription(name, description string) error {
 	notebookPath := filepath.Join(nm.vaultDir, name)
 
-	if _, err := os.Stat(notebookPath); err == nil {
+	if _, err := nm.store.Stat(notebookPath); err == nil {
 		return fmt.Errorf("notebook '%s' already exists", name)
 	}
 
-	if err := os.MkdirAll(notebookPath, 0755); err != nil {
+	if err := nm.store.Mkdir(notebookPath); err != nil {
 		return fmt.Errorf("error creating notebook: %w", err)
 	}
 
@@ -115,20 +123,23 @@ func (nm *NotebookManager) CreateNotebookWithDescription(name, description strin
 	return nil
 }
 
+// MetadataFile is the file in a notebook folder holding its metadata
+const MetadataFile = ".notebook.json"
+
 // saveMetadata saves notebook metadata to .notebook.json
 func (nm *NotebookManager) saveMetadata(notebookPath string, metadata NotebookMetadata) error {
-	metadataPath := filepath.Join(notebookPath, ".notebook.json")
+	metadataPath := filepath.Join(notebookPath, MetadataFile)
 	data, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(metadataPath, data, 0644)
+	return nm.store.WriteFile(metadataPath, data)
 }
 
 // loadMetadata loads notebook metadata from .notebook.json
 func (nm *NotebookManager) loadMetadata(notebookPath string) (*NotebookMetadata, error) {
-	metadataPath := filepath.Join(notebookPath, ".notebook.json")
-	data, err := os.ReadFile(metadataPath)
+	metadataPath := filepath.Join(notebookPath, MetadataFile)
+	data, err := nm.store.ReadFile(metadataPath)
 	if err != nil {
 		// Return default metadata if file doesn't exist
 		return &NotebookMetadata{
@@ -148,13 +159,44 @@ func (nm *NotebookManager) loadMetadata(notebookPath string) (*NotebookMetadata,
 	return &metadata, nil
 }
 
-// DeleteNotebook deletes a notebook/folder and all its contents
+// CheckMetadata returns the error parsing a notebook's .notebook.json, if it is broken
+// A notebook without the file is fine, it gets default metadata
+func (nm *NotebookManager) CheckMetadata(notebookPath string) error {
+	_, err := nm.loadMetadata(notebookPath)
+	return err
+}
+
+// RepairMetadata replaces a broken .notebook.json with default metadata
+// The broken file is kept as .notebook.json.bak
+func (nm *NotebookManager) RepairMetadata(notebookPath string) error {
+	metadataPath := filepath.Join(notebookPath, MetadataFile)
+	if data, err := nm.store.ReadFile(metadataPath); err == nil {
+		// Overwrites the backup of an earlier repair
+		if err := nm.store.WriteFile(metadataPath+".bak", data); err != nil {
+			return fmt.Errorf("error backing up metadata: %w", err)
+		}
+	}
+
+	created := time.Now()
+	if info, err := nm.store.Stat(notebookPath); err == nil {
+		created = info.ModTime
+	}
+	return nm.saveMetadata(notebookPath, NotebookMetadata{
+		Name:       filepath.Base(notebookPath),
+		Icon:       "📓",
+		Color:      "blue",
+		CreatedAt:  created,
+		ModifiedAt: time.Now(),
+	})
+}
+
+// DeleteNotebook moves a notebook/folder and all its contents to the trash
 func (nm *NotebookManager) DeleteNotebook(path string) error {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	if _, err := nm.store.Stat(path); errors.Is(err, fs.ErrNotExist) {
 		return fmt.Errorf("notebook does not exist: %s", path)
 	}
 
-	if err := os.RemoveAll(path); err != nil {
+	if _, err := nm.store.Trash().Trash(path); err != nil {
 		return fmt.Errorf("error deleting notebook: %w", err)
 	}
 
@@ -164,13 +206,9 @@ func (nm *NotebookManager) DeleteNotebook(path string) error {
 // GetNoteCount counts the number of notes in a notebook
 func (nm *NotebookManager) GetNoteCount(notebookPath string) (int, error) {
 	count := 0
-	err := filepath.Walk(notebookPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			ext := filepath.Ext(path)
-			if ext == ".md" || ext == ".txt" {
+	err := nm.store.Walk(notebookPath, func(path string, info backend.FileInfo) error {
+		if !info.IsDir {
+			if storage.IsNoteFile(path) {
 				count++
 			}
 		}
@@ -238,13 +276,14 @@ func (nm *NotebookManager) UpdateMetadata(notebookPath string, updates map[strin
 func (nm *NotebookManager) ListNotebooks() ([]*Notebook, error) {
 	notebooks := []*Notebook{}
 
-	entries, err := os.ReadDir(nm.vaultDir)
+	entries, err := nm.store.ReadDir(nm.vaultDir)
 	if err != nil {
 		return nil, fmt.Errorf("error reading vault directory: %w", err)
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() {
+		// The vault-wide attachments folder holds files, not notes
+		if entry.IsDir && !storage.IsIgnoredDir(entry.Name()) && entry.Name() != attachments.DirName {
 			notebookPath := filepath.Join(nm.vaultDir, entry.Name())
 			notebook, err := nm.GetNotebookInfo(notebookPath)
 			if err != nil {
@@ -263,10 +302,11 @@ func (nm *NotebookManager) ListNotebooks() ([]*Notebook, error) {
 }
 
 // RenameNotebook renames a notebook and updates its metadata
+// It only moves the folder; use rename.Renamer to keep links, pins and recents intact
 func (nm *NotebookManager) RenameNotebook(oldPath, newName string) error {
 	newPath := filepath.Join(filepath.Dir(oldPath), newName)
 
-	if err := os.Rename(oldPath, newPath); err != nil {
+	if err := nm.store.Move(oldPath, newPath); err != nil {
 		return fmt.Errorf("error renaming notebook: %w", err)
 	}
 
@@ -287,7 +327,7 @@ func (nm *NotebookManager) MoveNotebook(notebookPath, newParentPath string) erro
 	notebookName := filepath.Base(notebookPath)
 	newPath := filepath.Join(newParentPath, notebookName)
 
-	if err := os.Rename(notebookPath, newPath); err != nil {
+	if err := nm.store.Move(notebookPath, newPath); err != nil {
 		return fmt.Errorf("error moving notebook: %w", err)
 	}
 
@@ -301,27 +341,26 @@ func (nm *NotebookManager) GetNotebookPath(notebookName string) string {
 
 // IsNotebook checks if a path is a notebook/folder
 func (nm *NotebookManager) IsNotebook(path string) bool {
-	info, err := os.Stat(path)
+	info, err := nm.store.Stat(path)
 	if err != nil {
 		return false
 	}
 
-	return info.IsDir()
+	return info.IsDir
 }
 
 // GetNotesInNotebook returns all note files in a notebook
 func (nm *NotebookManager) GetNotesInNotebook(notebookPath string) ([]string, error) {
 	notes := []string{}
 
-	entries, err := os.ReadDir(notebookPath)
+	entries, err := nm.store.ReadDir(notebookPath)
 	if err != nil {
 		return nil, fmt.Errorf("error reading notebook directory: %w", err)
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			ext := filepath.Ext(entry.Name())
-			if ext == ".md" || ext == ".txt" {
+		if !entry.IsDir {
+			if storage.IsNoteFile(entry.Name()) {
 				notes = append(notes, filepath.Join(notebookPath, entry.Name()))
 			}
 		}
@@ -397,7 +436,7 @@ func (nm *NotebookManager) GetNotebooksByTag(tag string) ([]*Notebook, error) {
 
 // SearchInNotebook searches for text within a notebook's notes
 func (nm *NotebookManager) SearchInNotebook(notebookPath, query string) ([]search.SearchResult, error) {
-	searchMgr := search.NewSearchManager(notebookPath)
+	searchMgr := search.NewSearchManagerForDir(nm.store, notebookPath)
 	return searchMgr.Search(query)
 }
 
@@ -411,12 +450,12 @@ func (nm *NotebookManager) GetTagsInNotebook(notebookPath string) ([]string, err
 	}
 
 	for _, notePath := range notes {
-		content, err := os.ReadFile(notePath)
+		content, err := nm.store.ReadFile(notePath)
 		if err != nil {
 			continue
 		}
 
-		noteTags := tags.ExtractTags(string(content))
+		noteTags := tags.ExtractNoteTags(notePath, string(content))
 		for _, tag := range noteTags {
 			tagSet[tag] = true
 		}
@@ -443,23 +482,8 @@ func (nm *NotebookManager) ExportNotebook(notebookPath, outputDir string, format
 	}
 
 	exporter := export.NewExporter()
-	exportData := []export.NoteData{}
-
-	for _, notePath := range notes {
-		content, err := os.ReadFile(notePath)
-		if err != nil {
-			continue
-		}
-
-		title := strings.TrimSuffix(filepath.Base(notePath), filepath.Ext(notePath))
-		exportData = append(exportData, export.NoteData{
-			Title:   title,
-			Content: string(content),
-			Path:    notePath,
-		})
-	}
-
-	return exporter.BatchExport(exportData, outputDir, format)
+	_, err = exporter.BatchExport(export.LoadNotes(nm.store, notes), outputDir, format)
+	return err
 }
 
 // GetNotebookStatistics returns statistics for a notebook
@@ -474,14 +498,14 @@ func (nm *NotebookManager) GetNotebookStatistics(notebookPath string) (*Notebook
 	}
 
 	for _, notePath := range notes {
-		info, err := os.Stat(notePath)
+		info, err := nm.store.Stat(notePath)
 		if err != nil {
 			continue
 		}
 
-		stats.TotalSize += info.Size()
+		stats.TotalSize += info.Size
 
-		content, err := os.ReadFile(notePath)
+		content, err := nm.store.ReadFile(notePath)
 		if err != nil {
 			continue
 		}
@@ -508,7 +532,7 @@ type NotebookStats struct {
 func (nm *NotebookManager) DuplicateNotebook(srcPath, newName string) error {
 	destPath := filepath.Join(filepath.Dir(srcPath), newName)
 
-	if _, err := os.Stat(destPath); err == nil {
+	if _, err := nm.store.Stat(destPath); err == nil {
 		return fmt.Errorf("notebook '%s' already exists", newName)
 	}
 
@@ -521,16 +545,11 @@ func (nm *NotebookManager) copyDir(src, dst string) error {
 	src = filepath.Clean(src)
 	dst = filepath.Clean(dst)
 
-	srcInfo, err := os.Stat(src)
-	if err != nil {
-		return err
-	}
-
-	if err := os.MkdirAll(dst, srcInfo.Mode()); err != nil {
+	if err := nm.store.Mkdir(dst); err != nil {
 		return err
 	}
 
-	entries, err := os.ReadDir(src)
+	entries, err := nm.store.ReadDir(src)
 	if err != nil {
 		return err
 	}
@@ -539,7 +558,7 @@ func (nm *NotebookManager) copyDir(src, dst string) error {
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
 
-		if entry.IsDir() {
+		if entry.IsDir {
 			if err := nm.copyDir(srcPath, dstPath); err != nil {
 				return err
 			}
@@ -555,17 +574,12 @@ func (nm *NotebookManager) copyDir(src, dst string) error {
 
 // copyFile copies a single file
 func (nm *NotebookManager) copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-
-	info, err := os.Stat(src)
+	data, err := nm.store.ReadFile(src)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(dst, data, info.Mode())
+	return nm.store.WriteFile(dst, data)
 }
 
 // ArchiveNotebook creates a zip archive of a notebook
@@ -588,14 +602,14 @@ func (nm *NotebookManager) GetRecentlyModifiedNotes(notebookPath string, limit i
 
 	noteInfos := []NoteInfo{}
 	for _, notePath := range notes {
-		info, err := os.Stat(notePath)
+		info, err := nm.store.Stat(notePath)
 		if err != nil {
 			continue
 		}
 
 		noteInfos = append(noteInfos, NoteInfo{
 			Path:    notePath,
-			ModTime: info.ModTime(),
+			ModTime: info.ModTime,
 		})
 	}
 
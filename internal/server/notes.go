@@ -0,0 +1,331 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xshariq/totion/internal/config"
+	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage"
+)
+
+// note is a note in API responses
+type note struct {
+	ID       string    `json:"id"`
+	Path     string    `json:"path"` // Vault-relative, with forward slashes
+	Name     string    `json:"name"`
+	Format   string    `json:"format"`
+	Notebook string    `json:"notebook"` // "" for the vault root
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Tags     []string  `json:"tags"`
+	ETag     string    `json:"etag"`
+	Content  *string   `json:"content,omitempty"` // Only when a single note is returned
+}
+
+// noteBody is the body of note create and update requests
+// With Content-Type text/plain the body is the content and the path comes from ?path=
+type noteBody struct {
+	Path    string  `json:"path"`
+	Content *string `json:"content"`
+}
+
+// newNote builds the API view of a note from its content
+func (s *Server) newNote(n models.Note, content []byte, withContent bool) note {
+	result := note{
+		ID:       n.ID,
+		Path:     n.RelID,
+		Name:     n.Name,
+		Format:   string(n.Format),
+		Notebook: n.Notebook,
+		Size:     n.Size,
+		Modified: n.ModTime,
+		Tags:     tags.ExtractNoteTags(n.Path, string(content)),
+		ETag:     ETag(content),
+	}
+	if result.Tags == nil {
+		result.Tags = []string{}
+	}
+	if withContent {
+		text := string(content)
+		result.Content = &text
+	}
+	return result
+}
+
+// readNoteBody reads a note create or update request
+func readNoteBody(r *http.Request) (noteBody, error) {
+	var body noteBody
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/plain") {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return body, err
+		}
+		text := string(data)
+		body.Path = r.URL.Query().Get("path")
+		body.Content = &text
+		return body, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+// writeBodyError reports a request body that couldn't be read
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+}
+
+// handleListNotes lists notes, optionally filtered by ?notebook=, ?tag= and ?format=
+func (s *Server) handleListNotes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && !models.IsValidFormat(format) {
+		writeError(w, http.StatusBadRequest, "unknown format %q", format)
+		return
+	}
+	dir, ok := s.notebookDir(query.Get("notebook"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no notebook %q", query.Get("notebook"))
+		return
+	}
+	tag := strings.ToLower(strings.TrimPrefix(query.Get("tag"), "#"))
+
+	notes, err := s.notesIn(dir)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	items := []note{}
+	for _, n := range notes {
+		if format != "" && string(n.Format) != format {
+			continue
+		}
+		content, err := s.store.ReadFile(n.Path)
+		if err != nil {
+			continue
+		}
+		item := s.newNote(n, content, false)
+		if tag != "" && !containsTag(item.Tags, tag) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	writeJSON(w, http.StatusOK, list{Count: len(items), Items: items})
+}
+
+// handleCreateNote creates a note; 409 if it already exists
+func (s *Server) handleCreateNote(w http.ResponseWriter, r *http.Request) {
+	body, err := readNoteBody(r)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	path, ok := s.vaultPath(body.Path)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid note path %q", body.Path)
+		return
+	}
+	if !storage.IsNoteFile(path) {
+		format := models.FormatMarkdown
+		if config.AppConfig != nil {
+			format = models.FileFormat(config.AppConfig.DefaultFormat)
+		}
+		info, ok := models.LookupFormat(format)
+		if !ok {
+			info, _ = models.LookupFormat(models.FormatMarkdown)
+		}
+		path += info.Extension()
+	}
+
+	content := ""
+	if body.Content != nil {
+		content = *body.Content
+	}
+	if dir := filepath.Dir(path); dir != s.store.VaultDir() {
+		if err := s.store.Mkdir(dir); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+	}
+
+	exists := false
+	err = s.store.WithLock(path, func() error {
+		if _, err := s.store.Stat(path); err == nil {
+			exists = true
+			return nil
+		}
+		return s.store.WriteNote(path, content)
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if exists {
+		writeError(w, http.StatusConflict, "note already exists: %s", s.relPath(path))
+		return
+	}
+	if _, err := s.store.NoteID(path); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	s.indexTags(path, false)
+
+	s.writeNote(w, http.StatusCreated, path)
+}
+
+// handleGetNote returns a note with its content; If-None-Match gives 304
+func (s *Server) handleGetNote(w http.ResponseWriter, r *http.Request) {
+	n, ok := s.resolve(r.PathValue("ref"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no note %q", r.PathValue("ref"))
+		return
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		content, err := s.store.ReadFile(n.Path)
+		if err == nil && matchesETag(match, ETag(content)) {
+			w.Header().Set("ETag", ETag(content))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	s.writeNote(w, http.StatusOK, n.Path)
+}
+
+// handleUpdateNote replaces a note's content
+// If-Match is required; 412 if the note changed since it was read
+func (s *Server) handleUpdateNote(w http.ResponseWriter, r *http.Request) {
+	n, ok := s.resolve(r.PathValue("ref"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no note %q", r.PathValue("ref"))
+		return
+	}
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		writeError(w, http.StatusPreconditionRequired, "If-Match header with the note's ETag is required")
+		return
+	}
+
+	body, err := readNoteBody(r)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.Content == nil {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	status := http.StatusOK
+	err = s.store.WithLock(n.Path, func() error {
+		current, err := s.store.ReadFile(n.Path)
+		if err != nil {
+			return err
+		}
+		if !matchesETag(match, ETag(current)) {
+			status = http.StatusPreconditionFailed
+			return nil
+		}
+		return s.store.WriteNote(n.Path, *body.Content)
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if status == http.StatusOK {
+		s.indexTags(n.Path, false)
+	}
+	// On 412 the current version is returned, for the client to merge against
+	s.writeNote(w, status, n.Path)
+}
+
+// handleDeleteNote moves a note to the trash
+// If-Match is required; 412 if the note changed since it was read
+func (s *Server) handleDeleteNote(w http.ResponseWriter, r *http.Request) {
+	n, ok := s.resolve(r.PathValue("ref"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no note %q", r.PathValue("ref"))
+		return
+	}
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		writeError(w, http.StatusPreconditionRequired, "If-Match header with the note's ETag is required")
+		return
+	}
+	if holder, locked := s.store.LockHolder(n.Path); locked {
+		writeError(w, http.StatusLocked, "%s is open in another totion (%s)", n.RelID, holder)
+		return
+	}
+
+	status := http.StatusNoContent
+	err := s.store.WithLock(n.Path, func() error {
+		current, err := s.store.ReadFile(n.Path)
+		if err != nil {
+			return err
+		}
+		if !matchesETag(match, ETag(current)) {
+			status = http.StatusPreconditionFailed
+			return nil
+		}
+		return s.store.DeleteNote(n.Path)
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if status == http.StatusPreconditionFailed {
+		s.writeNote(w, status, n.Path)
+		return
+	}
+	s.indexTags(n.Path, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeNote responds with a note, its content and its ETag
+func (s *Server) writeNote(w http.ResponseWriter, status int, path string) {
+	n, err := s.store.GetNote(path)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	content, err := s.store.ReadFile(path)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	item := s.newNote(n, content, true)
+	w.Header().Set("ETag", item.ETag)
+	if status == http.StatusCreated {
+		w.Header().Set("Location", APIPrefix+"/notes/"+item.Path)
+	}
+	writeJSON(w, status, item)
+}
+
+// containsTag checks if a tag list has a tag
+func containsTag(list []string, tag string) bool {
+	for _, t := range list {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,422 @@
+package server
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xshariq/totion/internal/features/export"
+	"github.com/0xshariq/totion/internal/features/search"
+	"github.com/0xshariq/totion/internal/features/tags"
+	"github.com/0xshariq/totion/internal/features/tasks"
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/notebook"
+)
+
+// notebookItem is a notebook in API responses
+type notebookItem struct {
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	Notes       int       `json:"notes"`
+	Description string    `json:"description"`
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+}
+
+// match is a search match
+type match struct {
+	ID      string `json:"id"`
+	Path    string `json:"path"`
+	Line    int    `json:"line"` // 1-based
+	Text    string `json:"text"`
+	Snippet string `json:"snippet"`
+}
+
+// tagItem is a tag with the notes that use it
+type tagItem struct {
+	Tag   string   `json:"tag"`
+	Count int      `json:"count"`
+	Notes []string `json:"notes"` // Vault-relative paths
+}
+
+// task is a checkbox or Org TODO in a note
+type task struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	Line int    `json:"line"` // 1-based
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// contentTypes are the response types of the export formats
+var contentTypes = map[export.ExportFormat]string{
+	export.FormatHTML:      "text/html; charset=utf-8",
+	export.FormatPDF:       "application/pdf",
+	export.FormatPlainText: "text/plain; charset=utf-8",
+	export.FormatMarkdown:  "text/markdown; charset=utf-8",
+	export.FormatJSON:      "application/json",
+}
+
+// handleListNotebooks lists the notebooks of the vault
+func (s *Server) handleListNotebooks(w http.ResponseWriter, r *http.Request) {
+	notebooks, err := notebook.NewNotebookManager(s.store).ListNotebooks()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	sort.Slice(notebooks, func(i, j int) bool { return notebooks[i].Name < notebooks[j].Name })
+
+	items := make([]notebookItem, 0, len(notebooks))
+	for _, nb := range notebooks {
+		items = append(items, s.newNotebookItem(nb))
+	}
+	writeJSON(w, http.StatusOK, list{Count: len(items), Items: items})
+}
+
+// handleCreateNotebook creates a notebook from {"name": "..."}; 409 if it exists
+func (s *Server) handleCreateNotebook(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	path, ok := s.vaultPath(body.Name)
+	if !ok || strings.Contains(strings.Trim(body.Name, "/"), "/") {
+		writeError(w, http.StatusBadRequest, "invalid notebook name %q", body.Name)
+		return
+	}
+	if _, err := s.store.Stat(path); err == nil {
+		writeError(w, http.StatusConflict, "notebook already exists: %s", body.Name)
+		return
+	}
+
+	nm := notebook.NewNotebookManager(s.store)
+	if err := nm.CreateNotebookWithDescription(filepath.Base(path), body.Description); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	nb, err := nm.GetNotebookInfo(path)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", APIPrefix+"/notes?notebook="+filepath.Base(path))
+	writeJSON(w, http.StatusCreated, s.newNotebookItem(nb))
+}
+
+// newNotebookItem builds the API view of a notebook
+func (s *Server) newNotebookItem(nb *notebook.Notebook) notebookItem {
+	return notebookItem{
+		Name:        nb.Name,
+		Path:        s.relPath(nb.Path),
+		Notes:       nb.NoteCount,
+		Description: nb.Description,
+		Created:     nb.CreatedAt,
+		Modified:    nb.ModifiedAt,
+	}
+}
+
+// handleSearch searches notes for ?q=, optionally in ?notebook=
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+	dir, ok := s.notebookDir(r.URL.Query().Get("notebook"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no notebook %q", r.URL.Query().Get("notebook"))
+		return
+	}
+
+	results, err := search.NewSearchManagerForDir(s.store, dir).Search(query)
+	var queryErr *search.QueryError
+	if errors.As(err, &queryErr) {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	noteIDs := s.store.IDs().IDs()
+	items := make([]match, 0, len(results))
+	for _, result := range results {
+		items = append(items, match{
+			ID:      noteIDs[result.NotePath],
+			Path:    s.relPath(result.NotePath),
+			Line:    result.LineNumber,
+			Text:    result.FullLine,
+			Snippet: result.MatchSnippet,
+		})
+	}
+	writeJSON(w, http.StatusOK, list{Count: len(items), Items: items})
+}
+
+// handleListTags lists tags by how many notes use them
+func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
+	tm, err := s.tagManager()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	all := tm.GetAllTags()
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Count != all[j].Count {
+			return all[i].Count > all[j].Count
+		}
+		return all[i].Tag < all[j].Tag
+	})
+
+	items := make([]tagItem, 0, len(all))
+	for _, info := range all {
+		items = append(items, s.newTagItem(info.Tag, info.Notes))
+	}
+	writeJSON(w, http.StatusOK, list{Count: len(items), Items: items})
+}
+
+// handleGetTag returns a tag with its notes
+func (s *Server) handleGetTag(w http.ResponseWriter, r *http.Request) {
+	tm, err := s.tagManager()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	notes := tm.GetNotesByTag(tag)
+	if len(notes) == 0 {
+		writeError(w, http.StatusNotFound, "no notes tagged %q", tag)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.newTagItem(tag, notes))
+}
+
+// tagManager returns a tag manager with the vault's saved index
+// Notes written through the API update it; the TUI watcher, doctor and CLI
+// rebuild it for changes made elsewhere. Only a vault never indexed is built here
+func (s *Server) tagManager() (*tags.TagManager, error) {
+	tm := tags.NewTagManager(s.store, s.store.VaultDir())
+	if _, err := s.store.Stat(filepath.Join(s.store.VaultDir(), ".tags.json")); err != nil {
+		return tm, tm.RebuildIndex()
+	}
+	return tm, nil
+}
+
+// indexTags updates the tag index for a note written or deleted through the API
+func (s *Server) indexTags(path string, deleted bool) {
+	tm, err := s.tagManager()
+	if err != nil {
+		return
+	}
+	if deleted {
+		_, _ = tm.RemoveNote(path)
+	} else {
+		_ = tm.IndexNote(path)
+	}
+}
+
+// newTagItem builds the API view of a tag
+func (s *Server) newTagItem(tag string, notePaths []string) tagItem {
+	item := tagItem{Tag: tag, Count: len(notePaths), Notes: make([]string, 0, len(notePaths))}
+	for _, path := range notePaths {
+		item.Notes = append(item.Notes, s.relPath(path))
+	}
+	sort.Strings(item.Notes)
+	return item
+}
+
+// handleListTasks lists tasks, optionally in ?notebook= and with ?status=open|done
+func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "open" && status != "done" {
+		writeError(w, http.StatusBadRequest, "status must be open or done")
+		return
+	}
+	dir, ok := s.notebookDir(r.URL.Query().Get("notebook"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no notebook %q", r.URL.Query().Get("notebook"))
+		return
+	}
+
+	notes, err := s.notesIn(dir)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	taskManager := tasks.NewTaskManager()
+	items := []task{}
+	for _, n := range notes {
+		content, err := s.store.ReadNote(n.Path)
+		if err != nil {
+			continue
+		}
+		for _, t := range taskManager.ParseTasksForFormat(content, n.Format) {
+			if (status == "open" && t.Completed) || (status == "done" && !t.Completed) {
+				continue
+			}
+			items = append(items, task{ID: n.ID, Path: n.RelID, Line: t.Line + 1, Text: t.Text, Done: t.Completed})
+		}
+	}
+	writeJSON(w, http.StatusOK, list{Count: len(items), Items: items})
+}
+
+// handleExportNote exports one note in ?format= (md by default)
+// Attachments aren't included; export a notebook to get them
+func (s *Server) handleExportNote(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "unknown export format %q", r.URL.Query().Get("format"))
+		return
+	}
+	n, ok := s.resolve(r.PathValue("ref"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no note %q", r.PathValue("ref"))
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "totion-export-*")
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	exported, err := export.NewExporter().BatchExport(export.LoadNotes(s.store, []string{n.Path}), dir, format)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if len(exported) == 0 {
+		writeError(w, http.StatusNotFound, "no note %q", r.PathValue("ref"))
+		return
+	}
+
+	name := path.Base(exported[0].File)
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(exported[0].File)))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	_, _ = io.Copy(w, f)
+}
+
+// handleExportNotes exports every note, or those in ?notebook=, as a zip file
+func (s *Server) handleExportNotes(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "unknown export format %q", r.URL.Query().Get("format"))
+		return
+	}
+	dir, ok := s.notebookDir(r.URL.Query().Get("notebook"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no notebook %q", r.URL.Query().Get("notebook"))
+		return
+	}
+
+	notes, err := s.notesIn(dir)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	paths := make([]string, 0, len(notes))
+	for _, n := range notes {
+		paths = append(paths, n.Path)
+	}
+
+	outDir, err := os.MkdirTemp("", "totion-export-*")
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	defer os.RemoveAll(outDir)
+
+	if _, err := export.NewExporter().BatchExport(export.LoadNotes(s.store, paths), outDir, format); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="totion-export.zip"`)
+	zw := zip.NewWriter(w)
+	_ = filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fw, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	})
+	_ = zw.Close()
+}
+
+// exportFormat reads ?format=, md by default
+func exportFormat(r *http.Request) (export.ExportFormat, bool) {
+	format := export.ExportFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = export.FormatMarkdown
+	}
+	_, ok := contentTypes[format]
+	return format, ok
+}
+
+// notebookDir returns the folder of a notebook, or the vault for ""
+func (s *Server) notebookDir(name string) (string, bool) {
+	if strings.Trim(name, "/") == "" {
+		return s.store.VaultDir(), true
+	}
+	dir, ok := s.vaultPath(name)
+	if !ok {
+		return "", false
+	}
+	info, err := s.store.Stat(dir)
+	return dir, err == nil && info.IsDir
+}
+
+// notesIn lists the notes in a folder of the vault, sorted by vault path
+func (s *Server) notesIn(dir string) ([]models.Note, error) {
+	notes, err := s.store.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]models.Note, 0, len(notes))
+	for _, n := range notes {
+		if dir == s.store.VaultDir() || strings.HasPrefix(n.Path, dir+string(filepath.Separator)) {
+			filtered = append(filtered, n)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].RelID < filtered[j].RelID })
+	return filtered, nil
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/lock"
+)
+
+// APIPrefix is the path every endpoint lives under
+const APIPrefix = "/api/v1"
+
+// maxBodySize limits request bodies (notes are text, this is generous)
+const maxBodySize = 10 << 20
+
+// Server serves a vault over a JSON HTTP API
+// Every request needs "Authorization: Bearer <token>". Writes to a note need
+// "If-Match: <etag>" with the ETag it was read with, so a client never
+// overwrites changes it hasn't seen
+// Server is an http.Handler, so it can be mounted on any listener or httptest.Server
+type Server struct {
+	store *storage.Storage
+	token string
+	mux   *http.ServeMux
+}
+
+// NewServer creates an API server for a vault
+func NewServer(store *storage.Storage, token string) *Server {
+	s := &Server{
+		store: store,
+		token: token,
+		mux:   http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET "+APIPrefix+"/notes", s.handleListNotes)
+	s.mux.HandleFunc("POST "+APIPrefix+"/notes", s.handleCreateNote)
+	s.mux.HandleFunc("GET "+APIPrefix+"/notes/{ref...}", s.handleGetNote)
+	s.mux.HandleFunc("PUT "+APIPrefix+"/notes/{ref...}", s.handleUpdateNote)
+	s.mux.HandleFunc("DELETE "+APIPrefix+"/notes/{ref...}", s.handleDeleteNote)
+	s.mux.HandleFunc("GET "+APIPrefix+"/notebooks", s.handleListNotebooks)
+	s.mux.HandleFunc("POST "+APIPrefix+"/notebooks", s.handleCreateNotebook)
+	s.mux.HandleFunc("GET "+APIPrefix+"/search", s.handleSearch)
+	s.mux.HandleFunc("GET "+APIPrefix+"/tags", s.handleListTags)
+	s.mux.HandleFunc("GET "+APIPrefix+"/tags/{tag}", s.handleGetTag)
+	s.mux.HandleFunc("GET "+APIPrefix+"/tasks", s.handleListTasks)
+	s.mux.HandleFunc("GET "+APIPrefix+"/export", s.handleExportNotes)
+	s.mux.HandleFunc("GET "+APIPrefix+"/export/{ref...}", s.handleExportNote)
+
+	return s
+}
+
+// ServeHTTP checks the token and routes the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="totion"`)
+		writeError(w, http.StatusUnauthorized, "missing or wrong token")
+		return
+	}
+
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized checks the bearer token in constant time
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return false // Never serve a vault without a token
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(given)), []byte(s.token)) == 1
+}
+
+// NewToken returns a new random API token
+func NewToken() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
+
+// ETag returns the entity tag of note content
+func ETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// matchesETag checks an If-Match or If-None-Match header against an ETag
+// "*" matches any existing note
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve finds the note a URL refers to: a note ID or a vault-relative path
+// (extension optional)
+func (s *Server) resolve(ref string) (models.Note, bool) {
+	if note, ok := s.store.ResolveNote(ref, ""); ok {
+		return note, true
+	}
+
+	path, ok := s.vaultPath(ref)
+	if !ok {
+		return models.Note{}, false
+	}
+	if storage.IsNoteFile(path) {
+		note, err := s.store.GetNote(path)
+		return note, err == nil
+	}
+	for _, ext := range models.NoteExtensions() {
+		if note, err := s.store.GetNote(path + ext); err == nil {
+			return note, true
+		}
+	}
+	return models.Note{}, false
+}
+
+// vaultPath turns a vault-relative slash path into an absolute path
+// Paths escaping the vault or pointing into hidden folders are refused
+func (s *Server) vaultPath(rel string) (string, bool) {
+	rel = strings.Trim(rel, "/")
+	if rel == "" {
+		return "", false
+	}
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || part == "." || part == ".." || storage.IsHidden(part) {
+			return "", false
+		}
+	}
+	return filepath.Join(s.store.VaultDir(), filepath.FromSlash(rel)), true
+}
+
+// relPath returns the vault-relative slash path of a file
+func (s *Server) relPath(path string) string {
+	rel, err := filepath.Rel(s.store.VaultDir(), path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// list is the body of responses that return several items
+type list struct {
+	Count int         `json:"count"`
+	Items interface{} `json:"items"`
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// writeError writes a JSON error response: {"error": "..."}
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// writeStoreError maps a storage error to a status code
+// Notes open in a totion editor are reported as 423 Locked
+func writeStoreError(w http.ResponseWriter, err error) {
+	if locked, ok := lock.IsLocked(err); ok {
+		writeError(w, http.StatusLocked, "%v", locked)
+		return
+	}
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "%v", err)
+}
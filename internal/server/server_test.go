@@ -0,0 +1,208 @@
+package server
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/0xshariq/totion/internal/storage"
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+const testToken = "test-token"
+
+// newTestServers serves a vault on disk and one in memory
+func newTestServers(t *testing.T) map[string]*httptest.Server {
+	t.Helper()
+
+	fsStore, err := storage.NewWithVault(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := map[string]*httptest.Server{
+		"fs":     httptest.NewServer(NewServer(fsStore, testToken)),
+		"memory": httptest.NewServer(NewServer(storage.NewWithBackend("/vault", backend.NewMemory()), testToken)),
+	}
+	for _, srv := range servers {
+		t.Cleanup(srv.Close)
+	}
+	return servers
+}
+
+// do sends an authorized request and returns the response with its body read
+func do(t *testing.T, srv *httptest.Server, method, path, body string, headers ...string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+APIPrefix+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+func TestCreateNoteConflict(t *testing.T) {
+	for name, srv := range newTestServers(t) {
+		t.Run(name, func(t *testing.T) {
+			resp, body := do(t, srv, "POST", "/notes", `{"path": "work/plan.md", "content": "# Plan"}`)
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf("create: %d %s", resp.StatusCode, body)
+			}
+			if resp.Header.Get("ETag") == "" {
+				t.Error("create: no ETag")
+			}
+
+			resp, body = do(t, srv, "POST", "/notes", `{"path": "work/plan.md", "content": "other"}`)
+			if resp.StatusCode != http.StatusConflict {
+				t.Fatalf("create again: %d %s, want 409", resp.StatusCode, body)
+			}
+
+			_, body = do(t, srv, "GET", "/notes/work/plan.md", "")
+			var n note
+			if err := json.Unmarshal([]byte(body), &n); err != nil {
+				t.Fatal(err)
+			}
+			if n.Content == nil || *n.Content != "# Plan" {
+				t.Errorf("content after 409 = %v, want the original", n.Content)
+			}
+		})
+	}
+}
+
+func TestUpdateNotePreconditions(t *testing.T) {
+	for name, srv := range newTestServers(t) {
+		t.Run(name, func(t *testing.T) {
+			resp, _ := do(t, srv, "POST", "/notes", `{"path": "a.md", "content": "one"}`)
+			etag := resp.Header.Get("ETag")
+
+			resp, _ = do(t, srv, "GET", "/notes/a.md", "", "If-None-Match", etag)
+			if resp.StatusCode != http.StatusNotModified {
+				t.Errorf("If-None-Match: %d, want 304", resp.StatusCode)
+			}
+
+			resp, _ = do(t, srv, "PUT", "/notes/a.md", `{"content": "two"}`)
+			if resp.StatusCode != http.StatusPreconditionRequired {
+				t.Errorf("no If-Match: %d, want 428", resp.StatusCode)
+			}
+
+			resp, _ = do(t, srv, "PUT", "/notes/a.md", `{"content": "two"}`, "If-Match", etag)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("update: %d, want 200", resp.StatusCode)
+			}
+			newETag := resp.Header.Get("ETag")
+			if newETag == etag {
+				t.Error("ETag unchanged after update")
+			}
+
+			resp, body := do(t, srv, "PUT", "/notes/a.md", `{"content": "three"}`, "If-Match", etag)
+			if resp.StatusCode != http.StatusPreconditionFailed {
+				t.Fatalf("stale update: %d, want 412", resp.StatusCode)
+			}
+			if !strings.Contains(body, `"two"`) {
+				t.Errorf("412 body %s doesn't have the current content", body)
+			}
+
+			resp, _ = do(t, srv, "DELETE", "/notes/a.md", "", "If-Match", etag)
+			if resp.StatusCode != http.StatusPreconditionFailed {
+				t.Errorf("stale delete: %d, want 412", resp.StatusCode)
+			}
+			resp, _ = do(t, srv, "DELETE", "/notes/a.md", "", "If-Match", newETag)
+			if resp.StatusCode != http.StatusNoContent {
+				t.Errorf("delete: %d, want 204", resp.StatusCode)
+			}
+			resp, _ = do(t, srv, "GET", "/notes/a.md", "")
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("get deleted: %d, want 404", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestConcurrentUpdatesWithSameETag(t *testing.T) {
+	for name, srv := range newTestServers(t) {
+		t.Run(name, func(t *testing.T) {
+			resp, _ := do(t, srv, "POST", "/notes", `{"path": "race.md", "content": "start"}`)
+			etag := resp.Header.Get("ETag")
+
+			const writers = 20
+			statuses := make(chan int, writers)
+			var wg sync.WaitGroup
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					content := strings.Repeat("x", i+1)
+					resp, _ := do(t, srv, "PUT", "/notes/race.md", `{"content": "`+content+`"}`, "If-Match", etag)
+					statuses <- resp.StatusCode
+				}(i)
+			}
+			wg.Wait()
+			close(statuses)
+
+			counts := make(map[int]int)
+			for status := range statuses {
+				counts[status]++
+			}
+			if counts[http.StatusOK] != 1 || counts[http.StatusPreconditionFailed] != writers-1 {
+				t.Errorf("statuses %v, want one 200 and %d 412", counts, writers-1)
+			}
+		})
+	}
+}
+
+func TestExportKeepsNotebookFolders(t *testing.T) {
+	for name, srv := range newTestServers(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, path := range []string{"Meeting.md", "Work/Meeting.md", "Meeting.txt"} {
+				resp, body := do(t, srv, "POST", "/notes", `{"path": "`+path+`", "content": "`+path+`"}`)
+				if resp.StatusCode != http.StatusCreated {
+					t.Fatalf("create %s: %d %s", path, resp.StatusCode, body)
+				}
+			}
+
+			resp, body := do(t, srv, "GET", "/export?format=md", "")
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("export: %d %s", resp.StatusCode, body)
+			}
+			zr, err := zip.NewReader(strings.NewReader(body), int64(len(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			files := []string{}
+			for _, f := range zr.File {
+				if !strings.HasSuffix(f.Name, "/") {
+					files = append(files, f.Name)
+				}
+			}
+			sort.Strings(files)
+			want := []string{"Meeting (2).md", "Meeting.md", "Work/Meeting.md"}
+			if strings.Join(files, ",") != strings.Join(want, ",") {
+				t.Errorf("zip has %v, want %v", files, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+)
+
+// WriteNote atomically replaces the content of the note at path
+// On the filesystem the content is written to a temp file in the same directory,
+// synced to disk and renamed over the note, so a crash never leaves a half-written note.
+// The previous content is kept in the note's history, and a successful write
+// also removes the note's swap file.
+// Notes another totion process has open are not written (see OpenNote).
+func (s *Storage) WriteNote(path, content string) error {
+	if err := s.checkLock(path); err != nil {
+		return err
+	}
+
+	data := []byte(touchFrontMatter(content))
+	s.snapshot(path, data)
+	if err := s.WriteFile(path, data); err != nil {
+		return err
+	}
+	s.recordWrite(path, data)
+
+	if err := s.RemoveSwap(path); err != nil {
+		return fmt.Errorf("note saved but swap file could not be removed: %w", err)
+	}
+
+	return nil
+}
+
+// IsOwnWrite reports whether the note on disk still holds the content this
+// Storage last wrote to it, i.e. a change event for it came from Totion itself
+func (s *Storage) IsOwnWrite(path string) bool {
+	data, err := s.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	s.writesMu.Lock()
+	defer s.writesMu.Unlock()
+	sum, ok := s.writes[path]
+	return ok && sum == sha256.Sum256(data)
+}
+
+// snapshot keeps the note's current content in its history before it is replaced
+// History is best effort: a failed snapshot never blocks a save
+func (s *Storage) snapshot(path string, data []byte) {
+	previous, err := s.ReadFile(path)
+	if err != nil || len(previous) == 0 || bytes.Equal(previous, data) {
+		return
+	}
+	_ = s.history.Snapshot(path, previous)
+}
+
+// recordWrite remembers the content written to a note and keeps the
+// fingerprint used to recognise it after a move current
+func (s *Storage) recordWrite(path string, data []byte) {
+	s.writesMu.Lock()
+	s.writes[path] = sha256.Sum256(data)
+	s.writesMu.Unlock()
+
+	_ = s.ids.Update(path, data)
+}
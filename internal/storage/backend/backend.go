@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// Backend stores the files of a vault: notes, notebooks and their metadata
+// Paths are relative to the vault root and use forward slashes (e.g. "Work/Plan.md");
+// "" is the root. Missing paths return errors matching fs.ErrNotExist.
+type Backend interface {
+	// Stat describes a file or folder
+	Stat(name string) (FileInfo, error)
+
+	// ReadDir lists a folder's direct children, sorted by name
+	ReadDir(name string) ([]FileInfo, error)
+
+	// Read returns the content of a file
+	Read(name string) ([]byte, error)
+
+	// Write atomically replaces a file, creating it and its parent folders if needed
+	Write(name string, data []byte) error
+
+	// Mkdir creates a folder and its parents
+	Mkdir(name string) error
+
+	// Move renames a file or folder, replacing nothing: the target must not exist
+	Move(oldName, newName string) error
+
+	// Delete removes a file, or a folder with everything in it
+	Delete(name string) error
+
+	// Watch reports changes to the vault, batched after debounce of quiet
+	Watch(debounce time.Duration) (Watcher, error)
+}
+
+// FileInfo describes a file or folder in a backend
+type FileInfo struct {
+	Path    string // Vault-relative, slash separated
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+}
+
+// Name returns the last element of the path
+func (fi FileInfo) Name() string {
+	return path.Base(fi.Path)
+}
+
+// Op is the kind of change seen on a path
+type Op int
+
+const (
+	Created Op = iota
+	Modified
+	Removed // Deleted, or moved away (the new path arrives as Created)
+)
+
+// String returns a short name for the operation
+func (op Op) String() string {
+	switch op {
+	case Created:
+		return "created"
+	case Modified:
+		return "modified"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Change is a change to a file or folder
+type Change struct {
+	Path  string // Vault-relative, slash separated
+	Op    Op
+	IsDir bool
+}
+
+// Watcher delivers batches of changes until it is closed
+type Watcher interface {
+	// Changes returns the channel of batches; it is closed by Close
+	Changes() <-chan []Change
+
+	// Close stops watching
+	Close() error
+}
+
+// IsHidden checks if a file or folder name is hidden (dot-prefixed)
+func IsHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// IsIgnoredDir checks if a folder never contains notes (e.g. .git, .trash, node_modules)
+func IsIgnoredDir(name string) bool {
+	return IsHidden(name) || ignoredDirs[name]
+}
+
+// ignoredDirs lists non-hidden folders that never contain notes
+var ignoredDirs = map[string]bool{
+	"node_modules": true,
+}
+
+// Clean normalizes a vault-relative path ("/a//b/" becomes "a/b", "." becomes "")
+func Clean(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	return strings.TrimPrefix(name, "/")
+}
+
+// Walk calls fn for every file and folder under root (parents first), skipping ignored folders
+// Returning fs.SkipDir from fn for a folder skips its contents. Subfolders that
+// can't be read (e.g. removed while walking) are skipped.
+func Walk(b Backend, root string, fn func(info FileInfo) error) error {
+	entries, err := b.ReadDir(Clean(root))
+	if err != nil {
+		return err
+	}
+	return walkEntries(b, entries, fn)
+}
+
+// walkEntries walks already listed entries and their subfolders
+func walkEntries(b Backend, entries []FileInfo, fn func(info FileInfo) error) error {
+	for _, entry := range entries {
+		if entry.IsDir && IsIgnoredDir(entry.Name()) {
+			continue
+		}
+
+		if err := fn(entry); err != nil {
+			if err == fs.SkipDir && entry.IsDir {
+				continue
+			}
+			return err
+		}
+
+		if entry.IsDir {
+			children, err := b.ReadDir(entry.Path)
+			if err != nil {
+				continue
+			}
+			if err := walkEntries(b, children, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SameFile reports whether two paths name the same file in a backend
+// Only the filesystem can alias paths (case-insensitive names); elsewhere
+// different paths are different files.
+func SameFile(b Backend, name1, name2 string) bool {
+	if fsb, ok := b.(*FS); ok {
+		return fsb.SameFile(name1, name2)
+	}
+	return Clean(name1) == Clean(name2)
+}
+
+// notExist returns an error for a missing path that matches fs.ErrNotExist
+func notExist(op, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// exists returns an error for a path that is already taken
+func exists(op, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrExist}
+}
+
+// join joins a folder and a child name into a vault-relative path
+func join(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// parent returns the folder containing a vault-relative path ("" for the root)
+func parent(name string) string {
+	dir := path.Dir(name)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// within reports whether name is dir or inside it
+func within(name, dir string) bool {
+	return dir == "" || name == dir || strings.HasPrefix(name, dir+"/")
+}
@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// backends returns an empty vault in each backend
+func backends(t *testing.T) map[string]Backend {
+	t.Helper()
+
+	db, err := NewSQLite(filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return map[string]Backend{
+		"fs":     NewFS(t.TempDir()),
+		"memory": NewMemory(),
+		"sqlite": db,
+	}
+}
+
+// write creates files with their name as content
+func write(t *testing.T, b Backend, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := b.Write(name, []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// walked lists every path under the root, in walk order
+func walked(t *testing.T, b Backend) []string {
+	t.Helper()
+	paths := []string{}
+	err := Walk(b, "", func(info FileInfo) error {
+		paths = append(paths, info.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func TestMoveFolder(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			write(t, b, "Café/a.md", "Café/sub/b.md", "Cafés.md", "Café.md")
+
+			if err := b.Move("Café", "Tea"); err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{"Café.md", "Cafés.md", "Tea", "Tea/a.md", "Tea/sub", "Tea/sub/b.md"}
+			if got := walked(t, b); !reflect.DeepEqual(got, want) {
+				t.Errorf("after move: %v, want %v", got, want)
+			}
+			data, err := b.Read("Tea/sub/b.md")
+			if err != nil || string(data) != "Café/sub/b.md" {
+				t.Errorf("moved content = %q, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestMoveOntoExisting(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			write(t, b, "a.md", "b.md")
+
+			if err := b.Move("a.md", "b.md"); !errors.Is(err, fs.ErrExist) {
+				t.Errorf("move onto an existing file: %v, want fs.ErrExist", err)
+			}
+			data, _ := b.Read("b.md")
+			if string(data) != "b.md" {
+				t.Errorf("target overwritten with %q", data)
+			}
+		})
+	}
+}
+
+func TestMoveOntoOtherCase(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			write(t, b, "a.md")
+			if _, err := b.Stat("A.md"); err == nil {
+				t.Skip("case-insensitive filesystem")
+			}
+			write(t, b, "A.md")
+
+			if err := b.Move("a.md", "A.md"); !errors.Is(err, fs.ErrExist) {
+				t.Errorf("move onto a file differing in case: %v, want fs.ErrExist", err)
+			}
+			data, _ := b.Read("A.md")
+			if string(data) != "A.md" {
+				t.Errorf("target overwritten with %q", data)
+			}
+		})
+	}
+}
+
+func TestMoveMissing(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := b.Move("nope.md", "other.md"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("move of a missing file: %v, want fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestDeleteFolder(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			write(t, b, "Café/a.md", "Café/sub/b.md", "Cafés.md", "Über/c.md")
+
+			if err := b.Delete("Café"); err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{"Cafés.md", "Über", "Über/c.md"}
+			if got := walked(t, b); !reflect.DeepEqual(got, want) {
+				t.Errorf("after delete: %v, want %v", got, want)
+			}
+			if _, err := b.Stat("Café/sub/b.md"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("child of a deleted folder: %v, want fs.ErrNotExist", err)
+			}
+			if err := b.Delete("Café"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("delete again: %v, want fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestWalkSkipsIgnoredAndSkipDir(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			write(t, b, ".git/config", "skip/a.md", "keep/b.md")
+
+			paths := []string{}
+			err := Walk(b, "", func(info FileInfo) error {
+				paths = append(paths, info.Path)
+				if info.IsDir && info.Path == "skip" {
+					return fs.SkipDir
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{"keep", "keep/b.md", "skip"}
+			if !reflect.DeepEqual(paths, want) {
+				t.Errorf("walk: %v, want %v", paths, want)
+			}
+		})
+	}
+}
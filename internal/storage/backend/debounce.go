@@ -0,0 +1,175 @@
+package backend
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// debouncer collects changes until there has been no new change for the debounce
+// period, then delivers them as one batch with at most one change per path
+type debouncer struct {
+	debounce time.Duration
+	changes  chan []Change
+	done     chan struct{}
+	onClose  func() error
+
+	mu      sync.Mutex
+	pending map[string]Change
+	timer   *time.Timer
+	closed  bool
+
+	sendMu sync.Mutex // Held while delivering a batch so Close can't close changes mid-send
+}
+
+// newDebouncer creates a debouncer; a non-positive debounce falls back to 300ms
+// onClose, if set, runs when the watcher is closed
+func newDebouncer(debounce time.Duration, onClose func() error) *debouncer {
+	if debounce <= 0 {
+		debounce = 300 * time.Millisecond
+	}
+
+	return &debouncer{
+		debounce: debounce,
+		changes:  make(chan []Change, 1),
+		done:     make(chan struct{}),
+		onClose:  onClose,
+		pending:  make(map[string]Change),
+	}
+}
+
+// Changes returns the channel of debounced batches
+func (d *debouncer) Changes() <-chan []Change {
+	return d.changes
+}
+
+// Close stops delivering changes and closes the changes channel
+func (d *debouncer) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.mu.Unlock()
+
+	close(d.done)
+
+	var err error
+	if d.onClose != nil {
+		err = d.onClose()
+	}
+
+	d.sendMu.Lock()
+	close(d.changes)
+	d.sendMu.Unlock()
+
+	return err
+}
+
+// queue merges a change into the pending batch and restarts the timer
+func (d *debouncer) queue(change Change) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	if prev, ok := d.pending[change.Path]; ok {
+		switch {
+		case prev.Op == Created && change.Op == Modified:
+			change.Op = Created // Still new to the reader
+		case prev.Op == Created && change.Op == Removed:
+			delete(d.pending, change.Path) // Came and went within one batch
+			d.resetTimer()
+			return
+		case prev.Op == Removed && change.Op == Created:
+			change.Op = Modified // Replaced, e.g. by an atomic save
+		}
+	}
+	d.pending[change.Path] = change
+	d.resetTimer()
+}
+
+// resetTimer schedules a flush after the debounce period (caller holds mu)
+func (d *debouncer) resetTimer() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.debounce, d.flush)
+}
+
+// flush delivers the pending batch, sorted by path
+func (d *debouncer) flush() {
+	d.mu.Lock()
+	if d.closed || len(d.pending) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	batch := make([]Change, 0, len(d.pending))
+	for _, change := range d.pending {
+		batch = append(batch, change)
+	}
+	d.pending = make(map[string]Change)
+	d.mu.Unlock()
+
+	sort.Slice(batch, func(i, j int) bool {
+		return batch[i].Path < batch[j].Path
+	})
+
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+	select {
+	case <-d.done:
+		return
+	default:
+	}
+
+	select {
+	case d.changes <- batch:
+	case <-d.done:
+	}
+}
+
+// hub fans changes made through a backend out to its watchers
+// It is used by backends that only change when written through this process
+type hub struct {
+	mu       sync.Mutex
+	watchers map[*debouncer]bool
+}
+
+// watch registers a new watcher
+func (h *hub) watch(debounce time.Duration) Watcher {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.watchers == nil {
+		h.watchers = make(map[*debouncer]bool)
+	}
+
+	var d *debouncer
+	d = newDebouncer(debounce, func() error {
+		h.mu.Lock()
+		delete(h.watchers, d)
+		h.mu.Unlock()
+		return nil
+	})
+	h.watchers[d] = true
+	return d
+}
+
+// notify sends changes to every watcher
+func (h *hub) notify(changes ...Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for d := range h.watchers {
+		for _, change := range changes {
+			d.queue(change)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// FS keeps the vault as plain files in a directory (the default backend)
+type FS struct {
+	root string
+}
+
+// NewFS creates a filesystem backend rooted at dir
+func NewFS(dir string) *FS {
+	return &FS{root: filepath.Clean(dir)}
+}
+
+// Root returns the directory holding the vault
+func (b *FS) Root() string {
+	return b.root
+}
+
+// Abs returns the OS path of a vault-relative path
+func (b *FS) Abs(name string) string {
+	return filepath.Join(b.root, filepath.FromSlash(Clean(name)))
+}
+
+// Stat describes a file or folder
+func (b *FS) Stat(name string) (FileInfo, error) {
+	info, err := os.Stat(b.Abs(name))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return fileInfo(Clean(name), info), nil
+}
+
+// ReadDir lists a folder's direct children, sorted by name
+func (b *FS) ReadDir(name string) ([]FileInfo, error) {
+	name = Clean(name)
+	entries, err := os.ReadDir(b.Abs(name))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed while listing
+		}
+		infos = append(infos, fileInfo(join(name, entry.Name()), info))
+	}
+	return infos, nil
+}
+
+// Read returns the content of a file
+func (b *FS) Read(name string) ([]byte, error) {
+	return os.ReadFile(b.Abs(name))
+}
+
+// Write atomically replaces a file, creating it and its parent folders if needed
+func (b *FS) Write(name string, data []byte) error {
+	path := b.Abs(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("error creating folder: %w", err)
+	}
+	return WriteFileAtomic(path, data, 0644)
+}
+
+// Mkdir creates a folder and its parents
+func (b *FS) Mkdir(name string) error {
+	return os.MkdirAll(b.Abs(name), 0750)
+}
+
+// Move renames a file or folder; the target must not exist
+func (b *FS) Move(oldName, newName string) error {
+	newPath := b.Abs(newName)
+	if _, err := os.Stat(newPath); err == nil && !b.SameFile(oldName, newName) {
+		return exists("move", newName)
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0750); err != nil {
+		return fmt.Errorf("error creating folder: %w", err)
+	}
+	return os.Rename(b.Abs(oldName), newPath)
+}
+
+// SameFile reports whether two paths name the same file, e.g. "a.md" and "A.md"
+// on a case-insensitive filesystem
+func (b *FS) SameFile(name1, name2 string) bool {
+	info1, err := os.Stat(b.Abs(name1))
+	if err != nil {
+		return false
+	}
+	info2, err := os.Stat(b.Abs(name2))
+	if err != nil {
+		return false
+	}
+	return os.SameFile(info1, info2)
+}
+
+// Delete removes a file, or a folder with everything in it
+func (b *FS) Delete(name string) error {
+	path := b.Abs(name)
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// Watch reports changes made by any process, using the OS file notification API
+func (b *FS) Watch(debounce time.Duration) (Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error creating file watcher: %w", err)
+	}
+
+	w := &fsWatcher{backend: b, fs: fsw}
+	w.debouncer = newDebouncer(debounce, fsw.Close)
+
+	if err := w.addTree("", false); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	go w.run()
+	return w.debouncer, nil
+}
+
+// fsWatcher turns fsnotify events into debounced changes
+type fsWatcher struct {
+	*debouncer
+	backend *FS
+	fs      *fsnotify.Watcher
+}
+
+// run reads raw fsnotify events until the watcher is closed
+func (w *fsWatcher) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case _, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			// Overflows lose events; the next change to a path reports it again
+		}
+	}
+}
+
+// handle turns a raw fsnotify event into a pending change
+func (w *fsWatcher) handle(event fsnotify.Event) {
+	rel, err := filepath.Rel(w.backend.root, event.Name)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return
+	}
+	name := filepath.ToSlash(rel)
+
+	// Nothing inside ignored folders is reported
+	for _, part := range strings.Split(parent(name), "/") {
+		if part != "" && IsIgnoredDir(part) {
+			return
+		}
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Stat(event.Name)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			if IsIgnoredDir(info.Name()) {
+				return
+			}
+			// Watch the new folder and pick up files that were moved in with it
+			_ = w.addTree(name, true)
+			w.queue(Change{Path: name, Op: Created, IsDir: true})
+			return
+		}
+		w.queue(Change{Path: name, Op: Created})
+
+	case event.Has(fsnotify.Write):
+		w.queue(Change{Path: name, Op: Modified})
+
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		// The path is gone, so guess from the name whether it was a folder
+		w.queue(Change{Path: name, Op: Removed, IsDir: filepath.Ext(name) == ""})
+	}
+}
+
+// addTree watches a folder and its subfolders
+// When report is set, files already inside are queued as created
+func (w *fsWatcher) addTree(dir string, report bool) error {
+	if err := w.fs.Add(w.backend.Abs(dir)); err != nil {
+		return fmt.Errorf("error watching %s: %w", w.backend.Abs(dir), err)
+	}
+
+	return Walk(w.backend, dir, func(info FileInfo) error {
+		if info.IsDir {
+			if err := w.fs.Add(w.backend.Abs(info.Path)); err != nil {
+				return fmt.Errorf("error watching %s: %w", w.backend.Abs(info.Path), err)
+			}
+		}
+		if report {
+			w.queue(Change{Path: info.Path, Op: Created, IsDir: info.IsDir})
+		}
+		return nil
+	})
+}
+
+// fileInfo converts an os.FileInfo
+func fileInfo(name string, info os.FileInfo) FileInfo {
+	return FileInfo{
+		Path:    name,
+		IsDir:   info.IsDir(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+}
+
+// sortInfos sorts file infos by path
+func sortInfos(infos []FileInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Path < infos[j].Path
+	})
+}
@@ -0,0 +1,199 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory keeps the vault in memory, for tests and throwaway vaults
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string]*memFile // Keyed by vault-relative path; the root is implicit
+	hub   hub
+}
+
+// memFile is a file or folder held by the memory backend
+type memFile struct {
+	isDir   bool
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemory creates an empty in-memory backend
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]*memFile)}
+}
+
+// Stat describes a file or folder
+func (b *Memory) Stat(name string) (FileInfo, error) {
+	name = Clean(name)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if name == "" {
+		return FileInfo{Path: "", IsDir: true}, nil
+	}
+	f, ok := b.files[name]
+	if !ok {
+		return FileInfo{}, notExist("stat", name)
+	}
+	return f.info(name), nil
+}
+
+// ReadDir lists a folder's direct children, sorted by name
+func (b *Memory) ReadDir(name string) ([]FileInfo, error) {
+	name = Clean(name)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if name != "" {
+		if f, ok := b.files[name]; !ok || !f.isDir {
+			return nil, notExist("readdir", name)
+		}
+	}
+
+	infos := []FileInfo{}
+	for path, f := range b.files {
+		if path != name && parent(path) == name {
+			infos = append(infos, f.info(path))
+		}
+	}
+	sortInfos(infos)
+	return infos, nil
+}
+
+// Read returns the content of a file
+func (b *Memory) Read(name string) ([]byte, error) {
+	name = Clean(name)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	f, ok := b.files[name]
+	if !ok || f.isDir {
+		return nil, notExist("read", name)
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+// Write replaces a file, creating it and its parent folders if needed
+func (b *Memory) Write(name string, data []byte) error {
+	name = Clean(name)
+
+	b.mu.Lock()
+	changes := b.mkdirLocked(parent(name))
+	op := Modified
+	if f, ok := b.files[name]; !ok {
+		op = Created
+	} else if f.isDir {
+		b.mu.Unlock()
+		return exists("write", name)
+	}
+	b.files[name] = &memFile{data: append([]byte(nil), data...), modTime: time.Now()}
+	b.mu.Unlock()
+
+	b.hub.notify(append(changes, Change{Path: name, Op: op})...)
+	return nil
+}
+
+// Mkdir creates a folder and its parents
+func (b *Memory) Mkdir(name string) error {
+	b.mu.Lock()
+	changes := b.mkdirLocked(Clean(name))
+	b.mu.Unlock()
+
+	b.hub.notify(changes...)
+	return nil
+}
+
+// mkdirLocked creates missing folders (caller holds mu) and returns the changes
+func (b *Memory) mkdirLocked(name string) []Change {
+	if name == "" {
+		return nil
+	}
+	if _, ok := b.files[name]; ok {
+		return nil
+	}
+
+	changes := b.mkdirLocked(parent(name))
+	b.files[name] = &memFile{isDir: true, modTime: time.Now()}
+	return append(changes, Change{Path: name, Op: Created, IsDir: true})
+}
+
+// Move renames a file or folder; the target must not exist
+func (b *Memory) Move(oldName, newName string) error {
+	oldName, newName = Clean(oldName), Clean(newName)
+
+	b.mu.Lock()
+	if _, ok := b.files[oldName]; !ok {
+		b.mu.Unlock()
+		return notExist("move", oldName)
+	}
+	if _, ok := b.files[newName]; ok && oldName != newName {
+		b.mu.Unlock()
+		return exists("move", newName)
+	}
+	if within(newName, oldName) && oldName != newName {
+		b.mu.Unlock()
+		return fmt.Errorf("cannot move %s into itself", oldName)
+	}
+
+	changes := b.mkdirLocked(parent(newName))
+	for path, f := range b.files {
+		if !within(path, oldName) {
+			continue
+		}
+		moved := newName + strings.TrimPrefix(path, oldName)
+		delete(b.files, path)
+		b.files[moved] = f
+		changes = append(changes,
+			Change{Path: path, Op: Removed, IsDir: f.isDir},
+			Change{Path: moved, Op: Created, IsDir: f.isDir})
+	}
+	b.mu.Unlock()
+
+	b.hub.notify(changes...)
+	return nil
+}
+
+// Delete removes a file, or a folder with everything in it
+func (b *Memory) Delete(name string) error {
+	name = Clean(name)
+
+	b.mu.Lock()
+	if _, ok := b.files[name]; !ok {
+		b.mu.Unlock()
+		return notExist("delete", name)
+	}
+
+	changes := []Change{}
+	for path, f := range b.files {
+		if within(path, name) {
+			delete(b.files, path)
+			changes = append(changes, Change{Path: path, Op: Removed, IsDir: f.isDir})
+		}
+	}
+	b.mu.Unlock()
+
+	b.hub.notify(changes...)
+	return nil
+}
+
+// Watch reports changes made through this backend
+func (b *Memory) Watch(debounce time.Duration) (Watcher, error) {
+	return b.hub.watch(debounce), nil
+}
+
+// info describes the file
+func (f *memFile) info(name string) FileInfo {
+	return FileInfo{
+		Path:    name,
+		IsDir:   f.isDir,
+		Size:    int64(len(f.data)),
+		ModTime: f.modTime,
+	}
+}
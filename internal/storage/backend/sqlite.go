@@ -0,0 +1,342 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver
+)
+
+// sqliteSchema creates the single table holding every file and folder
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS files (
+	path     TEXT PRIMARY KEY,
+	parent   TEXT NOT NULL,
+	is_dir   INTEGER NOT NULL,
+	data     BLOB,
+	mod_time INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS files_parent ON files(parent);
+`
+
+// SQLite keeps the whole vault in a single SQLite database file
+type SQLite struct {
+	db  *sql.DB
+	hub hub
+}
+
+// NewSQLite opens (or creates) a vault stored in the database file at path
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	// One connection keeps writes serialized and transactions simple
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating database schema: %w", err)
+	}
+
+	return &SQLite{db: db}, nil
+}
+
+// Close closes the database
+func (b *SQLite) Close() error {
+	return b.db.Close()
+}
+
+// Stat describes a file or folder
+func (b *SQLite) Stat(name string) (FileInfo, error) {
+	name = Clean(name)
+	if name == "" {
+		return FileInfo{Path: "", IsDir: true}, nil
+	}
+
+	row := b.db.QueryRow(`SELECT path, is_dir, length(data), mod_time FROM files WHERE path = ?`, name)
+	info, err := scanInfo(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return FileInfo{}, notExist("stat", name)
+	}
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// ReadDir lists a folder's direct children, sorted by name
+func (b *SQLite) ReadDir(name string) ([]FileInfo, error) {
+	name = Clean(name)
+	if info, err := b.Stat(name); err != nil {
+		return nil, err
+	} else if !info.IsDir {
+		return nil, notExist("readdir", name)
+	}
+
+	rows, err := b.db.Query(`SELECT path, is_dir, length(data), mod_time FROM files WHERE parent = ? AND path != '' ORDER BY path`, name)
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	infos := []FileInfo{}
+	for rows.Next() {
+		info, err := scanInfo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s: %w", name, err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+// Read returns the content of a file
+func (b *SQLite) Read(name string) ([]byte, error) {
+	name = Clean(name)
+
+	var data []byte
+	err := b.db.QueryRow(`SELECT data FROM files WHERE path = ? AND is_dir = 0`, name).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notExist("read", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
+
+// Write replaces a file, creating it and its parent folders if needed
+func (b *SQLite) Write(name string, data []byte) error {
+	name = Clean(name)
+	if data == nil {
+		data = []byte{}
+	}
+
+	var changes []Change
+	err := b.inTx(func(tx *sql.Tx) error {
+		var isDir bool
+		err := tx.QueryRow(`SELECT is_dir FROM files WHERE path = ?`, name).Scan(&isDir)
+		op := Modified
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			op = Created
+		case err != nil:
+			return err
+		case isDir:
+			return exists("write", name)
+		}
+
+		changes, err = mkdirTx(tx, parent(name))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO files (path, parent, is_dir, data, mod_time) VALUES (?, ?, 0, ?, ?)
+			ON CONFLICT(path) DO UPDATE SET data = excluded.data, mod_time = excluded.mod_time`,
+			name, parent(name), data, time.Now().UnixNano())
+		if err != nil {
+			return err
+		}
+		changes = append(changes, Change{Path: name, Op: op})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+
+	b.hub.notify(changes...)
+	return nil
+}
+
+// Mkdir creates a folder and its parents
+func (b *SQLite) Mkdir(name string) error {
+	var changes []Change
+	err := b.inTx(func(tx *sql.Tx) error {
+		var err error
+		changes, err = mkdirTx(tx, Clean(name))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error creating folder %s: %w", name, err)
+	}
+
+	b.hub.notify(changes...)
+	return nil
+}
+
+// Move renames a file or folder; the target must not exist
+func (b *SQLite) Move(oldName, newName string) error {
+	oldName, newName = Clean(oldName), Clean(newName)
+	if oldName == newName {
+		_, err := b.Stat(oldName)
+		return err
+	}
+	if within(newName, oldName) {
+		return fmt.Errorf("cannot move %s into itself", oldName)
+	}
+
+	var changes []Change
+	err := b.inTx(func(tx *sql.Tx) error {
+		infos, err := subtreeTx(tx, oldName)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			return notExist("move", oldName)
+		}
+
+		var taken int
+		if err := tx.QueryRow(`SELECT count(*) FROM files WHERE path = ?`, newName).Scan(&taken); err != nil {
+			return err
+		}
+		if taken > 0 {
+			return exists("move", newName)
+		}
+
+		changes, err = mkdirTx(tx, parent(newName))
+		if err != nil {
+			return err
+		}
+
+		for _, info := range infos {
+			moved := newName + info.Path[len(oldName):]
+			if _, err := tx.Exec(`UPDATE files SET path = ?, parent = ? WHERE path = ?`, moved, parent(moved), info.Path); err != nil {
+				return err
+			}
+			changes = append(changes,
+				Change{Path: info.Path, Op: Removed, IsDir: info.IsDir},
+				Change{Path: moved, Op: Created, IsDir: info.IsDir})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error moving %s: %w", oldName, err)
+	}
+
+	b.hub.notify(changes...)
+	return nil
+}
+
+// Delete removes a file, or a folder with everything in it
+func (b *SQLite) Delete(name string) error {
+	name = Clean(name)
+
+	var changes []Change
+	err := b.inTx(func(tx *sql.Tx) error {
+		infos, err := subtreeTx(tx, name)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			return notExist("delete", name)
+		}
+
+		for _, info := range infos {
+			if _, err := tx.Exec(`DELETE FROM files WHERE path = ?`, info.Path); err != nil {
+				return err
+			}
+			changes = append(changes, Change{Path: info.Path, Op: Removed, IsDir: info.IsDir})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting %s: %w", name, err)
+	}
+
+	b.hub.notify(changes...)
+	return nil
+}
+
+// Watch reports changes made through this backend
+// Other processes writing to the same database file are not seen
+func (b *SQLite) Watch(debounce time.Duration) (Watcher, error) {
+	return b.hub.watch(debounce), nil
+}
+
+// inTx runs fn in a transaction, committing if it succeeds
+func (b *SQLite) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// mkdirTx creates missing folders inside a transaction and returns the changes
+func mkdirTx(tx *sql.Tx, name string) ([]Change, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	var isDir bool
+	err := tx.QueryRow(`SELECT is_dir FROM files WHERE path = ?`, name).Scan(&isDir)
+	if err == nil {
+		if !isDir {
+			return nil, exists("mkdir", name)
+		}
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	changes, err := mkdirTx(tx, parent(name))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO files (path, parent, is_dir, data, mod_time) VALUES (?, ?, 1, NULL, ?)`,
+		name, parent(name), time.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, Change{Path: name, Op: Created, IsDir: true}), nil
+}
+
+// subtreeTx returns a path and everything inside it
+// Paths inside name sort from name+"/" up to name+"0" ('0' follows '/'), by
+// byte like Go strings, so this holds for names with any characters
+func subtreeTx(tx *sql.Tx, name string) ([]FileInfo, error) {
+	rows, err := tx.Query(`SELECT path, is_dir, length(data), mod_time FROM files
+		WHERE path = ? OR (path >= ? AND path < ?) ORDER BY path`, name, name+"/", name+"0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	infos := []FileInfo{}
+	for rows.Next() {
+		info, err := scanInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+// scanInfo reads a file info from a row of (path, is_dir, length(data), mod_time)
+func scanInfo(row interface{ Scan(...any) error }) (FileInfo, error) {
+	var (
+		info    FileInfo
+		size    sql.NullInt64
+		modTime int64
+	)
+	if err := row.Scan(&info.Path, &info.IsDir, &size, &modTime); err != nil {
+		return FileInfo{}, err
+	}
+	info.Size = size.Int64
+	info.ModTime = time.Unix(0, modTime)
+	return info, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+// errNotFile is returned when an open file is needed but the vault is not on the filesystem
+var errNotFile = errors.New("notes can only be opened as files with the filesystem backend")
+
+// rel returns the backend path of a path inside the vault
+func (s *Storage) rel(path string) (string, bool) {
+	rel, err := filepath.Rel(s.vaultDir, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return backend.Clean(filepath.ToSlash(rel)), true
+}
+
+// resolve returns the backend holding path, the backend path and the directory it is rooted at
+// Paths outside the vault (e.g. the scratch pad) are plain files on the filesystem
+func (s *Storage) resolve(path string) (backend.Backend, string, string) {
+	if rel, ok := s.rel(path); ok {
+		return s.backend, rel, s.vaultDir
+	}
+
+	dir := filepath.Dir(filepath.Clean(path))
+	return backend.NewFS(dir), filepath.Base(path), dir
+}
+
+// ReadFile returns the content of a file in the vault
+func (s *Storage) ReadFile(path string) ([]byte, error) {
+	b, name, _ := s.resolve(path)
+	return b.Read(name)
+}
+
+// WriteFile atomically replaces a file in the vault, creating its folder if needed
+func (s *Storage) WriteFile(path string, data []byte) error {
+	b, name, _ := s.resolve(path)
+	return b.Write(name, data)
+}
+
+// Stat describes a file or folder in the vault
+func (s *Storage) Stat(path string) (backend.FileInfo, error) {
+	b, name, _ := s.resolve(path)
+	return b.Stat(name)
+}
+
+// SameFile reports whether two paths name the same file, e.g. a case-only rename
+// on a case-insensitive filesystem
+func (s *Storage) SameFile(path1, path2 string) bool {
+	b1, name1, _ := s.resolve(path1)
+	b2, name2, _ := s.resolve(path2)
+	if b1 != s.backend || b2 != s.backend {
+		return filepath.Clean(path1) == filepath.Clean(path2)
+	}
+	return backend.SameFile(s.backend, name1, name2)
+}
+
+// ReadDir lists the direct children of a folder in the vault, sorted by name
+func (s *Storage) ReadDir(path string) ([]backend.FileInfo, error) {
+	b, name, _ := s.resolve(path)
+	return b.ReadDir(name)
+}
+
+// Mkdir creates a folder (and its parents) in the vault
+func (s *Storage) Mkdir(path string) error {
+	b, name, _ := s.resolve(path)
+	return b.Mkdir(name)
+}
+
+// Move renames a file or folder within the vault; the target must not exist
+// Note history and IDs move along with it
+func (s *Storage) Move(oldPath, newPath string) error {
+	oldBackend, oldName, _ := s.resolve(oldPath)
+	newBackend, newName, _ := s.resolve(newPath)
+	if oldBackend != s.backend || newBackend != s.backend {
+		return errors.New("can only move files within the vault")
+	}
+	if err := s.backend.Move(oldName, newName); err != nil {
+		return err
+	}
+
+	_ = s.history.MoveNote(oldPath, newPath)
+	_ = s.ids.MoveNote(oldPath, newPath)
+	return nil
+}
+
+// Remove deletes a file, or a folder with everything in it
+// Notes should normally go through DeleteNote, which uses the trash
+func (s *Storage) Remove(path string) error {
+	b, name, _ := s.resolve(path)
+	return b.Delete(name)
+}
+
+// Walk calls fn with the path and info of every file and folder under root,
+// skipping ignored folders such as .git and .trash
+// Returning fs.SkipDir from fn for a folder skips its contents
+func (s *Storage) Walk(root string, fn func(path string, info backend.FileInfo) error) error {
+	b, name, dir := s.resolve(root)
+	return backend.Walk(b, name, func(info backend.FileInfo) error {
+		return fn(filepath.Join(dir, filepath.FromSlash(info.Path)), info)
+	})
+}
@@ -0,0 +1,360 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// suffix is the extension used for lock files
+const suffix = ".lock"
+
+// StaleAfter is how long a lock held on another host stays valid without a refresh
+// Holders on this host are checked directly, so this only matters for shared folders
+const StaleAfter = 10 * time.Minute
+
+// DefaultTimeout is how long short updates wait for another process to release a lock
+const DefaultTimeout = 2 * time.Second
+
+// writeGrace is how long an empty lock file is treated as being written
+const writeGrace = 5 * time.Second
+
+// Holder identifies the totion process holding a lock
+type Holder struct {
+	PID   int       `json:"pid"`
+	Host  string    `json:"host"`
+	User  string    `json:"user,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+// String describes the holder, e.g. "pid 4242 on laptop (alice) since 14:05"
+func (h Holder) String() string {
+	if h.PID == 0 {
+		return "another process"
+	}
+
+	s := fmt.Sprintf("pid %d", h.PID)
+	if h.Host != "" {
+		s += " on " + h.Host
+	}
+	if h.User != "" {
+		s += " (" + h.User + ")"
+	}
+	if !h.Since.IsZero() {
+		s += " since " + h.Since.Format("Jan 2 15:04")
+	}
+	return s
+}
+
+// LockedError is returned when a file is locked by another totion process
+type LockedError struct {
+	Path   string
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s is in use by another totion (%s)", filepath.Base(e.Path), e.Holder)
+}
+
+// IsLocked checks if an error means a file is locked by another process
+// It returns the lock details if so
+func IsLocked(err error) (*LockedError, bool) {
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		return locked, true
+	}
+	return nil, false
+}
+
+// Lock is an advisory lock held on a file by this process
+// Other totion processes see it as a hidden .<name>.lock file next to the file
+type Lock struct {
+	target string
+	path   string
+	held   bool // Until released, guarded by localMu
+}
+
+// local is the in-process side of a lock: a lock file can't tell the
+// goroutines of one process apart, so they are kept out of each other here
+type local struct {
+	sem  chan struct{} // Full while a goroutine holds the lock
+	refs int           // Goroutines holding or waiting for the lock
+}
+
+var (
+	localMu sync.Mutex
+	locals  = make(map[string]*local)
+)
+
+// Path returns the lock file path for a file (e.g. dir/.note.md.lock)
+func Path(target string) string {
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+suffix)
+}
+
+// IsLockFile checks if a file name is a lock file
+func IsLockFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, suffix)
+}
+
+// Acquire locks a file for this process
+// Locks left by processes that have exited (or stopped refreshing) are taken over;
+// a live holder, or another goroutine of this process holding the lock, results
+// in a *LockedError. A lock file of this process that nothing in it holds (a
+// handle closed without releasing) is taken over too.
+func Acquire(target string) (*Lock, error) {
+	return acquire(target, time.Time{})
+}
+
+// acquire locks a file, waiting until deadline (if not zero) for other
+// goroutines of this process to release it
+func acquire(target string, deadline time.Time) (*Lock, error) {
+	l := &Lock{target: target, path: Path(target)}
+
+	if !l.lockLocal(deadline) {
+		return nil, &LockedError{Path: target, Holder: self()}
+	}
+	if err := l.lockFile(); err != nil {
+		l.unlockLocal()
+		return nil, err
+	}
+	return l, nil
+}
+
+// lockFile creates the lock file, taking over stale ones
+func (l *Lock) lockFile() error {
+	for attempt := 0; attempt < 2; attempt++ {
+		err := l.create()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("error creating lock file: %w", err)
+		}
+
+		holder, stale, err := inspect(l.path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // Released meanwhile
+			}
+			return fmt.Errorf("error reading lock file: %w", err)
+		}
+		if holder.isSelf() {
+			return l.Refresh() // Left over, no goroutine of ours holds it
+		}
+		if !stale {
+			return &LockedError{Path: l.target, Holder: holder}
+		}
+
+		if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("error removing stale lock file: %w", err)
+		}
+	}
+
+	holder, _, _ := inspect(l.path)
+	return &LockedError{Path: l.target, Holder: holder}
+}
+
+// lockLocal takes the in-process lock, waiting until deadline if it isn't zero
+func (l *Lock) lockLocal(deadline time.Time) bool {
+	localMu.Lock()
+	loc, ok := locals[l.path]
+	if !ok {
+		loc = &local{sem: make(chan struct{}, 1)}
+		locals[l.path] = loc
+	}
+	loc.refs++
+	localMu.Unlock()
+
+	locked := false
+	if deadline.IsZero() {
+		select {
+		case loc.sem <- struct{}{}:
+			locked = true
+		default:
+		}
+	} else {
+		timer := time.NewTimer(time.Until(deadline))
+		select {
+		case loc.sem <- struct{}{}:
+			locked = true
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	localMu.Lock()
+	defer localMu.Unlock()
+	if locked {
+		l.held = true
+	} else {
+		l.forget(loc)
+	}
+	return locked
+}
+
+// unlockLocal releases the in-process lock, once
+func (l *Lock) unlockLocal() {
+	localMu.Lock()
+	defer localMu.Unlock()
+	if !l.held {
+		return
+	}
+	l.held = false
+
+	loc := locals[l.path]
+	<-loc.sem
+	l.forget(loc)
+}
+
+// forget drops a reference to the in-process lock, and the lock once it has
+// none; localMu must be held
+func (l *Lock) forget(loc *local) {
+	loc.refs--
+	if loc.refs == 0 {
+		delete(locals, l.path)
+	}
+}
+
+// Do runs fn while holding the lock on a file, waiting up to timeout for
+// another process, or goroutine, to release it
+// Used for short read-modify-write updates such as index files. Do isn't
+// re-entrant: fn must not take the same lock again
+func Do(target string, timeout time.Duration, fn func() error) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		l, err := acquire(target, deadline)
+		if err == nil {
+			defer l.Release()
+			return fn()
+		}
+		if _, locked := IsLocked(err); !locked || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// DoLocal runs fn while holding an in-process lock on a key, waiting up to
+// timeout for another goroutine to release it
+// Used for files other processes can't see, e.g. in a memory or SQLite backend
+func DoLocal(key string, timeout time.Duration, fn func() error) error {
+	l := &Lock{target: key, path: key}
+	if !l.lockLocal(time.Now().Add(timeout)) {
+		return &LockedError{Path: key, Holder: self()}
+	}
+	defer l.unlockLocal()
+	return fn()
+}
+
+// Check returns the holder of a file's lock if another live process holds it
+func Check(target string) (*Holder, bool) {
+	holder, stale, err := inspect(Path(target))
+	if err != nil || stale || holder.isSelf() {
+		return nil, false
+	}
+	return &holder, true
+}
+
+// Target returns the locked file
+func (l *Lock) Target() string {
+	return l.target
+}
+
+// Refresh marks the lock as still in use, so other hosts don't consider it stale
+func (l *Lock) Refresh() error {
+	now := time.Now()
+	return os.Chtimes(l.path, now, now)
+}
+
+// Release removes the lock file, unless another process has taken it over
+func (l *Lock) Release() error {
+	localMu.Lock()
+	held := l.held
+	localMu.Unlock()
+	if !held {
+		return nil // Released already, the lock file may be someone else's now
+	}
+	defer l.unlockLocal()
+
+	holder, _, err := inspect(l.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error reading lock file: %w", err)
+	}
+	if !holder.isSelf() {
+		return nil
+	}
+
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error removing lock file: %w", err)
+	}
+	return nil
+}
+
+// create writes a new lock file for this process, failing if one exists
+func (l *Lock) create() error {
+	data, err := json.Marshal(self())
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(l.path)
+		return err
+	}
+	return f.Close()
+}
+
+// inspect reads a lock file and decides whether its holder is gone
+func inspect(path string) (Holder, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Holder{}, false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Holder{}, false, err
+	}
+
+	var holder Holder
+	if len(data) == 0 || json.Unmarshal(data, &holder) != nil || holder.PID == 0 {
+		// Still being written, or garbage left by a crash
+		return Holder{}, time.Since(info.ModTime()) > writeGrace, nil
+	}
+
+	if host, _ := os.Hostname(); holder.Host == host {
+		return holder, !processAlive(holder.PID), nil
+	}
+	return holder, time.Since(info.ModTime()) > StaleAfter, nil
+}
+
+// self describes this process
+func self() Holder {
+	h := Holder{PID: os.Getpid(), Since: time.Now()}
+	h.Host, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		h.User = u.Username
+	}
+	return h
+}
+
+// isSelf checks if the holder is this process
+func (h Holder) isSelf() bool {
+	host, _ := os.Hostname()
+	return h.PID == os.Getpid() && h.Host == host
+}
@@ -0,0 +1,88 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDoExcludesGoroutines(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "counter")
+	if err := os.WriteFile(target, []byte("0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := Do(target, 10*time.Second, func() error {
+				data, err := os.ReadFile(target)
+				if err != nil {
+					return err
+				}
+				n, _ := strconv.Atoi(string(data))
+				time.Sleep(time.Millisecond)
+				return os.WriteFile(target, []byte(strconv.Itoa(n+1)), 0644)
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, _ := os.ReadFile(target)
+	if string(data) != "20" {
+		t.Errorf("counter = %s, want 20 (lost updates)", data)
+	}
+	if _, err := os.Stat(Path(target)); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
+
+func TestNestedDoKeepsOuterLock(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "index.json")
+
+	err := Do(target, time.Second, func() error {
+		if err := Do(target, 100*time.Millisecond, func() error { return nil }); err == nil {
+			t.Error("nested Do on the same file succeeded")
+		}
+		if _, err := os.Stat(Path(target)); err != nil {
+			t.Errorf("outer lock file removed by the nested Do: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireHeldByGoroutine(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "note.md")
+
+	l, err := Acquire(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Acquire(target); err == nil {
+		t.Error("second Acquire succeeded while the lock is held")
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := Acquire(target)
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	_ = again.Release()
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/0xshariq/totion/internal/storage/backend"
+	"github.com/0xshariq/totion/internal/storage/lock"
+)
+
+// diskPath returns the filesystem path of a file, if it lives on disk
+// Locks are only needed there: other backends aren't shared between processes this way
+func (s *Storage) diskPath(path string) (string, bool) {
+	b, name, _ := s.resolve(path)
+	fsb, ok := b.(*backend.FS)
+	if !ok {
+		return "", false
+	}
+	return fsb.Abs(name), true
+}
+
+// lockNote locks a note for this process while it's open in the editor
+func (s *Storage) lockNote(path string) error {
+	diskPath, ok := s.diskPath(path)
+	if !ok {
+		return nil
+	}
+
+	s.locksMu.Lock()
+	_, open := s.locks[path]
+	s.locksMu.Unlock()
+	if open {
+		return nil // Opened again without closing, the lock is still ours
+	}
+
+	l, err := lock.Acquire(diskPath)
+	if err != nil {
+		return err
+	}
+
+	s.locksMu.Lock()
+	defer s.locksMu.Unlock()
+	s.locks[path] = l
+	return nil
+}
+
+// unlockNote releases the lock taken when a note was opened
+func (s *Storage) unlockNote(path string) error {
+	s.locksMu.Lock()
+	l, ok := s.locks[path]
+	delete(s.locks, path)
+	s.locksMu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return l.Release()
+}
+
+// checkLock fails if another totion process has the note open
+func (s *Storage) checkLock(path string) error {
+	diskPath, ok := s.diskPath(path)
+	if !ok {
+		return nil
+	}
+	if holder, locked := lock.Check(diskPath); locked {
+		return &lock.LockedError{Path: path, Holder: *holder}
+	}
+	return nil
+}
+
+// LockHolder returns the other totion process that has a note open, if any
+func (s *Storage) LockHolder(path string) (*lock.Holder, bool) {
+	diskPath, ok := s.diskPath(path)
+	if !ok {
+		return nil, false
+	}
+	return lock.Check(diskPath)
+}
+
+// OpenNoteReadOnly opens a note for reading without locking it
+// Used when another totion process has the note open
+func (s *Storage) OpenNoteReadOnly(path string) (*os.File, error) {
+	if _, inVault := s.rel(path); inVault {
+		if _, ok := s.backend.(*backend.FS); !ok {
+			return nil, errNotFile
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+
+	return f, nil
+}
+
+// CloseNote closes a note opened with OpenNote or CreateNote and releases its lock
+func (s *Storage) CloseNote(file *os.File) error {
+	if file == nil {
+		return nil
+	}
+
+	err := file.Close()
+	if unlockErr := s.unlockNote(file.Name()); unlockErr != nil {
+		return unlockErr
+	}
+	if err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+	return nil
+}
+
+// RefreshLocks marks the locks of open notes as still in use
+// Called periodically so totion on another host doesn't take them over
+func (s *Storage) RefreshLocks() {
+	s.locksMu.Lock()
+	defer s.locksMu.Unlock()
+	for _, l := range s.locks {
+		_ = l.Refresh()
+	}
+}
+
+// ReleaseLocks releases the locks of every note still open, e.g. on exit
+func (s *Storage) ReleaseLocks() {
+	s.locksMu.Lock()
+	locks := s.locks
+	s.locks = make(map[string]*lock.Lock)
+	s.locksMu.Unlock()
+
+	for _, l := range locks {
+		_ = l.Release()
+	}
+}
+
+// WithLock runs fn while holding the lock on a file such as an index, so two
+// totion processes, or goroutines such as API requests, don't interleave
+// read-modify-write updates
+func (s *Storage) WithLock(path string, fn func() error) error {
+	diskPath, ok := s.diskPath(path)
+	if !ok {
+		// Only this process sees the file, its goroutines still need keeping apart
+		return lock.DoLocal(path, lock.DefaultTimeout, fn)
+	}
+	return lock.Do(diskPath, lock.DefaultTimeout, fn)
+}
@@ -1,89 +1,215 @@
 package storage
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/0xshariq/totion/internal/config"
+	"github.com/0xshariq/totion/internal/features/history"
+	"github.com/0xshariq/totion/internal/features/ids"
+	"github.com/0xshariq/totion/internal/features/trash"
 	"github.com/0xshariq/totion/internal/models"
+	"github.com/0xshariq/totion/internal/storage/backend"
+	"github.com/0xshariq/totion/internal/storage/lock"
 )
 
 // Storage handles file operations
+// All vault access goes through a Backend; callers keep using absolute paths
+// under the vault directory, which Storage maps to backend paths.
 type Storage struct {
 	vaultDir string
+	backend  backend.Backend
+	trash    *trash.TrashManager
+	history  *history.HistoryManager
+	ids      *ids.IDManager
+
+	writesMu sync.Mutex
+	writes   map[string][sha256.Size]byte // Hash of the last content written to each note
+
+	locksMu sync.Mutex
+	locks   map[string]*lock.Lock // Locks on notes open in the editor
+}
+
+// New creates a new Storage instance for the configured vault
+func New() (*Storage, error) {
+	return NewWithVault(config.AppConfig.VaultDir)
+}
+
+// NewWithVault creates a Storage instance for a vault directory, kept in the
+// configured backend (storage.backend): files in the directory, or a SQLite
+// database (storage.path)
+func NewWithVault(vaultDir string) (*Storage, error) {
+	cfg := config.StorageConfig{Backend: config.StorageFS}
+	if config.AppConfig != nil {
+		cfg = config.AppConfig.Storage
+	}
+
+	if cfg.Backend != config.StorageSQLite {
+		return NewWithBackend(vaultDir, backend.NewFS(vaultDir)), nil
+	}
+	db, err := backend.NewSQLite(cfg.DatabasePath(vaultDir))
+	if err != nil {
+		return nil, fmt.Errorf("error opening vault database %s: %w", cfg.DatabasePath(vaultDir), err)
+	}
+	return NewWithBackend(vaultDir, db), nil
 }
 
-// New creates a new Storage instance
-func New() *Storage {
+// NewWithBackend creates a Storage instance for a vault kept in a backend
+// vaultDir is the path notes are addressed by; for non-filesystem backends it
+// only needs to be a stable absolute path (e.g. "/vault")
+func NewWithBackend(vaultDir string, b backend.Backend) *Storage {
+	vaultDir = filepath.Clean(vaultDir)
 	return &Storage{
-		vaultDir: config.AppConfig.VaultDir,
+		vaultDir: vaultDir,
+		backend:  b,
+		trash:    trash.NewTrashManagerWithBackend(vaultDir, b),
+		history:  history.NewHistoryManager(vaultDir, b),
+		ids:      ids.NewIDManager(vaultDir, b),
+		writes:   make(map[string][sha256.Size]byte),
+		locks:    make(map[string]*lock.Lock),
 	}
 }
 
-// ListNotes returns all notes in the vault
+// Backend returns the backend holding the vault
+func (s *Storage) Backend() backend.Backend {
+	return s.backend
+}
+
+// Close releases the backend, e.g. the SQLite database of the vault
+func (s *Storage) Close() error {
+	if closer, ok := s.backend.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ListNotes returns all notes in the vault, including notes inside notebooks
+// Hidden and config directories (e.g. .git, .trash) are skipped
 func (s *Storage) ListNotes() ([]models.Note, error) {
-	entries, err := os.ReadDir(s.vaultDir)
-	if err != nil {
+	if _, err := s.Stat(s.vaultDir); err != nil {
 		return nil, fmt.Errorf("error reading vault directory: %w", err)
 	}
 
+	noteIDs := s.ids.IDs()
 	notes := make([]models.Note, 0)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	err := s.Walk(s.vaultDir, func(path string, info backend.FileInfo) error {
+		if info.IsDir || IsHidden(info.Name()) || !IsNoteFile(info.Name()) {
+			return nil
 		}
 
-		// Only process .md and .txt files
-		ext := strings.ToLower(filepath.Ext(entry.Name()))
-		if ext != ".md" && ext != ".txt" {
-			continue
-		}
+		note := s.buildNote(path, info)
+		note.ID = noteIDs[path]
+		notes = append(notes, note)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading vault directory: %w", err)
+	}
 
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
+	return notes, nil
+}
 
-		format := models.FormatMarkdown
-		if ext == ".txt" {
-			format = models.FormatText
-		}
+// GetNote returns the note at the given path with its notebook identity filled in
+func (s *Storage) GetNote(path string) (models.Note, error) {
+	info, err := s.Stat(path)
+	if err != nil {
+		return models.Note{}, fmt.Errorf("error reading note info: %w", err)
+	}
+
+	note := s.buildNote(path, info)
+	note.ID = s.ids.ID(path)
+	return note, nil
+}
+
+// buildNote creates a note model for a file inside the vault
+func (s *Storage) buildNote(path string, info backend.FileInfo) models.Note {
+	name := filepath.Base(path)
 
-		notes = append(notes, models.Note{
-			Name:    entry.Name(),
-			Path:    filepath.Join(s.vaultDir, entry.Name()),
-			Format:  format,
-			ModTime: info.ModTime(),
-			Size:    info.Size(),
-		})
+	format := models.FormatForPath(name)
+
+	note := models.Note{
+		Name:    name,
+		Path:    path,
+		Format:  format,
+		ModTime: info.ModTime,
+		Size:    info.Size,
 	}
 
-	return notes, nil
+	relPath, ok := s.rel(path)
+	if !ok {
+		// Note lives outside the vault (e.g. scratch pad), keep its plain name
+		note.RelID = name
+		return note
+	}
+
+	note.RelID = relPath
+	if dir := filepath.Dir(filepath.FromSlash(relPath)); dir != "." {
+		note.Notebook = filepath.ToSlash(dir)
+		note.Depth = strings.Count(note.Notebook, "/") + 1
+	}
+
+	return note
+}
+
+// VaultDir returns the vault directory managed by this storage
+func (s *Storage) VaultDir() string {
+	return s.vaultDir
+}
+
+// IsNoteFile checks if a file name has the extension of a registered note format
+func IsNoteFile(name string) bool {
+	return models.IsNoteExt(filepath.Ext(name))
+}
+
+// IsHidden checks if a file or directory name is hidden (dot-prefixed)
+func IsHidden(name string) bool {
+	return backend.IsHidden(name)
+}
+
+// IsIgnoredDir checks if a directory should be skipped when scanning the vault
+func IsIgnoredDir(name string) bool {
+	return backend.IsIgnoredDir(name)
 }
 
 // CreateNote creates a new note with the specified name and format
+// Notes are returned as open files, so this needs the filesystem backend
+// The note is locked until it is closed with SaveNote or CloseNote
 func (s *Storage) CreateNote(name string, format models.FileFormat) (*os.File, string, error) {
 	filename := name + format.GetExtension()
 	filepath := filepath.Join(s.vaultDir, filename)
 
 	// Check if file already exists
-	if _, err := os.Stat(filepath); err == nil {
+	if _, err := s.Stat(filepath); err == nil {
 		return nil, "", fmt.Errorf("file already exists: %s", filename)
 	}
 
+	if _, ok := s.backend.(*backend.FS); !ok {
+		return nil, "", errNotFile
+	}
+
+	if err := s.lockNote(filepath); err != nil {
+		return nil, "", err
+	}
+
 	f, err := os.Create(filepath)
 	if err != nil {
+		_ = s.unlockNote(filepath)
 		return nil, "", fmt.Errorf("error creating file: %w", err)
 	}
+	_, _ = s.ids.Assign(filepath, nil)
 
 	return f, filepath, nil
 }
 
 // ReadNote reads the content of a note
 func (s *Storage) ReadNote(path string) (string, error) {
-	content, err := os.ReadFile(path)
+	content, err := s.ReadFile(path)
 	if err != nil {
 		return "", fmt.Errorf("error reading file: %w", err)
 	}
@@ -91,44 +217,174 @@ func (s *Storage) ReadNote(path string) (string, error) {
 	return string(content), nil
 }
 
+// LoadNote reads a note with its content and parsed front matter
+func (s *Storage) LoadNote(path string) (*models.Note, error) {
+	note, err := s.GetNote(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.ReadNote(path)
+	if err != nil {
+		return nil, err
+	}
+
+	note.Content = content
+	if meta, _, err := models.ParseFrontMatter(content); err == nil {
+		note.Meta = meta
+	}
+
+	return &note, nil
+}
+
+// WriteFrontMatter replaces the front matter of a note on disk
+func (s *Storage) WriteFrontMatter(path string, meta *models.FrontMatter) error {
+	if err := s.checkLock(path); err != nil {
+		return err
+	}
+
+	content, err := s.ReadNote(path)
+	if err != nil {
+		return err
+	}
+
+	meta.Updated = time.Now()
+	data := []byte(meta.Apply(content))
+	s.snapshot(path, data)
+	if err := s.WriteFile(path, data); err != nil {
+		return err
+	}
+	s.recordWrite(path, data)
+	return nil
+}
+
 // OpenNote opens a note for reading and writing
+// Like CreateNote, this needs the filesystem backend (or a note outside the vault)
+// The note is locked until it is closed with SaveNote or CloseNote; if another
+// totion process has it open, a *lock.LockedError naming that process is returned
+// and the note can still be opened with OpenNoteReadOnly
 func (s *Storage) OpenNote(path string) (*os.File, error) {
+	if _, inVault := s.rel(path); inVault {
+		if _, ok := s.backend.(*backend.FS); !ok {
+			return nil, errNotFile
+		}
+	}
+
+	if err := s.lockNote(path); err != nil {
+		return nil, err
+	}
+
 	f, err := os.OpenFile(path, os.O_RDWR, 0644)
 	if err != nil {
+		_ = s.unlockNote(path)
 		return nil, fmt.Errorf("error opening file: %w", err)
 	}
 
 	return f, nil
 }
 
-// SaveNote saves the content to the file
+// SaveNote atomically saves the content to the note, closes the file handle
+// and releases the note's lock
+// If the note has front matter, its "updated" field is refreshed
 func (s *Storage) SaveNote(file *os.File, content string) error {
-	if err := file.Truncate(0); err != nil {
-		return fmt.Errorf("error truncating file: %w", err)
+	if err := s.WriteNote(file.Name(), content); err != nil {
+		return err
 	}
 
-	if _, err := file.Seek(0, 0); err != nil {
-		return fmt.Errorf("error seeking file: %w", err)
+	// The handle points at the replaced file, it is only kept open by callers
+	return s.CloseNote(file)
+}
+
+// DeleteNote moves a note to the vault's trash
+// Use Trash() to restore or permanently delete it
+func (s *Storage) DeleteNote(path string) error {
+	if _, err := s.trash.Trash(path); err != nil {
+		return fmt.Errorf("error deleting file: %w", err)
+	}
+
+	// Unsaved edits of a deleted note should not be offered for recovery
+	_ = s.RemoveSwap(path)
+
+	return nil
+}
+
+// Trash returns the trash manager for the vault
+func (s *Storage) Trash() *trash.TrashManager {
+	return s.trash
+}
+
+// History returns the version history of the vault's notes
+func (s *Storage) History() *history.HistoryManager {
+	return s.history
+}
+
+// IDs returns the persistent IDs of the vault's notes
+func (s *Storage) IDs() *ids.IDManager {
+	return s.ids
+}
+
+// NoteID returns the persistent ID of a note, assigning one if needed
+func (s *Storage) NoteID(path string) (string, error) {
+	if id := s.ids.ID(path); id != "" {
+		return id, nil
 	}
 
-	if _, err := file.WriteString(content); err != nil {
-		return fmt.Errorf("error writing file: %w", err)
+	content, err := s.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading file: %w", err)
 	}
+	return s.ids.Assign(path, content)
+}
 
-	if err := file.Close(); err != nil {
-		return fmt.Errorf("error closing file: %w", err)
+// ResolveNote finds a note by its persistent ID, falling back to its last known path
+// Used by pins, recents and the tag index, which store both
+func (s *Storage) ResolveNote(id, path string) (models.Note, bool) {
+	if id != "" {
+		if current, ok := s.ids.Path(id); ok {
+			if note, err := s.GetNote(current); err == nil {
+				return note, true
+			}
+		}
+	}
+	if path == "" {
+		return models.Note{}, false
 	}
 
-	return nil
+	note, err := s.GetNote(path)
+	return note, err == nil
 }
 
-// DeleteNote deletes a note by its path
-func (s *Storage) DeleteNote(path string) error {
-	if err := os.Remove(path); err != nil {
-		return fmt.Errorf("error deleting file: %w", err)
+// SyncIDs gives every note in the vault an ID and finds the IDs of notes that
+// were moved outside Totion
+// Returns the number of notes found at a new path
+func (s *Storage) SyncIDs() (int, error) {
+	notes, err := s.ListNotes()
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	paths := make([]string, 0, len(notes))
+	for _, note := range notes {
+		paths = append(paths, note.Path)
+	}
+
+	items, err := s.trash.List()
+	if err != nil {
+		return 0, err
+	}
+	trashed := make([]string, 0, len(items))
+	for _, item := range items {
+		trashed = append(trashed, item.OriginalPath)
+	}
+	return s.ids.Sync(paths, trashed)
+}
+
+// touchFrontMatter sets the "updated" front matter field to the current time
+func touchFrontMatter(content string) string {
+	if !models.HasFrontMatter(content) {
+		return content
+	}
+	return models.SetFrontMatterValue(content, "updated", time.Now().Format(time.RFC3339))
 }
 
 // FilterByFormat filters notes by format
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xshariq/totion/internal/storage/backend"
+)
+
+// swapSuffix is the extension used for editor swap files
+const swapSuffix = ".swp"
+
+// SwapFile represents unsaved editor content left behind for a note
+type SwapFile struct {
+	NotePath    string
+	SwapPath    string
+	ModTime     time.Time // When the swap file was last written
+	NoteModTime time.Time // Zero if the note no longer exists
+}
+
+// NoteName returns the file name of the note the swap file belongs to
+func (sf SwapFile) NoteName() string {
+	return filepath.Base(sf.NotePath)
+}
+
+// SwapPath returns the swap file path for a note (e.g. dir/.note.md.swp)
+func SwapPath(notePath string) string {
+	return filepath.Join(filepath.Dir(notePath), "."+filepath.Base(notePath)+swapSuffix)
+}
+
+// notePathFromSwap returns the note path a swap file belongs to
+func notePathFromSwap(swapPath string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(swapPath), "."), swapSuffix)
+	return filepath.Join(filepath.Dir(swapPath), name)
+}
+
+// WriteSwap stores the current editor buffer for a note in its swap file
+// On the filesystem swap files are only readable by the user, like the editor buffer
+func (s *Storage) WriteSwap(notePath, content string) error {
+	b, name, _ := s.resolve(SwapPath(notePath))
+
+	var err error
+	if fsb, ok := b.(*backend.FS); ok {
+		err = backend.WriteFileAtomic(fsb.Abs(name), []byte(content), 0600)
+	} else {
+		err = b.Write(name, []byte(content))
+	}
+	if err != nil {
+		return fmt.Errorf("error writing swap file: %w", err)
+	}
+	return nil
+}
+
+// RemoveSwap deletes the swap file for a note, if any
+func (s *Storage) RemoveSwap(notePath string) error {
+	if err := s.Remove(SwapPath(notePath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ReadSwap returns the content saved in a swap file
+func (s *Storage) ReadSwap(sf SwapFile) (string, error) {
+	content, err := s.ReadFile(sf.SwapPath)
+	if err != nil {
+		return "", fmt.Errorf("error reading swap file: %w", err)
+	}
+	return string(content), nil
+}
+
+// FindSwapFiles scans the vault for swap files left by an interrupted session
+// Swap files whose content matches the saved note are removed silently, and
+// notes another totion process has open are skipped
+func (s *Storage) FindSwapFiles() ([]SwapFile, error) {
+	swaps := []SwapFile{}
+
+	err := s.Walk(s.vaultDir, func(path string, info backend.FileInfo) error {
+		name := info.Name()
+		if info.IsDir || !IsHidden(name) || !strings.HasSuffix(name, swapSuffix) {
+			return nil
+		}
+
+		notePath := notePathFromSwap(path)
+		if !IsNoteFile(notePath) {
+			return nil
+		}
+
+		// The swap file belongs to a totion process that still has the note open
+		if s.checkLock(notePath) != nil {
+			return nil
+		}
+
+		sf := SwapFile{
+			NotePath: notePath,
+			SwapPath: path,
+			ModTime:  info.ModTime,
+		}
+
+		if noteInfo, err := s.Stat(notePath); err == nil {
+			sf.NoteModTime = noteInfo.ModTime
+
+			// Nothing to recover if the note already has the swapped content
+			swapContent, errSwap := s.ReadFile(path)
+			noteContent, errNote := s.ReadFile(notePath)
+			if errSwap == nil && errNote == nil && string(swapContent) == string(noteContent) {
+				_ = s.Remove(path)
+				return nil
+			}
+		}
+
+		swaps = append(swaps, sf)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning for swap files: %w", err)
+	}
+
+	return swaps, nil
+}
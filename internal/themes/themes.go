@@ -14,23 +14,43 @@ type Theme struct {
 	Success    lipgloss.Color
 }
 
+// BuiltinThemes lists the names of the themes shipped with Totion
+var BuiltinThemes = []string{"default", "dark", "light", "monokai", "solarized-dark", "nord"}
+
+// IsBuiltin checks if name is one of the built-in themes
+func IsBuiltin(name string) bool {
+	for _, builtin := range BuiltinThemes {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
 // ThemeManager handles theme management
 type ThemeManager struct {
 	themes       map[string]Theme
 	currentTheme string
 }
 
-// NewThemeManager creates a new theme manager
-func NewThemeManager() *ThemeManager {
+// NewThemeManager creates a new theme manager starting with the given theme
+// Unknown theme names fall back to the default theme
+func NewThemeManager(theme string) *ThemeManager {
 	tm := &ThemeManager{
 		themes:       make(map[string]Theme),
 		currentTheme: "default",
 	}
 
 	tm.initializeThemes()
+	tm.SetTheme(theme)
 	return tm
 }
 
+// CurrentName returns the key of the current theme (e.g. "nord")
+func (tm *ThemeManager) CurrentName() string {
+	return tm.currentTheme
+}
+
 // initializeThemes sets up built-in themes
 func (tm *ThemeManager) initializeThemes() {
 	// Default (Pink) Theme
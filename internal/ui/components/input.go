@@ -17,3 +17,16 @@ func NewFileNameInput() textinput.Model {
 	ti.TextStyle = styles.CursorStyle
 	return ti
 }
+
+// NewSearchInput creates a new text input for search queries
+func NewSearchInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Search notes... (#tag for tags)"
+	ti.Focus()
+	ti.CharLimit = 256
+	ti.Width = 60
+	ti.Cursor.Style = styles.CursorStyle
+	ti.PromptStyle = styles.CursorStyle
+	ti.TextStyle = styles.CursorStyle
+	return ti
+}
@@ -29,7 +29,7 @@ func GetDeveloperCorePackages() string {
 		dimStyle.Render("   Core file management and note CRUD operations") + "\n" +
 		dimStyle.Render("   • Create, read, update, delete notes") + "\n" +
 		dimStyle.Render("   • Manages vault directory (~/.totion)") + "\n" +
-		dimStyle.Render("   • Supports .md and .txt formats") + "\n\n" +
+		dimStyle.Render("   • Supports .md, .txt, .org and .adoc formats") + "\n\n" +
 
 		textStyle.Render("📊 internal/features/stats") + "\n" +
 		dimStyle.Render("   Statistics, analytics, and dashboard metrics") + "\n" +
@@ -107,8 +107,8 @@ func GetDeveloperStorage() string {
 		successStyle.Render("INITIALIZATION:") + "\n" +
 		codeStyle.Render("  import \"github.com/0xshariq/totion/internal/storage\"") + "\n" +
 		codeStyle.Render("  import \"github.com/0xshariq/totion/internal/models\"") + "\n\n" +
-		codeStyle.Render("  storage := storage.New()") + "\n" +
-		dimStyle.Render("  // Automatically creates ~/.totion if not exists") + "\n\n" +
+		codeStyle.Render("  storage, err := storage.New()") + "\n" +
+		dimStyle.Render("  // Opens the configured vault (vault_dir) in the storage.backend set in the config") + "\n\n" +
 
 		successStyle.Render("CORE METHODS:") + "\n\n" +
 
@@ -116,16 +116,21 @@ func GetDeveloperStorage() string {
 		codeStyle.Render("  CreateNote(name string, format models.FileFormat) (*os.File, error)") + "\n" +
 		dimStyle.Render("  • Creates a new note file in vault") + "\n" +
 		dimStyle.Render("  • name: Filename without extension (e.g., \"todo\")") + "\n" +
-		dimStyle.Render("  • format: models.FormatMarkdown or models.FormatText") + "\n" +
+		dimStyle.Render("  • format: models.FormatMarkdown, FormatText, FormatOrg or FormatAsciiDoc") + "\n" +
+		dimStyle.Render("  • More formats: models.RegisterFormat(models.FormatInfo{...})") + "\n" +
 		dimStyle.Render("  • Returns: Open file handle for writing") + "\n" +
 		dimStyle.Render("  • Error: File already exists, permission denied, etc.") + "\n\n" +
 
 		textStyle.Render("LIST NOTES:") + "\n" +
 		codeStyle.Render("  ListNotes() ([]models.Note, error)") + "\n" +
-		dimStyle.Render("  • Returns all notes in vault directory") + "\n" +
+		dimStyle.Render("  • Returns all notes in vault, including notebook subfolders") + "\n" +
 		dimStyle.Render("  • Each Note contains: Name, Path, Format, ModTime, Size") + "\n" +
-		dimStyle.Render("  • Excludes hidden files and system files") + "\n" +
-		dimStyle.Render("  • Sorted by modification time (newest first)") + "\n\n" +
+		dimStyle.Render("  • Plus Notebook (folder path), RelID (vault-relative path), Depth") + "\n" +
+		dimStyle.Render("  • Excludes hidden files and hidden/config folders (.git, .trash)") + "\n\n" +
+
+		textStyle.Render("NOTE INFO:") + "\n" +
+		codeStyle.Render("  GetNote(path string) (models.Note, error)") + "\n" +
+		dimStyle.Render("  • Builds a Note with notebook identity for any vault path") + "\n\n" +
 
 		textStyle.Render("READ NOTES:") + "\n" +
 		codeStyle.Render("  ReadNote(path string) (string, error)") + "\n" +
@@ -134,22 +139,67 @@ func GetDeveloperStorage() string {
 		dimStyle.Render("  • Returns: File content as string") + "\n" +
 		dimStyle.Render("  • Handles large files efficiently") + "\n\n" +
 
+		textStyle.Render("FRONT MATTER:") + "\n" +
+		codeStyle.Render("  LoadNote(path string) (*models.Note, error)") + "\n" +
+		dimStyle.Render("  • Reads content and parses YAML front matter into note.Meta") + "\n" +
+		codeStyle.Render("  WriteFrontMatter(path string, meta *models.FrontMatter) error") + "\n" +
+		dimStyle.Render("  • Replaces the front matter block, keeping the note body") + "\n\n" +
+
 		textStyle.Render("SAVE NOTES:") + "\n" +
 		codeStyle.Render("  SaveNote(file *os.File, content string) error") + "\n" +
 		dimStyle.Render("  • Writes content to file and closes it") + "\n" +
-		dimStyle.Render("  • Automatically syncs to disk") + "\n" +
-		dimStyle.Render("  • Truncates file before writing (overwrites)") + "\n" +
-		dimStyle.Render("  • Closes file handle after write") + "\n\n" +
+		dimStyle.Render("  • Atomic: temp file + fsync + rename, never half-written") + "\n" +
+		dimStyle.Render("  • Closes file handle after write") + "\n" +
+		codeStyle.Render("  WriteNote(path, content string) error") + "\n" +
+		dimStyle.Render("  • Atomic write by path (used by auto-save)") + "\n\n" +
+
+		textStyle.Render("SWAP FILES:") + "\n" +
+		codeStyle.Render("  WriteSwap(notePath, content string) error") + "\n" +
+		dimStyle.Render("  • Stores unsaved editor content in .<note>.swp") + "\n" +
+		codeStyle.Render("  FindSwapFiles() ([]SwapFile, error)") + "\n" +
+		dimStyle.Render("  • Lists swap files left by a crash (offered for recovery)") + "\n\n" +
+
+		textStyle.Render("LOCKING:") + "\n" +
+		codeStyle.Render("  OpenNote(path string) (*os.File, error)") + "\n" +
+		dimStyle.Render("  • Locks the note with .<note>.lock until SaveNote/CloseNote") + "\n" +
+		dimStyle.Render("  • Returns *lock.LockedError if another totion has it open") + "\n" +
+		codeStyle.Render("  OpenNoteReadOnly(path string) (*os.File, error)") + "\n" +
+		dimStyle.Render("  • Read-only fallback for locked notes; WriteNote refuses them") + "\n" +
+		codeStyle.Render("  WithLock(path string, fn func() error) error") + "\n" +
+		dimStyle.Render("  • Serializes index updates (tags, stats, pins) across processes") + "\n" +
+		dimStyle.Render("  • Locks of exited processes are detected as stale and taken over") + "\n\n" +
 
 		textStyle.Render("DELETE NOTES:") + "\n" +
 		codeStyle.Render("  DeleteNote(path string) error") + "\n" +
-		dimStyle.Render("  • Permanently deletes note file") + "\n" +
-		dimStyle.Render("  • No trash/recycle bin - cannot undo!") + "\n" +
-		dimStyle.Render("  • Use with caution in production") + "\n\n" +
+		dimStyle.Render("  • Moves the note to <vault>/.trash") + "\n" +
+		codeStyle.Render("  Trash() *trash.TrashManager") + "\n" +
+		dimStyle.Render("  • List(), Restore(id), Purge(id), Empty(), PurgeExpired(retention)") + "\n\n" +
+
+		textStyle.Render("VERSION HISTORY:") + "\n" +
+		codeStyle.Render("  History() *history.HistoryManager") + "\n" +
+		dimStyle.Render("  • WriteNote snapshots the previous content into <vault>/.history") + "\n" +
+		dimStyle.Render("  • Versions(path), Read(version), Prune(); history.Diff(old, new)") + "\n\n" +
+
+		textStyle.Render("NOTE IDS:") + "\n" +
+		codeStyle.Render("  NoteID(path string) (string, error)") + "\n" +
+		dimStyle.Render("  • Persistent ID kept in <vault>/.ids.json (or front matter id:)") + "\n" +
+		codeStyle.Render("  ResolveNote(id, path string) (models.Note, bool)") + "\n" +
+		dimStyle.Render("  • Finds a note by ID, falling back to its last known path") + "\n" +
+		codeStyle.Render("  SyncIDs() (int, error)") + "\n" +
+		dimStyle.Render("  • Assigns missing IDs and finds notes moved outside Totion") + "\n\n" +
+
+		textStyle.Render("STORAGE BACKENDS:") + "\n" +
+		codeStyle.Render("  NewWithBackend(vaultDir string, b backend.Backend) *Storage") + "\n" +
+		dimStyle.Render("  • Package internal/storage/backend: list, read, write, move, delete, stat, watch") + "\n" +
+		dimStyle.Render("  • backend.NewFS(dir) - plain files (default, storage.backend: fs)") + "\n" +
+		dimStyle.Render("  • backend.NewMemory() - in memory, for tests") + "\n" +
+		dimStyle.Render("  • backend.NewSQLite(path) - the whole vault in one database file (storage.backend: sqlite)") + "\n" +
+		dimStyle.Render("  • Search, tags, notebooks, export and sync all go through Storage") + "\n" +
+		dimStyle.Render("  • CreateNote/OpenNote return *os.File, so they need the FS backend") + "\n\n" +
 
 		textStyle.Render("VAULT PATH:") + "\n" +
 		codeStyle.Render("  GetVaultPath() string") + "\n" +
-		dimStyle.Render("  • Returns ~/.totion directory path") + "\n" +
+		dimStyle.Render("  • Returns the configured vault_dir (default ~/.totion)") + "\n" +
 		dimStyle.Render("  • Use for custom file operations") + "\n\n" +
 
 		successStyle.Render("COMPLETE EXAMPLE:") + "\n" +
@@ -161,7 +211,10 @@ func GetDeveloperStorage() string {
 		codeStyle.Render("  )") + "\n\n" +
 		codeStyle.Render("  func main() {") + "\n" +
 		codeStyle.Render("    // Initialize storage") + "\n" +
-		codeStyle.Render("    s := storage.New()") + "\n\n" +
+		codeStyle.Render("    s, err := storage.New()") + "\n" +
+		codeStyle.Render("    if err != nil {") + "\n" +
+		codeStyle.Render("      log.Fatal(err)") + "\n" +
+		codeStyle.Render("    }") + "\n\n" +
 		codeStyle.Render("    // Create a new markdown note") + "\n" +
 		codeStyle.Render("    file, err := s.CreateNote(\"meeting-notes\", models.FormatMarkdown)") + "\n" +
 		codeStyle.Render("    if err != nil {") + "\n" +
@@ -5,15 +5,15 @@ func GetDeveloperAdvanced() string {
 	return headerStyle.Render("🚀 ADVANCED FEATURES API") + "\n\n" +
 		successStyle.Render("PINNED NOTES:") + "\n" +
 		successStyle.Render("PACKAGE: internal/features/pinned") + "\n\n" +
-		codeStyle.Render("  pm := pinned.NewPinnedManager(configDir)") + "\n\n" +
-		dimStyle.Render("  • Pin(path, name) error - Pin a note") + "\n" +
+		codeStyle.Render("  pm := pinned.NewPinnedManager(configDir, cfg.MaxPinned)") + "\n\n" +
+		dimStyle.Render("  • Pin(note *models.Note) error - Pin a note") + "\n" +
 		dimStyle.Render("  • Unpin(path) error - Unpin note") + "\n" +
-		dimStyle.Render("  • Toggle(path, name) (bool, error)") + "\n" +
+		dimStyle.Render("  • Toggle(note *models.Note) (bool, error)") + "\n" +
 		dimStyle.Render("  • IsPinned(path) bool") + "\n" +
 		dimStyle.Render("  • GetPinned() []PinnedNote") + "\n\n" +
 		successStyle.Render("TAG SYSTEM:") + "\n" +
 		successStyle.Render("PACKAGE: internal/features/tags") + "\n\n" +
-		codeStyle.Render("  tm := tags.NewTagManager(vaultDir, configDir)") + "\n\n" +
+		codeStyle.Render("  tm := tags.NewTagManager(store, configDir)") + "\n\n" +
 		dimStyle.Render("  • ExtractTags(content) []string") + "\n" +
 		dimStyle.Render("    Extract # from text") + "\n\n" +
 		dimStyle.Render("  • RebuildIndex() error") + "\n" +
@@ -24,7 +24,7 @@ func GetDeveloperAdvanced() string {
 		dimStyle.Render("    List all tags sorted by frequency") + "\n\n" +
 		successStyle.Render("SEARCH:") + "\n" +
 		successStyle.Render("PACKAGE: internal/features/search") + "\n\n" +
-		codeStyle.Render("  sm := search.NewSearchManager(vaultDir)") + "\n\n" +
+		codeStyle.Render("  sm := search.NewSearchManager(store)") + "\n\n" +
 		dimStyle.Render("  • Search(query) ([]SearchResult, error)") + "\n" +
 		dimStyle.Render("    Full-text search across all notes") + "\n\n" +
 		dimStyle.Render("  • SearchInNote(path, query) ([]SearchResult, error)") + "\n" +
@@ -39,9 +39,9 @@ func GetDeveloperExamples() string {
 	return headerStyle.Render("💡 CODE EXAMPLES") + "\n\n" +
 		successStyle.Render("COMPLETE WORKFLOW:") + "\n\n" +
 		codeStyle.Render("  // Initialize managers") + "\n" +
-		codeStyle.Render("  storage := storage.New()") + "\n" +
+		codeStyle.Render("  storage, _ := storage.New()") + "\n" +
 		codeStyle.Render("  statsM := stats.NewStatsManager()") + "\n" +
-		codeStyle.Render("  tagM := tags.NewTagManager(vault, config)") + "\n\n" +
+		codeStyle.Render("  tagM := tags.NewTagManager(storage, config)") + "\n\n" +
 		codeStyle.Render("  // Create and analyze note") + "\n" +
 		codeStyle.Render("  file, _ := storage.CreateNote(\"My Note\", models.FormatMarkdown)") + "\n" +
 		codeStyle.Render("  content := \"# Hello\\nThis is a #test note\"") + "\n" +
@@ -54,7 +54,7 @@ func GetDeveloperExamples() string {
 		codeStyle.Render("  tagM.IndexNote(file.Name())") + "\n\n" +
 		successStyle.Render("SEARCH & FILTER:") + "\n\n" +
 		codeStyle.Render("  // Full-text search") + "\n" +
-		codeStyle.Render("  searchM := search.NewSearchManager(vault)") + "\n" +
+		codeStyle.Render("  searchM := search.NewSearchManager(storage)") + "\n" +
 		codeStyle.Render("  results, _ := searchM.Search(\"golang\")") + "\n" +
 		codeStyle.Render("  for _, r := range results {") + "\n" +
 		codeStyle.Render("    fmt.Printf(\"%s: %s\\n\", r.NoteName, r.MatchSnippet)") + "\n" +
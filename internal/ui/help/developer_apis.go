@@ -62,7 +62,7 @@ func GetDeveloperTemplates() string {
 	return headerStyle.Render("📝 TEMPLATES & THEMES API") + "\n\n" +
 		successStyle.Render("PACKAGE: internal/features/templates") + "\n\n" +
 		textStyle.Render("TEMPLATE MANAGEMENT:") + "\n" +
-		codeStyle.Render("  tm := templates.NewTemplateManager()") + "\n\n" +
+		codeStyle.Render("  tm := templates.NewTemplateManager(configDir)") + "\n\n" +
 		dimStyle.Render("  • GetTemplate(name string) (string, error)") + "\n" +
 		dimStyle.Render("    Retrieve template content") + "\n\n" +
 		dimStyle.Render("  • SaveCustomTemplate(name, content) error") + "\n" +
@@ -78,7 +78,7 @@ func GetDeveloperTemplates() string {
 		dimStyle.Render("  • research - Research notes") + "\n" +
 		dimStyle.Render("  • book - Book summary") + "\n\n" +
 		successStyle.Render("EXAMPLE:") + "\n" +
-		codeStyle.Render("  tm := templates.NewTemplateManager()") + "\n" +
+		codeStyle.Render("  tm := templates.NewTemplateManager(configDir)") + "\n" +
 		codeStyle.Render("  content, err := tm.GetTemplate(\"meeting\")") + "\n" +
 		codeStyle.Render("  if err != nil { log.Fatal(err) }") + "\n\n" +
 		dimStyle.Render("Press Esc to go back")
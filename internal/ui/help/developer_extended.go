@@ -43,16 +43,16 @@ func GetDeveloperNotebooks() string {
 	return headerStyle.Render("📂 NOTEBOOKS ORGANIZATION API") + "\n\n" +
 		successStyle.Render("PACKAGE: internal/notebook") + "\n\n" +
 		textStyle.Render("INITIALIZATION:") + "\n" +
-		codeStyle.Render("  nm := notebook.NewNotebookManager(vaultDir)") + "\n\n" +
+		codeStyle.Render("  nm := notebook.NewNotebookManager(store)") + "\n\n" +
 		textStyle.Render("CORE METHODS:") + "\n" +
 		dimStyle.Render("  • CreateNotebook(name string) error") + "\n" +
 		dimStyle.Render("  • ListNotebooks() ([]string, error)") + "\n" +
 		dimStyle.Render("  • GetNotebookInfo(path) (NotebookInfo, error)") + "\n" +
 		dimStyle.Render("  • RenameNotebook(old, new string) error") + "\n" +
-		dimStyle.Render("  • DeleteNotebook(name string) error") + "\n" +
+		dimStyle.Render("  • DeleteNotebook(path string) error - moves to trash") + "\n" +
 		dimStyle.Render("  • MoveNoteToNotebook(notePath, notebook) error") + "\n\n" +
 		successStyle.Render("EXAMPLE:") + "\n" +
-		codeStyle.Render("  nm := notebook.NewNotebookManager(vault)") + "\n" +
+		codeStyle.Render("  nm := notebook.NewNotebookManager(store)") + "\n" +
 		codeStyle.Render("  nm.CreateNotebook(\"Work\")") + "\n" +
 		codeStyle.Render("  nm.MoveNoteToNotebook(\"todo.md\", \"Work\")") + "\n\n" +
 		dimStyle.Render("Press Esc to go back")
@@ -70,7 +70,8 @@ func GetDeveloperBestPractices() string {
 		successStyle.Render("3. PERSISTENCE:") + "\n" +
 		textStyle.Render("  • .stats.json - Statistics history") + "\n" +
 		textStyle.Render("  • .pinned_notes.json - Pinned notes") + "\n" +
-		textStyle.Render("  • .tags.json - Tag index") + "\n\n" +
+		textStyle.Render("  • .tags.json - Tag index") + "\n" +
+		textStyle.Render("  • .search-index.json - Full-text search index") + "\n\n" +
 		successStyle.Render("4. THREAD SAFETY:") + "\n" +
 		textStyle.Render("  • All managers are thread-safe") + "\n" +
 		textStyle.Render("  • JSON operations are atomic") + "\n\n" +
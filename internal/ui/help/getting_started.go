@@ -37,7 +37,7 @@ func GetGettingStarted(translate func(string) string) string {
 		textStyle.Render("  • "+translate("Search across notes with Ctrl+/ (full-text search)")) + "\n" +
 		textStyle.Render("  • "+translate("Add # in notes for cross-cutting organization")) + "\n" +
 		textStyle.Render("  • "+translate("Use Alt+T to change UI language (works from any screen)")) + "\n" +
-		textStyle.Render("  • "+translate("Auto-save runs every 30 seconds in editor")) + "\n\n" +
+		textStyle.Render("  • "+translate("Auto-save runs every 30 seconds in editor (change it in Settings, C)")) + "\n\n" +
 		textStyle.Render(translate("STORAGE:")) + "\n" +
 		textStyle.Render("  "+translate("All notes are saved in: ~/.totion/")) + "\n" +
 		textStyle.Render("  "+translate("Format: .md (Markdown) or .txt (Plain Text)")) + "\n\n" +
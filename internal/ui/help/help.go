@@ -29,6 +29,7 @@ func GetHelpMenu(translate func(string) string) string {
 		menuStyle.Render("  8. ☁️  "+translate("Sync & Backup - Cloud sync setup")) + "\n" +
 		menuStyle.Render("  9. 📂 "+translate("Notebooks & Organization - Folder system")) + "\n" +
 		menuStyle.Render("  0. 💻 "+translate("Developer Integration - API docs")) + "\n" +
+		menuStyle.Render("  S. 🔍 "+translate("Search - Query language and filters")) + "\n" +
 		menuStyle.Render("  T. 🌐 "+translate("UI Translation - Multi-language support")) + "\n\n" +
-		dimStyle.Render(translate("Press 1-9, 0, or T to view a topic")+" • "+translate("Press Esc to go back to home"))
+		dimStyle.Render(translate("Press 1-9, 0, S or T to view a topic")+" • "+translate("Press Esc to go back to home"))
 }
@@ -11,8 +11,8 @@ func GetNotebooksGuide(translate func(string) string) string {
 		textStyle.Render(translate("  1. Create Notebook - New folder")) + "\n" +
 		textStyle.Render(translate("  2. List Notebooks - View all folders")) + "\n" +
 		textStyle.Render(translate("  3. Move Note - Organize notes")) + "\n" +
-		textStyle.Render(translate("  4. Rename Notebook - Change folder name")) + "\n" +
-		textStyle.Render(translate("  5. Delete Notebook - Remove folder")) + "\n" +
+		textStyle.Render(translate("  4. Rename Notebook - Change folder name, [[links]] are updated")) + "\n" +
+		textStyle.Render(translate("  5. Delete Notebook - Move folder to trash (X to restore)")) + "\n" +
 		textStyle.Render(translate("  6. Create Note in Notebook - Direct creation")) + "\n\n" +
 		textStyle.Render(translate("BENEFITS:")) + "\n" +
 		textStyle.Render(translate("  • Keep related notes together")) + "\n" +
@@ -0,0 +1,34 @@
+package help
+
+// GetSearchGuide returns the search query language guide
+func GetSearchGuide(translate func(string) string) string {
+	return headerStyle.Render(translate("🔍 SEARCH")) + "\n\n" +
+		textStyle.Render(translate("HOW TO SEARCH:")) + "\n" +
+		textStyle.Render(translate("  • Press Ctrl+/ anywhere (or / on the home screen)")) + "\n" +
+		textStyle.Render(translate("  • Type a query and press Enter, Enter again opens the selected note")) + "\n" +
+		textStyle.Render(translate("  • Best matching notes come first, PgUp/PgDn to page")) + "\n\n" +
+		textStyle.Render(translate("WORDS AND OPERATORS:")) + "\n" +
+		codeStyle.Render("  meeting notes") + textStyle.Render("          "+translate("Notes with both words")) + "\n" +
+		codeStyle.Render(`  "meeting notes"`) + textStyle.Render("        "+translate("The exact phrase")) + "\n" +
+		codeStyle.Render("  budget OR invoice") + textStyle.Render("      "+translate("Either word")) + "\n" +
+		codeStyle.Render("  budget NOT draft") + textStyle.Render("       "+translate("Without a word")) + "\n" +
+		codeStyle.Render("  (a OR b) AND c") + textStyle.Render("         "+translate("Group with parentheses")) + "\n" +
+		dimStyle.Render("  "+translate("AND, OR and NOT are written in capitals, AND is optional")) + "\n\n" +
+		textStyle.Render(translate("FILTERS:")) + "\n" +
+		codeStyle.Render("  #work  tag:work") + textStyle.Render("        "+translate("Notes with a tag")) + "\n" +
+		codeStyle.Render("  notebook:projects") + textStyle.Render("      "+translate("Notes in a notebook (or notebook:work/sub)")) + "\n" +
+		codeStyle.Render("  format:org") + textStyle.Render("             "+translate("Notes of a format: md, txt, org, adoc")) + "\n" +
+		codeStyle.Render("  modified:>2026-01-01") + textStyle.Render("   "+translate("Changed after a day (also >=, <, <=, or the day itself)")) + "\n" +
+		codeStyle.Render("  has:task") + textStyle.Render("               "+translate("Notes with checkboxes or TODO headlines")) + "\n" +
+		codeStyle.Render(`  title:plan  title:"q3 plan"`) + textStyle.Render(" "+translate("Name, title or alias contains")) + "\n\n" +
+		textStyle.Render(translate("EXAMPLE:")) + "\n" +
+		codeStyle.Render(`  has:task notebook:work NOT "done" modified:>=2026-01-01`) + "\n\n" +
+		textStyle.Render(translate("MODES (Tab switches, matches are highlighted):")) + "\n" +
+		codeStyle.Render("  exact") + textStyle.Render("                  "+translate("Words as written, also inside longer words")) + "\n" +
+		codeStyle.Render("  fuzzy") + textStyle.Render("                  "+translate("Typos and left out letters too, note names too (mtg → meeting)")) + "\n" +
+		codeStyle.Render("  regex") + textStyle.Render("                  "+translate("A regular expression (RE2) matched against each line")) + "\n" +
+		codeStyle.Render(`  PROJ-(\d+)`) + textStyle.Render("             "+translate("Ticket IDs, the number highlighted as a capture group")) + "\n" +
+		dimStyle.Render("  "+translate("Regex ignores case unless it starts with (?-i), and stops after 5s or 10,000 matches")) + "\n\n" +
+		dimStyle.Render(translate("A malformed query shows what is wrong and where")) + "\n" +
+		dimStyle.Render(translate("Press Esc to go back"))
+}
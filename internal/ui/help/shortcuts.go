@@ -7,6 +7,7 @@ func GetKeyboardShortcuts(translate func(string) string) string {
 		textStyle.Render(translate("  Ctrl+N      Create new note")) + "\n" +
 		textStyle.Render(translate("  Ctrl+L      List all notes")) + "\n" +
 		textStyle.Render(translate("  Ctrl+D      Daily note (home) / Delete (list)")) + "\n" +
+		textStyle.Render(translate("  Ctrl+R      Rename/move note (list), updates [[links]]")) + "\n" +
 		textStyle.Render(translate("  Ctrl+Q      Quick note (scratch pad)")) + "\n" +
 		textStyle.Render(translate("  Ctrl+S      Save and close editor")) + "\n" +
 		textStyle.Render(translate("  Enter       Open/Create note")) + "\n" +
@@ -16,18 +17,22 @@ func GetKeyboardShortcuts(translate func(string) string) string {
 		textStyle.Render(translate("  Alt+T       Translate note to another language")) + "\n" +
 		textStyle.Render(translate("  Alt+P       Pin/unpin current note (max 10)")) + "\n" +
 		textStyle.Render(translate("  Alt+L       Wiki linking help")) + "\n" +
+		textStyle.Render(translate("  Alt+R       Reload note changed outside Totion")) + "\n" +
 		textStyle.Render(translate("  Ctrl+S      Save & close (auto-save enabled)")) + "\n\n" +
 		textStyle.Render(translate("SEARCH & ORGANIZATION:")) + "\n" +
 		textStyle.Render(translate("  Ctrl+/      Full-text search across notes")) + "\n" +
 		textStyle.Render(translate("              • Search text: type any word")) + "\n" +
 		textStyle.Render(translate("              • Search tags: type #tagname")) + "\n" +
+		textStyle.Render(translate("              • AND/OR/NOT, \"phrases\" and filters (Help → S)")) + "\n" +
+		textStyle.Render(translate("              • Best matching notes first, PgUp/PgDn to page")) + "\n" +
+		textStyle.Render(translate("              • Tab switches exact, fuzzy and regex search")) + "\n" +
 		textStyle.Render(translate("  T           View tags browser (all #hashtags)")) + "\n" +
 		textStyle.Render(translate("  B           Notebooks (folder organization)")) + "\n" +
 		textStyle.Render(translate("  #           Type tags in notes (e.g., #work)")) + "\n" +
 		textStyle.Render(translate("  /           Search/filter notes in list (use Ctrl+L to open list)")) + "\n\n" +
 		textStyle.Render(translate("NAVIGATION:")) + "\n" +
 		textStyle.Render(translate("  ↑ ↓         Navigate lists")) + "\n" +
-		textStyle.Render(translate("  Tab         Switch format (Markdown/Text)")) + "\n\n" +
+		textStyle.Render(translate("  Tab         Switch format (Markdown/Text/Org/AsciiDoc)")) + "\n\n" +
 		textStyle.Render(translate("FEATURES:")) + "\n" +
 		textStyle.Render(translate("  Ctrl+T      Templates menu")) + "\n" +
 		textStyle.Render(translate("  P           Themes menu")) + "\n" +
@@ -35,7 +40,8 @@ func GetKeyboardShortcuts(translate func(string) string) string {
 		textStyle.Render(translate("  Alt+I       Import notes")) + "\n" +
 		textStyle.Render(translate("  S           View statistics & dashboard")) + "\n" +
 		textStyle.Render(translate("  G           Git operations")) + "\n" +
-		textStyle.Render(translate("  Alt+Y       Sync & backup")) + "\n\n" +
+		textStyle.Render(translate("  Alt+Y       Sync & backup")) + "\n" +
+		textStyle.Render(translate("  A           Attachments (orphaned/missing files)")) + "\n\n" +
 		textStyle.Render(translate("GENERAL:")) + "\n" +
 		textStyle.Render(translate("  Ctrl+H / ?  Show this help")) + "\n" +
 		textStyle.Render(translate("  Q           Quit application")) + "\n\n" +
@@ -48,6 +54,14 @@ func GetKeyboardShortcuts(translate func(string) string) string {
 		textStyle.Render(translate("  • Pinned notes show 📌 indicator")) + "\n" +
 		textStyle.Render(translate("  • View pinned notes on home screen")) + "\n" +
 		textStyle.Render(translate("  • Maximum 10 pinned notes allowed")) + "\n\n" +
+		successStyle.Render(translate("🕘 VERSION HISTORY:")) + "\n" +
+		textStyle.Render(translate("  • Press Alt+V in editor to list earlier versions")) + "\n" +
+		textStyle.Render(translate("  • D shows a diff, R restores the selected version")) + "\n" +
+		textStyle.Render(translate("  • A version is kept on every save, no git needed")) + "\n\n" +
+		successStyle.Render(translate("📎 ATTACHMENTS:")) + "\n" +
+		textStyle.Render(translate("  • Press Alt+A in editor to attach an image or file")) + "\n" +
+		textStyle.Render(translate("  • Files are copied into the vault's attachments folder")) + "\n" +
+		textStyle.Render(translate("  • Press A on home to find orphaned and missing files")) + "\n\n" +
 		successStyle.Render(translate("🌐 UI TRANSLATION:")) + "\n" +
 		textStyle.Render(translate("  • Press Alt+T from ANY screen (not just homepage)")) + "\n" +
 		textStyle.Render(translate("  • Translates entire UI: menus, labels, help text")) + "\n" +
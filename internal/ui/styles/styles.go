@@ -60,6 +60,18 @@ var (
 	SubtleStyle = lipgloss.NewStyle().
 			Foreground(ColorGray)
 
+	// MatchStyle for the parts of search results that matched
+	MatchStyle = lipgloss.NewStyle().
+			Foreground(ColorWhite).
+			Bold(true).
+			Underline(true)
+
+	// CaptureStyle for the capture groups of regex search results
+	CaptureStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("226")).
+			Bold(true).
+			Underline(true)
+
 	// BoxStyle for bordered content
 	BoxStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).